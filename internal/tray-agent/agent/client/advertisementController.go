@@ -5,7 +5,7 @@ import (
 )
 
 //createAdvertisementController creates a new CRDController for the Liqo Advertisement CRD.
-func createAdvertisementController(kubeconfig string) (*CRDController, error) {
+func (ctrl *AgentController) createAdvertisementController(kubeconfig string) (*CRDController, error) {
 	controller := &CRDController{}
 	//init client
 	newClient, err := advertisementApi.CreateAdvertisementClient(kubeconfig, nil, false, nil)
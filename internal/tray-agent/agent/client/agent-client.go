@@ -6,13 +6,25 @@ import (
 	"flag"
 	"github.com/gen2brain/dlgs"
 	"github.com/liqotech/liqo/pkg/crdClient"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/clientcmd"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 )
 
 const (
@@ -43,10 +55,19 @@ func UseMockedAgentController() {
 	})
 }
 
-//DestroyMockedAgentController destroys the AgentController singleton for
+//destroyTimeout is the maximum time DestroyMockedAgentController waits for the goroutines of the
+//destroyed AgentController to exit.
+const destroyTimeout = 5 * time.Second
+
+//DestroyMockedAgentController shuts down and destroys the AgentController singleton for
 //testing purposes. It works only after calling UseMockedAgentController().
 func DestroyMockedAgentController() {
 	if mockedController {
+		if agentCtrl != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), destroyTimeout)
+			_ = agentCtrl.Shutdown(ctx)
+			cancel()
+		}
 		agentCtrl = nil
 	}
 }
@@ -57,20 +78,41 @@ var kubeconfArg *string
 //flagOnce prevents the program arguments flag redefinition which would cause panic.
 var flagOnce sync.Once
 
-//NotifyChan is the wrapper type for generic data sent over a NotifyChannel. After receiving such element from a
-//chan, it is then possible to try its conversion into a specific type.
-type NotifyDataGeneric interface{}
+//NotifyDataGeneric is the wrapper type for generic data sent over a NotifyChannel. After receiving such element
+//with a NotifyEvent, it is then possible to try its conversion into a specific type.
+type NotifyDataGeneric interface {
+	//EventKey returns the key of the event carrying the data. The events having the same key (e.g. the ones
+	//regarding the same peer, see PeerEventKey) have to be handled in the order they are notified.
+	EventKey() string
+}
 
 //AgentController is the data structure that manages Tray Agent interaction with the cluster.
 type AgentController struct {
-	//notifyChannels is a set of channels used by the cache logic to notify a watched event.
-	notifyChannels map[NotifyChannel]chan NotifyDataGeneric
+	//ctx is the root context of the AgentController. It is cancelled by Shutdown.
+	ctx    context.Context
+	cancel context.CancelFunc
+	//workers tracks the goroutines started by the AgentController.
+	workers WorkerGroup
+	//events is the stream used by the cache logic to notify the watched events.
+	events chan NotifyEvent
 	//kubeClient is a standard kubernetes client.
 	kubeClient kubernetes.Interface
+	//restConfig is the configuration used by the kubeClient. It is nil for a mocked AgentController.
+	restConfig *rest.Config
+	//dynClient is a dynamic kubernetes client, used for the resources whose API version depends on the cluster.
+	dynClient dynamic.Interface
 	//agentConf contains Liqo Agent configuration parameters acquired from the cluster.
 	agentConf *agentConfiguration
 	//crdManager manages CRD operations.
 	*crdManager
+	//kubeManager manages the watch on standard kubernetes resources.
+	*kubeManager
+	//portForwards manages the port-forwards to the Services of the home cluster.
+	portForwards *portForwardManager
+	//dashboard resolves the LiqoDash endpoint.
+	dashboard *dashboardResolver
+	//dashProxy is the local proxy that authenticates the LiqoDash sessions.
+	dashProxy *dashboardProxy
 	//valid specifies whether the provided kubeconfig actually describes a correct configuration.
 	valid bool
 	//connected specifies whether all AgentController components are correctly up and running.
@@ -83,20 +125,26 @@ func (ctrl *AgentController) Mocked() bool {
 	return ctrl.mocked
 }
 
+//KubeClient returns the kubernetes client of the AgentController.
+func (ctrl *AgentController) KubeClient() kubernetes.Interface {
+	return ctrl.kubeClient
+}
+
 //Connected returns if the Controller client is actually connected to the cluster.
 func (ctrl *AgentController) Connected() bool {
 	return ctrl.connected
 }
 
-//NotifyChannel returns the NotifyChannel of type 'channelType'.
-func (ctrl *AgentController) NotifyChannel(channelType NotifyChannel) chan NotifyDataGeneric {
-	return ctrl.notifyChannels[channelType]
-}
-
-//StartCaches starts each available AgentController cache.
+//StartCaches starts each available AgentController cache. The caches are stopped by StopCaches or when the
+//AgentController is shut down.
 func (ctrl *AgentController) StartCaches() error {
 	for _, crdCtrl := range ctrl.crdManager.clientMap {
-		if err := crdCtrl.StartCache(); err != nil {
+		if err := crdCtrl.StartCache(ctrl.ctx, &ctrl.workers); err != nil {
+			return err
+		}
+	}
+	for _, kubeRes := range kubeResources {
+		if err := ctrl.KubeController(kubeRes).StartCache(ctrl.ctx, &ctrl.workers); err != nil {
 			return err
 		}
 	}
@@ -105,9 +153,19 @@ func (ctrl *AgentController) StartCaches() error {
 
 //StopCaches stops all the CR caches running for the AgentController.
 func (ctrl *AgentController) StopCaches() {
-	for _, crdCtrl := range ctrl.crdManager.clientMap {
-		crdCtrl.StopCache()
+	if ctrl.crdManager != nil {
+		for _, crdCtrl := range ctrl.crdManager.clientMap {
+			crdCtrl.StopCache()
+		}
+	}
+	if ctrl.kubeManager != nil {
+		for _, kubeCtrl := range ctrl.kubeManager.kubeClientMap {
+			kubeCtrl.StopCache()
+		}
+		ctrl.kubeManager.stopOffloadedPodCaches()
 	}
+	ctrl.stopDashboardWatch()
+	ctrl.StopDashboardProxy()
 }
 
 /*acquireKubeconfig sets the EnvLiqoKConfig env variable.
@@ -192,39 +250,125 @@ func acquireKubeconfig() {
 //If no value for kubeconfig is provided, it returns an error.
 //
 //The file path is retrieved from the env var specified by EnvLiqoKConfig.
-func createKubeClient() (kubernetes.Interface, error) {
+func createKubeClient() (kubernetes.Interface, *rest.Config, error) {
 	if mockedController {
-		return fake.NewSimpleClientset(), nil
+		return newFakeKubeClient(), nil, nil
 	}
 	kubeconfig, ok := os.LookupEnv(EnvLiqoKConfig)
 	if !ok || kubeconfig == "" {
-		return nil, errors.New("no kubeconfig provided")
+		return nil, nil, errors.New("no kubeconfig provided")
 	}
 	cfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	return client, cfg, err
+}
+
+//KubectlCommand returns the command line running kubectl with the provided arguments on the cluster the Agent
+//is connected to, i.e. using the kubeconfig file specified by EnvLiqoKConfig (if any).
+func KubectlCommand(args ...string) string {
+	cmd := []string{"kubectl"}
+	if kubeconfig, ok := os.LookupEnv(EnvLiqoKConfig); ok && kubeconfig != "" {
+		cmd = append(cmd, "--kubeconfig", shellQuote(kubeconfig))
+	}
+	for _, arg := range args {
+		cmd = append(cmd, shellQuote(arg))
 	}
-	return kubernetes.NewForConfig(cfg)
+	return strings.Join(cmd, " ")
+}
+
+//shellQuote quotes an argument of a command line for a POSIX shell, if it contains special characters.
+func shellQuote(arg string) string {
+	special := func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			strings.ContainsRune("-_./=:@,+", r))
+	}
+	if arg != "" && strings.IndexFunc(arg, special) < 0 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+//newFakeKubeClient creates the kubernetes client of a mocked AgentController. Unlike the plain fake clientset,
+//its watches honor the label and field selectors, so that each informer receives only the objects it would
+//receive from an API server.
+func newFakeKubeClient() kubernetes.Interface {
+	client := fake.NewSimpleClientset()
+	client.PrependWatchReactor("*", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		watchAction, ok := action.(k8stesting.WatchAction)
+		if !ok {
+			return false, nil, nil
+		}
+		restrictions := watchAction.GetWatchRestrictions()
+		w, err := client.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		return true, watch.Filter(w, func(e watch.Event) (watch.Event, bool) {
+			return e, selectorsMatch(e.Object, restrictions.Labels, restrictions.Fields)
+		}), nil
+	})
+	return client
+}
+
+//selectorsMatch returns whether an object matches a label and a field selector. As for the API server, only
+//the name and the namespace of an object can be selected by the field selector, together with the node and
+//the phase of a pod.
+func selectorsMatch(obj runtime.Object, labelSelector labels.Selector, fieldSelector fields.Selector) bool {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	if labelSelector != nil && !labelSelector.Matches(labels.Set(accessor.GetLabels())) {
+		return false
+	}
+	if fieldSelector == nil || fieldSelector.Empty() {
+		return true
+	}
+	fieldSet := fields.Set{
+		"metadata.name":      accessor.GetName(),
+		"metadata.namespace": accessor.GetNamespace(),
+	}
+	if pod, ok := obj.(*corev1.Pod); ok {
+		fieldSet["spec.nodeName"] = pod.Spec.NodeName
+		fieldSet["status.phase"] = string(pod.Status.Phase)
+	}
+	return fieldSelector.Matches(fieldSet)
+}
+
+//createDynamicClient creates a new dynamic client from the configuration of the kubernetes client.
+func createDynamicClient(cfg *rest.Config) (dynamic.Interface, error) {
+	if mockedController {
+		return dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), nil
+	}
+	return dynamic.NewForConfig(cfg)
 }
 
 //GetAgentController returns an initialized AgentController singleton.
 func GetAgentController() *AgentController {
 	if agentCtrl == nil {
 		agentCtrl = &AgentController{
-			agentConf: &agentConfiguration{},
+			agentConf:    &agentConfiguration{},
+			portForwards: &portForwardManager{forwards: make(map[string]*portForward)},
+			dashboard:    &dashboardResolver{},
+			dashProxy:    &dashboardProxy{},
 		}
 		agentCtrl.mocked = mockedController
-		//init the notifyChannels that are kept open during the entire Agent execution.
-		agentCtrl.notifyChannels = make(map[NotifyChannel]chan NotifyDataGeneric)
-		for _, i := range notifyChannelNames {
-			agentCtrl.notifyChannels[i] = make(chan NotifyDataGeneric, notifyBuffLength)
-		}
+		agentCtrl.dashboard.resolve = agentCtrl.resolveDashboard
+		agentCtrl.ctx, agentCtrl.cancel = context.WithCancel(context.Background())
+		//init the stream of events that is kept open during the entire Agent execution.
+		agentCtrl.events = make(chan NotifyEvent, notifyBuffLength)
 		var err error
 		//acquire configuration, try to connect clients, start caches.
 		acquireKubeconfig()
-		if agentCtrl.kubeClient, err = createKubeClient(); err == nil {
+		if agentCtrl.kubeClient, agentCtrl.restConfig, err = createKubeClient(); err == nil {
+			agentCtrl.dynClient, err = createDynamicClient(agentCtrl.restConfig)
+		}
+		if err == nil {
 			if err = agentCtrl.initCRDManager(); err == nil {
-				if agentCtrl.ConnectionTest() {
+				if err = agentCtrl.initKubeManager(); err == nil && agentCtrl.ConnectionTest() {
 					if err = agentCtrl.StartCaches(); err == nil {
 						agentCtrl.connected = true
 						//init configuration data
@@ -245,7 +389,9 @@ func GetAgentController() *AgentController {
 //ConnectionTest checks the validity of the provided kubernetes configuration via
 //kubeconfig file by trying to establish a connection to the API server.
 func (ctrl *AgentController) ConnectionTest() bool {
-	_, err := ctrl.kubeClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
+	ctx, cancel := ctrl.requestContext()
+	defer cancel()
+	_, err := ctrl.kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{
 		LabelSelector: masterNodeLabel,
 	})
 	if err == nil {
@@ -2,7 +2,9 @@ package client
 
 import (
 	"github.com/stretchr/testify/assert"
+	"os"
 	"testing"
+	"time"
 )
 
 func TestAgentControllerComponentsReadiness(t *testing.T) {
@@ -16,4 +18,86 @@ func TestAgentControllerComponentsReadiness(t *testing.T) {
 		assert.NotNilf(t, crdCtrl, "%v CRDController is nil", crName)
 		assert.Truef(t, crdCtrl.Running(), "%v CRDController is not running", crName)
 	}
+	for _, kubeRes := range kubeResources {
+		kubeCtrl := ctrl.KubeController(kubeRes)
+		assert.NotNilf(t, kubeCtrl, "%v KubeController is nil", kubeRes)
+		assert.Truef(t, kubeCtrl.Running(), "%v KubeController is not running", kubeRes)
+	}
+}
+
+func TestClusterConfigNotification(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	conf, _ := createClusterConfig()
+	conf.Spec.DiscoveryConfig.ClusterName = "home"
+	conf.Spec.DiscoveryConfig.AutoJoin = true
+	conf.Spec.AgentConfig.DashboardConfig.Namespace = "liqo-dash"
+	ccCtrl := ctrl.Controller(CRClusterConfig)
+	if err := ccCtrl.Store.Add(conf); err != nil {
+		t.Fatal(err)
+	}
+	data := waitNotifyData(t, ctrl, ChanClusterConfig).(*NotifyDataClusterConfig)
+	assert.Equal(t, "home", data.ClusterName, "wrong ClusterName notified")
+	assert.True(t, data.Discovery.AutoJoin, "wrong discovery settings notified")
+	assert.Equal(t, "liqo-dash", ctrl.agentConf.dashboardConfig().namespace,
+		"dashboard configuration not updated")
+	assert.True(t, ctrl.ValidConfiguration(), "configuration should be valid")
+	if err := ccCtrl.Store.Delete(conf); err != nil {
+		t.Fatal(err)
+	}
+	data = waitNotifyData(t, ctrl, ChanClusterConfig).(*NotifyDataClusterConfig)
+	assert.True(t, data.Deleted, "ClusterConfig deletion not notified")
+	assert.Empty(t, data.ClusterName, "ClusterName not cleared on deletion")
+	assert.False(t, ctrl.ValidConfiguration(), "configuration should be invalidated on deletion")
+}
+
+//pendingNotifyData stores, by AgentController and NotifyChannel, the events received by waitNotifyData
+//while waiting for the ones of another NotifyChannel.
+var pendingNotifyData = make(map[*AgentController]map[NotifyChannel][]NotifyDataGeneric)
+
+//waitNotifyData waits for a NotifyDataGeneric on a NotifyChannel.
+func waitNotifyData(t *testing.T, ctrl *AgentController, channel NotifyChannel) NotifyDataGeneric {
+	pending, present := pendingNotifyData[ctrl]
+	if !present {
+		pending = make(map[NotifyChannel][]NotifyDataGeneric)
+		pendingNotifyData[ctrl] = pending
+	}
+	if queue := pending[channel]; len(queue) > 0 {
+		pending[channel] = queue[1:]
+		return queue[0]
+	}
+	timeout := time.After(time.Second * 5)
+	for {
+		select {
+		case event := <-ctrl.Events():
+			if event.Channel == channel {
+				return event.Data
+			}
+			pending[event.Channel] = append(pending[event.Channel], event.Data)
+		case <-timeout:
+			t.Fatal("no event received")
+			return nil
+		}
+	}
+}
+
+func TestKubectlCommand(t *testing.T) {
+	oldKubeconfig, present := os.LookupEnv(EnvLiqoKConfig)
+	defer func() {
+		if present {
+			_ = os.Setenv(EnvLiqoKConfig, oldKubeconfig)
+		} else {
+			_ = os.Unsetenv(EnvLiqoKConfig)
+		}
+	}()
+	_ = os.Unsetenv(EnvLiqoKConfig)
+	assert.Equal(t, "kubectl logs -n apps web-1", KubectlCommand("logs", "-n", "apps", "web-1"),
+		"wrong command without kubeconfig")
+	_ = os.Setenv(EnvLiqoKConfig, "/home/user/.kube/liqo config")
+	assert.Equal(t, "kubectl --kubeconfig '/home/user/.kube/liqo config' exec -it -n apps web-1 -- sh",
+		KubectlCommand("exec", "-it", "-n", "apps", "web-1", "--", "sh"), "kubeconfig not added to the command")
+	_ = os.Setenv(EnvLiqoKConfig, "/tmp/it's")
+	assert.Equal(t, `kubectl --kubeconfig '/tmp/it'\''s' version`, KubectlCommand("version"),
+		"kubeconfig path not quoted")
 }
@@ -5,6 +5,7 @@ import (
 	clusterConfig "github.com/liqotech/liqo/apis/config/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"strconv"
+	"sync"
 )
 
 //agentConfiguration contains Agent config parameters.
@@ -13,6 +14,8 @@ type agentConfiguration struct {
 	valid bool
 	//dashboard contains parameters for LiqoDash.
 	dashboard *dashConfig
+	//mutex protecting the agentConfiguration, which is updated by the ClusterConfig cache.
+	sync.RWMutex
 }
 
 //dashConfig contains the parameters required for Agent
@@ -25,13 +28,22 @@ type dashConfig struct {
 	label string
 }
 
+//dashboardConfig returns a copy of the current LiqoDash configuration.
+func (c *agentConfiguration) dashboardConfig() dashConfig {
+	c.RLock()
+	defer c.RUnlock()
+	if c.dashboard == nil {
+		return dashConfig{}
+	}
+	return *c.dashboard
+}
+
 //acquireClusterConfiguration initializes the AgentController configuration
 //by retrieving data from the ClusterConfig CR.
 func (ctrl *AgentController) acquireClusterConfiguration() {
 	if !ctrl.connected {
 		return
 	}
-	aConf := ctrl.agentConf
 	var clConf *clusterConfig.ClusterConfig
 	var err error
 	if ctrl.Mocked() {
@@ -42,12 +54,37 @@ func (ctrl *AgentController) acquireClusterConfiguration() {
 	if err != nil {
 		return
 	}
+	ctrl.loadClusterConfiguration(clConf)
+}
+
+//loadClusterConfiguration updates the AgentController configuration with the content of a ClusterConfig CR.
+//If the LiqoDash parameters changed, the watch on the LiqoDash resources is restarted.
+func (ctrl *AgentController) loadClusterConfiguration(clConf *clusterConfig.ClusterConfig) {
+	aConf := ctrl.agentConf
 	agentConfig := clConf.Spec.AgentConfig
-	aConf.dashboard = &dashConfig{
+	newDash := &dashConfig{
 		namespace: agentConfig.DashboardConfig.Namespace,
 		label:     agentConfig.DashboardConfig.AppLabel,
 	}
+	aConf.Lock()
+	changed := aConf.dashboard == nil || *aConf.dashboard != *newDash
+	aConf.dashboard = newDash
 	aConf.valid = true
+	aConf.Unlock()
+	if changed {
+		_ = ctrl.watchDashboard(*newDash)
+	}
+}
+
+//invalidateClusterConfiguration marks the AgentController configuration as not valid, e.g. after the
+//ClusterConfig CR has been deleted.
+func (ctrl *AgentController) invalidateClusterConfiguration() {
+	ctrl.agentConf.Lock()
+	ctrl.agentConf.valid = false
+	ctrl.agentConf.dashboard = nil
+	ctrl.agentConf.Unlock()
+	ctrl.stopDashboardWatch()
+	ctrl.StopDashboardProxy()
 }
 
 //getConfig retrieves the ClusterConfig CR which contains configuration data.
@@ -88,5 +125,7 @@ func createClusterConfig() (*clusterConfig.ClusterConfig, error) {
 //ValidConfiguration returns whether AgentController configuration data
 //have been correctly initialized.
 func (ctrl *AgentController) ValidConfiguration() bool {
+	ctrl.agentConf.RLock()
+	defer ctrl.agentConf.RUnlock()
 	return ctrl.agentConf.valid
 }
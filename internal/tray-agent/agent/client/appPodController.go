@@ -0,0 +1,118 @@
+package client
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"sort"
+)
+
+//createAppPodController creates a new KubeController for the pods of the applications launched by the Agent.
+func (ctrl *AgentController) createAppPodController() *KubeController {
+	factory := informers.NewSharedInformerFactoryWithOptions(ctrl.kubeClient, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = appLabel
+		}))
+	controller := newKubeController(KRAppPod, factory,
+		func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Core().V1().Pods().Informer()
+		})
+	controller.addFunc = ctrl.appPodAddFunc
+	controller.updateFunc = ctrl.appPodUpdateFunc
+	controller.deleteFunc = ctrl.appPodDeleteFunc
+	return controller
+}
+
+//NotifyDataAppPod is a NotifyDataGeneric sub-type used to exchange data concerning a pod of an application
+//launched by the Agent.
+type NotifyDataAppPod struct {
+	//App is the name of the application.
+	App string
+	//Namespace of the application.
+	Namespace string
+	//Name of the pod.
+	Name string
+	//Phase is the current PodPhase of the pod.
+	Phase string
+	//ClusterID of the peer running the pod. It is empty if the pod runs on the home cluster
+	//or it is not scheduled yet.
+	ClusterID string
+	//Terminating specifies whether the pod is being deleted, i.e. it has a DeletionTimestamp.
+	Terminating bool
+}
+
+//EventKey returns the key of the events regarding the pods of an application.
+func (d *NotifyDataAppPod) EventKey() string {
+	return "application/" + d.Namespace + "/" + d.App
+}
+
+//loadAppPod loads useful data about an application pod.
+func (d *NotifyDataAppPod) loadAppPod(ctrl *AgentController, pod *corev1.Pod) {
+	d.App = pod.Labels[appLabel]
+	d.Namespace = pod.Namespace
+	d.Name = pod.Name
+	d.Phase = string(pod.Status.Phase)
+	d.ClusterID, _ = ctrl.offloadingClusterID(pod)
+	d.Terminating = pod.DeletionTimestamp != nil
+}
+
+//ListAppPods returns the data of the application pods currently stored in the cache, sorted by namespace
+//and name.
+func (ctrl *AgentController) ListAppPods() []*NotifyDataAppPod {
+	var pods []*NotifyDataAppPod
+	podCtrl := ctrl.KubeController(KRAppPod)
+	if podCtrl == nil || !podCtrl.Running() {
+		return pods
+	}
+	for _, obj := range podCtrl.Store.List() {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			continue
+		}
+		data := &NotifyDataAppPod{}
+		data.loadAppPod(ctrl, pod)
+		pods = append(pods, data)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].Name < pods[j].Name
+	})
+	return pods
+}
+
+//			**** EVENT FUNCTIONS ****
+//	The following functions are the callbacks the AppPod Controller uses to handle the events
+//	of the correspondent cache.
+
+//appPodAddFunc is the ADD event handler for the AppPod KubeController.
+func (ctrl *AgentController) appPodAddFunc(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	data := &NotifyDataAppPod{}
+	data.loadAppPod(ctrl, pod)
+	ctrl.Notify(ChanAppPodAddedOrUpdated, data)
+}
+
+//appPodUpdateFunc is the UPDATE event handler for the AppPod KubeController.
+func (ctrl *AgentController) appPodUpdateFunc(_ interface{}, newObj interface{}) {
+	ctrl.appPodAddFunc(newObj)
+}
+
+//appPodDeleteFunc is the DELETE event handler for the AppPod KubeController.
+func (ctrl *AgentController) appPodDeleteFunc(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	data := &NotifyDataAppPod{}
+	data.loadAppPod(ctrl, pod)
+	ctrl.Notify(ChanAppPodDeleted, data)
+}
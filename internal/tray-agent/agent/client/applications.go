@@ -0,0 +1,424 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+	"os"
+	"strings"
+)
+
+const (
+	//appLabel is the label identifying all the resources belonging to an application launched by the Agent.
+	appLabel = "liqo.io/agent-app"
+	//virtualNodeTaintKey is the key of the taint applied to the Liqo virtual nodes.
+	virtualNodeTaintKey = "virtual-node.liqo.io/not-allowed"
+	//virtualNodeTaintEffect is the effect of the taint applied to the Liqo virtual nodes.
+	virtualNodeTaintEffect = corev1.TaintEffectNoExecute
+)
+
+//AppKind defines the kind of workload used to run an application.
+type AppKind string
+
+const (
+	//AppDeployment runs the application as a Deployment, for long-running services.
+	AppDeployment AppKind = "Deployment"
+	//AppJob runs the application as a Job, for tasks that run to completion.
+	AppJob AppKind = "Job"
+)
+
+//AppKinds contains the AppKind available to run a container image.
+var AppKinds = []AppKind{AppDeployment, AppJob}
+
+//AppSpec describes an application to be launched from a container image.
+type AppSpec struct {
+	//Name of the application, also used as name of the workload.
+	Name string
+	//Image is the container image of the application.
+	Image string
+	//Kind of the workload running the application.
+	Kind AppKind
+	//Replicas is the number of pods of a Deployment.
+	Replicas int32
+}
+
+//AppTarget specifies where an application is deployed.
+type AppTarget struct {
+	//Namespace of the application. It must be enabled to the offloading.
+	Namespace string
+	//ClusterID of the peer the application pods are forced to run on. If empty, the pods
+	//are scheduled according to the usual Kubernetes policies.
+	ClusterID string
+}
+
+//OffloadingNamespaces returns the namespaces of the home cluster enabled to the offloading, retrieving them
+//from the namespaces cache.
+func (ctrl *AgentController) OffloadingNamespaces() []string {
+	var namespaces []string
+	nsCtrl := ctrl.KubeController(KRNamespace)
+	if nsCtrl == nil {
+		return namespaces
+	}
+	for _, obj := range nsCtrl.Store.List() {
+		if ns, ok := obj.(*corev1.Namespace); ok && ns.Labels[namespaceOffloadingLabel] == namespaceOffloadingEnabled {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+	return namespaces
+}
+
+//virtualNodeName returns the name of the virtual node representing the peer with the given ClusterID.
+func (ctrl *AgentController) virtualNodeName(clusterID string) (string, error) {
+	nodeCtrl := ctrl.KubeController(KRVirtualNode)
+	if nodeCtrl != nil {
+		for _, obj := range nodeCtrl.Store.List() {
+			if node, ok := obj.(*corev1.Node); ok && node.Annotations[virtualNodeClusterIDAnnotation] == clusterID {
+				return node.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no virtual node available for peer %s", clusterID)
+}
+
+//NewAppObjects creates the workload running an application from its AppSpec.
+func NewAppObjects(spec *AppSpec) ([]runtime.Object, error) {
+	if errs := validation.IsDNS1123Label(spec.Name); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid application name '%s': %s", spec.Name, strings.Join(errs, ", "))
+	}
+	if strings.TrimSpace(spec.Image) == "" {
+		return nil, errors.New("no container image provided")
+	}
+	podSpec := corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Name:  spec.Name,
+			Image: strings.TrimSpace(spec.Image),
+		}},
+	}
+	meta := metav1.ObjectMeta{Name: spec.Name}
+	selector := map[string]string{appLabel: spec.Name}
+	switch spec.Kind {
+	case AppDeployment:
+		replicas := spec.Replicas
+		if replicas < 1 {
+			replicas = 1
+		}
+		return []runtime.Object{&appsv1.Deployment{
+			ObjectMeta: meta,
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: selector},
+				Template: corev1.PodTemplateSpec{Spec: podSpec},
+			},
+		}}, nil
+	case AppJob:
+		podSpec.RestartPolicy = corev1.RestartPolicyNever
+		return []runtime.Object{&batchv1.Job{
+			ObjectMeta: meta,
+			Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{Spec: podSpec},
+			},
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported application kind %s", spec.Kind)
+	}
+}
+
+//DecodeManifests decodes a multi-document YAML (or JSON) stream of Kubernetes manifests.
+func DecodeManifests(data []byte) ([]runtime.Object, error) {
+	var objs []runtime.Object
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if strings.TrimSpace(string(doc)) == "" {
+			continue
+		}
+		obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(doc, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+	if len(objs) == 0 {
+		return nil, errors.New("no manifest found")
+	}
+	return objs, nil
+}
+
+//LoadManifestFile reads and decodes the Kubernetes manifests stored in a file.
+func LoadManifestFile(path string) ([]runtime.Object, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeManifests(data)
+}
+
+//prepareAppPodTemplate labels the pod template of an application and, if requested, forces its pods
+//to run on the virtual node of a peer. The affinity rules already defined in the template are preserved.
+func prepareAppPodTemplate(template *corev1.PodTemplateSpec, appName string, nodeName string) {
+	if template.Labels == nil {
+		template.Labels = make(map[string]string)
+	}
+	template.Labels[appLabel] = appName
+	if nodeName == "" {
+		return
+	}
+	spec := &template.Spec
+	if spec.Affinity == nil {
+		spec.Affinity = &corev1.Affinity{}
+	}
+	if spec.Affinity.NodeAffinity == nil {
+		spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAffinity := spec.Affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	required := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(required.NodeSelectorTerms) == 0 {
+		required.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+	//the terms are ORed, so the node requirement is added to each of them
+	for j := range required.NodeSelectorTerms {
+		term := &required.NodeSelectorTerms[j]
+		term.MatchFields = append(term.MatchFields, corev1.NodeSelectorRequirement{
+			Key:      "metadata.name",
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{nodeName},
+		})
+	}
+	toleration := corev1.Toleration{
+		Key:      virtualNodeTaintKey,
+		Operator: corev1.TolerationOpExists,
+		Effect:   virtualNodeTaintEffect,
+	}
+	for j := range spec.Tolerations {
+		if spec.Tolerations[j].MatchToleration(&toleration) {
+			return
+		}
+	}
+	spec.Tolerations = append(spec.Tolerations, toleration)
+}
+
+//DeployApplication creates in the home cluster the resources of an application named appName.
+//
+//Supported resources are Deployments, Jobs, Services, ConfigMaps and Secrets. All of them are labelled as part of
+//the application, in order to be tracked and removed by StopApplication.
+func (ctrl *AgentController) DeployApplication(appName string, objs []runtime.Object, target *AppTarget) error {
+	if !ctrl.connected {
+		return errors.New("no connection available")
+	}
+	if errs := validation.IsDNS1123Label(appName); len(errs) > 0 {
+		return fmt.Errorf("invalid application name '%s': %s", appName, strings.Join(errs, ", "))
+	}
+	if target == nil || target.Namespace == "" {
+		return errors.New("no namespace provided")
+	}
+	enabled := false
+	for _, ns := range ctrl.OffloadingNamespaces() {
+		if ns == target.Namespace {
+			enabled = true
+			break
+		}
+	}
+	if !enabled {
+		return fmt.Errorf("namespace %s is not enabled to the offloading", target.Namespace)
+	}
+	var nodeName string
+	if target.ClusterID != "" {
+		var err error
+		if nodeName, err = ctrl.virtualNodeName(target.ClusterID); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := ctrl.requestContext()
+	defer cancel()
+	c := ctrl.kubeClient
+	ns := target.Namespace
+	//the resources of a running application with the same name would be mixed with the new ones
+	existing, err := ctrl.listAppResources(ctx, appName, ns)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("application %s is already running in namespace %s", appName, ns)
+	}
+	opts := metav1.CreateOptions{}
+	var created []appResource
+	for _, obj := range objs {
+		obj = obj.DeepCopyObject()
+		accessor, ok := obj.(metav1.Object)
+		if !ok {
+			return errors.New("invalid resource in the application")
+		}
+		labels := accessor.GetLabels()
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[appLabel] = appName
+		accessor.SetLabels(labels)
+		accessor.SetNamespace(ns)
+		res := appResource{name: accessor.GetName()}
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			prepareAppPodTemplate(&o.Spec.Template, appName, nodeName)
+			if o.Spec.Selector == nil {
+				o.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{appLabel: appName}}
+			}
+			res.kind = appResourceDeployment
+			_, err = c.AppsV1().Deployments(ns).Create(ctx, o, opts)
+		case *batchv1.Job:
+			prepareAppPodTemplate(&o.Spec.Template, appName, nodeName)
+			res.kind = appResourceJob
+			_, err = c.BatchV1().Jobs(ns).Create(ctx, o, opts)
+		case *corev1.Service:
+			res.kind = appResourceService
+			_, err = c.CoreV1().Services(ns).Create(ctx, o, opts)
+		case *corev1.ConfigMap:
+			res.kind = appResourceConfigMap
+			_, err = c.CoreV1().ConfigMaps(ns).Create(ctx, o, opts)
+		case *corev1.Secret:
+			res.kind = appResourceSecret
+			_, err = c.CoreV1().Secrets(ns).Create(ctx, o, opts)
+		default:
+			err = fmt.Errorf("unsupported resource %s", obj.GetObjectKind().GroupVersionKind().Kind)
+		}
+		if err != nil {
+			//remove only the resources created by this call, leaving untouched any resource already present
+			for _, r := range created {
+				_ = ctrl.deleteAppResource(ctx, ns, r)
+			}
+			return err
+		}
+		created = append(created, res)
+	}
+	return nil
+}
+
+//StopApplication removes from the home cluster all the resources of an application.
+func (ctrl *AgentController) StopApplication(appName string, namespace string) error {
+	if !ctrl.connected {
+		return errors.New("no connection available")
+	}
+	ctx, cancel := ctrl.requestContext()
+	defer cancel()
+	resources, err := ctrl.listAppResources(ctx, appName, namespace)
+	var failed []string
+	for _, res := range resources {
+		if err := ctrl.deleteAppResource(ctx, namespace, res); err != nil {
+			failed = append(failed, res.String())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not remove %s", strings.Join(failed, ", "))
+	}
+	return err
+}
+
+//appResourceKind identifies the kind of a resource of an application.
+type appResourceKind string
+
+//appResourceKind of the resources supported by DeployApplication.
+const (
+	appResourceDeployment appResourceKind = "deployment"
+	appResourceJob        appResourceKind = "job"
+	appResourceService    appResourceKind = "service"
+	appResourceConfigMap  appResourceKind = "configmap"
+	appResourceSecret     appResourceKind = "secret"
+)
+
+//appResource identifies a resource of an application in its namespace.
+type appResource struct {
+	kind appResourceKind
+	name string
+}
+
+//String returns the literal representation of the appResource, e.g. "deployment/web".
+func (r appResource) String() string {
+	return string(r.kind) + "/" + r.name
+}
+
+//listAppResources returns the resources of the home cluster labelled as part of an application. If some kind
+//of resource cannot be listed, the resources found are returned together with an error.
+func (ctrl *AgentController) listAppResources(ctx context.Context, appName string,
+	namespace string) ([]appResource, error) {
+	c := ctrl.kubeClient
+	lo := metav1.ListOptions{LabelSelector: appLabel + "=" + appName}
+	listers := []struct {
+		kind appResourceKind
+		list func() (runtime.Object, error)
+	}{
+		{appResourceDeployment, func() (runtime.Object, error) { return c.AppsV1().Deployments(namespace).List(ctx, lo) }},
+		{appResourceJob, func() (runtime.Object, error) { return c.BatchV1().Jobs(namespace).List(ctx, lo) }},
+		{appResourceService, func() (runtime.Object, error) { return c.CoreV1().Services(namespace).List(ctx, lo) }},
+		{appResourceConfigMap, func() (runtime.Object, error) { return c.CoreV1().ConfigMaps(namespace).List(ctx, lo) }},
+		{appResourceSecret, func() (runtime.Object, error) { return c.CoreV1().Secrets(namespace).List(ctx, lo) }},
+	}
+	var resources []appResource
+	var failed []string
+	for _, l := range listers {
+		list, err := l.list()
+		if err != nil {
+			failed = append(failed, string(l.kind))
+			continue
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			failed = append(failed, string(l.kind))
+			continue
+		}
+		for _, item := range items {
+			if accessor, err := meta.Accessor(item); err == nil {
+				resources = append(resources, appResource{kind: l.kind, name: accessor.GetName()})
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return resources, fmt.Errorf("could not list the %s resources of application %s",
+			strings.Join(failed, ", "), appName)
+	}
+	return resources, nil
+}
+
+//deleteAppResource removes a resource of an application.
+func (ctrl *AgentController) deleteAppResource(ctx context.Context, namespace string, res appResource) error {
+	c := ctrl.kubeClient
+	propagation := metav1.DeletePropagationBackground
+	do := metav1.DeleteOptions{PropagationPolicy: &propagation}
+	switch res.kind {
+	case appResourceDeployment:
+		return c.AppsV1().Deployments(namespace).Delete(ctx, res.name, do)
+	case appResourceJob:
+		return c.BatchV1().Jobs(namespace).Delete(ctx, res.name, do)
+	case appResourceService:
+		return c.CoreV1().Services(namespace).Delete(ctx, res.name, do)
+	case appResourceConfigMap:
+		return c.CoreV1().ConfigMaps(namespace).Delete(ctx, res.name, do)
+	case appResourceSecret:
+		return c.CoreV1().Secrets(namespace).Delete(ctx, res.name, do)
+	default:
+		return fmt.Errorf("unsupported resource %s", res.kind)
+	}
+}
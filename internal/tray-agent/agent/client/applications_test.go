@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"testing"
+)
+
+const testManifest = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+`
+
+func TestNewAppObjects(t *testing.T) {
+	objs, err := NewAppObjects(&AppSpec{Name: "web", Image: "nginx", Kind: AppDeployment})
+	if assert.NoError(t, err, "valid application refused") && assert.Len(t, objs, 1) {
+		deploy, ok := objs[0].(*appsv1.Deployment)
+		if assert.True(t, ok, "Deployment not created") {
+			assert.Equal(t, int32(1), *deploy.Spec.Replicas, "wrong default replicas")
+		}
+	}
+	_, err = NewAppObjects(&AppSpec{Name: "Not Valid", Image: "nginx", Kind: AppJob})
+	assert.Error(t, err, "invalid application name accepted")
+	_, err = NewAppObjects(&AppSpec{Name: "web", Kind: AppJob})
+	assert.Error(t, err, "application without image accepted")
+	_, err = NewAppObjects(&AppSpec{Name: "web", Image: "nginx", Kind: "StatefulSet"})
+	assert.Error(t, err, "unsupported application kind accepted")
+}
+
+func TestDecodeManifests(t *testing.T) {
+	objs, err := DecodeManifests([]byte(testManifest))
+	if assert.NoError(t, err, "valid manifests refused") {
+		assert.Len(t, objs, 2, "wrong number of decoded manifests")
+	}
+	_, err = DecodeManifests([]byte("---\n"))
+	assert.Error(t, err, "empty manifest accepted")
+}
+
+func TestPrepareAppPodTemplate(t *testing.T) {
+	zoneTerm := corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{
+		Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"},
+	}}}
+	antiAffinity := &corev1.PodAntiAffinity{RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+		LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		TopologyKey:   "kubernetes.io/hostname",
+	}}}
+	template := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{Affinity: &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{zoneTerm},
+		}},
+		PodAntiAffinity: antiAffinity,
+	}}}
+	prepareAppPodTemplate(template, "web", "liqo-cl1")
+	prepareAppPodTemplate(template, "web", "")
+	assert.Equal(t, "web", template.Labels[appLabel], "pod template not labelled")
+	affinity := template.Spec.Affinity
+	assert.Equal(t, antiAffinity, affinity.PodAntiAffinity, "pod anti-affinity not preserved")
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if assert.Len(t, terms, 1, "wrong node selector terms") {
+		assert.Equal(t, zoneTerm.MatchExpressions, terms[0].MatchExpressions, "node affinity not preserved")
+		assert.Equal(t, []corev1.NodeSelectorRequirement{{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn,
+			Values: []string{"liqo-cl1"}}}, terms[0].MatchFields, "pods not forced on the virtual node")
+	}
+	if assert.Len(t, template.Spec.Tolerations, 1, "wrong tolerations") {
+		assert.True(t, template.Spec.Tolerations[0].ToleratesTaint(&corev1.Taint{Key: virtualNodeTaintKey,
+			Value: "true", Effect: corev1.TaintEffectNoExecute}), "virtual node taint not tolerated")
+	}
+	//a template with no affinity
+	template = &corev1.PodTemplateSpec{}
+	prepareAppPodTemplate(template, "web", "liqo-cl1")
+	terms = template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if assert.Len(t, terms, 1, "wrong node selector terms") {
+		assert.Len(t, terms[0].MatchFields, 1, "pods not forced on the virtual node")
+	}
+}
+
+func TestDeployApplication(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	objs, _ := DecodeManifests([]byte(testManifest))
+	//the namespace must be enabled to the offloading
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	if _, err := ctrl.kubeClient.CoreV1().Namespaces().Create(context.TODO(), ns, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitNotifyData(t, ctrl, ChanNamespaceAddedOrUpdated)
+	target := &AppTarget{Namespace: "apps"}
+	assert.Error(t, ctrl.DeployApplication("web", objs, target), "application deployed in a disabled namespace")
+	if err := ctrl.SetNamespaceOffloading("apps", true); err != nil {
+		t.Fatal(err)
+	}
+	waitNotifyData(t, ctrl, ChanNamespaceAddedOrUpdated)
+	assert.Error(t, ctrl.DeployApplication("web", objs, &AppTarget{Namespace: "apps", ClusterID: "cl1"}),
+		"application deployed towards a peer without virtual node")
+	assert.NoError(t, ctrl.DeployApplication("web", objs, target), "application not deployed")
+	deploy, err := ctrl.kubeClient.AppsV1().Deployments("apps").Get(context.TODO(), "web", metav1.GetOptions{})
+	if assert.NoError(t, err, "application Deployment not created") {
+		assert.Equal(t, "web", deploy.Labels[appLabel], "Deployment not labelled")
+		assert.Equal(t, "web", deploy.Spec.Template.Labels[appLabel], "pod template not labelled")
+	}
+	//a second deployment with the same name must not touch the running application
+	objs, _ = DecodeManifests([]byte(testManifest))
+	assert.Error(t, ctrl.DeployApplication("web", objs, target), "application deployed twice")
+	_, err = ctrl.kubeClient.AppsV1().Deployments("apps").Get(context.TODO(), "web", metav1.GetOptions{})
+	assert.NoError(t, err, "running application removed by a duplicate deployment")
+	_, err = ctrl.kubeClient.CoreV1().Services("apps").Get(context.TODO(), "web", metav1.GetOptions{})
+	assert.NoError(t, err, "running application removed by a duplicate deployment")
+	//a failed deployment removes only the resources it created
+	apiObjs := []runtime.Object{
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+	}
+	assert.Error(t, ctrl.DeployApplication("api", apiObjs, target), "conflicting resource created")
+	_, err = ctrl.kubeClient.AppsV1().Deployments("apps").Get(context.TODO(), "api", metav1.GetOptions{})
+	assert.Error(t, err, "resources of a failed deployment not removed")
+	_, err = ctrl.kubeClient.CoreV1().Services("apps").Get(context.TODO(), "web", metav1.GetOptions{})
+	assert.NoError(t, err, "resource not created by a failed deployment removed")
+	//stop the application
+	assert.NoError(t, ctrl.StopApplication("web", "apps"), "application not stopped")
+	_, err = ctrl.kubeClient.AppsV1().Deployments("apps").Get(context.TODO(), "web", metav1.GetOptions{})
+	assert.Error(t, err, "application Deployment not removed")
+	_, err = ctrl.kubeClient.CoreV1().Services("apps").Get(context.TODO(), "web", metav1.GetOptions{})
+	assert.Error(t, err, "application Service not removed")
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	discovery "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"k8s.io/apimachinery/pkg/runtime"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+)
+
+//AppCatalogDir is the directory, inside the EnvLiqoPath directory, containing the application templates.
+const AppCatalogDir = "apps"
+
+//AppTemplate maps the content of an application template of the catalog. The Manifests are
+//rendered as a Go text/template using the Parameters, e.g.
+//
+//	name: web
+//	namespace: apps
+//	peer: cluster-2
+//	parameters:
+//	  tag: "1.19"
+//	  replicas: "2"
+//	manifests: |
+//	  apiVersion: apps/v1
+//	  kind: Deployment
+//	  ...
+//	  replicas: {{ .replicas }}
+//	  ...
+//	        image: nginx:{{ .tag }}
+type AppTemplate struct {
+	//Name of the application.
+	Name string `yaml:"name"`
+	//Description of the application.
+	Description string `yaml:"description,omitempty"`
+	//Namespace of the application. It must be enabled to the offloading.
+	Namespace string `yaml:"namespace"`
+	//Peer is the ClusterName or the ClusterID of the peer the application is forced to run on (optional).
+	Peer string `yaml:"peer,omitempty"`
+	//Parameters are the values used to render the Manifests.
+	Parameters map[string]string `yaml:"parameters,omitempty"`
+	//Manifests contains the Kubernetes manifests of the application.
+	Manifests string `yaml:"manifests"`
+	//path of the template file.
+	path string
+}
+
+//Path returns the path of the file containing the AppTemplate.
+func (t *AppTemplate) Path() string {
+	return t.path
+}
+
+//Render renders the manifests of the AppTemplate using its Parameters.
+func (t *AppTemplate) Render() ([]runtime.Object, error) {
+	tmpl, err := template.New(t.Name).Option("missingkey=error").Parse(t.Manifests)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	if err = tmpl.Execute(buf, t.Parameters); err != nil {
+		return nil, err
+	}
+	return DecodeManifests(buf.Bytes())
+}
+
+//LoadAppTemplate reads an AppTemplate from a file.
+func LoadAppTemplate(path string) (*AppTemplate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &AppTemplate{}
+	if err = yaml.Unmarshal(data, t); err != nil {
+		return nil, err
+	}
+	if t.Name == "" || t.Namespace == "" || t.Manifests == "" {
+		return nil, errors.New("name, namespace and manifests are mandatory")
+	}
+	t.path = path
+	return t, nil
+}
+
+//LoadAppCatalog reads all the AppTemplate stored in the AppCatalogDir directory, sorted by name.
+//Invalid templates are skipped and reported in the returned error.
+func LoadAppCatalog() ([]*AppTemplate, error) {
+	liqoDir, present := os.LookupEnv(EnvLiqoPath)
+	if !present {
+		return nil, errors.New("envLiqoPath not set")
+	}
+	paths, err := filepath.Glob(filepath.Join(liqoDir, AppCatalogDir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	var catalog []*AppTemplate
+	var invalid []string
+	for _, path := range paths {
+		t, err := LoadAppTemplate(path)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s: %v", filepath.Base(path), err))
+			continue
+		}
+		catalog = append(catalog, t)
+	}
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].Name < catalog[j].Name
+	})
+	if len(invalid) > 0 {
+		return catalog, fmt.Errorf("invalid application templates:\n%v", invalid)
+	}
+	return catalog, nil
+}
+
+//peerClusterID returns the ClusterID of a peer given its ClusterName or its ClusterID.
+func (ctrl *AgentController) peerClusterID(peer string) (string, error) {
+	fcCtrl := ctrl.Controller(CRForeignCluster)
+	if fcCtrl != nil && fcCtrl.Running() {
+		for _, obj := range fcCtrl.Store.List() {
+			fc, ok := obj.(*discovery.ForeignCluster)
+			if !ok {
+				continue
+			}
+			identity := fc.Spec.ClusterIdentity
+			if identity.ClusterID == peer || identity.ClusterName == peer {
+				return identity.ClusterID, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no peer %s found", peer)
+}
+
+//DeployTemplate deploys the application described by an AppTemplate.
+func (ctrl *AgentController) DeployTemplate(t *AppTemplate) error {
+	objs, err := t.Render()
+	if err != nil {
+		return err
+	}
+	target := &AppTarget{Namespace: t.Namespace}
+	if t.Peer != "" {
+		if target.ClusterID, err = ctrl.peerClusterID(t.Peer); err != nil {
+			return err
+		}
+	}
+	return ctrl.DeployApplication(t.Name, objs, target)
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	appsv1 "k8s.io/api/apps/v1"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testAppTemplate = `
+name: web
+namespace: apps
+parameters:
+  tag: "1.19"
+  replicas: "2"
+manifests: |
+  apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: web
+  spec:
+    replicas: {{ .replicas }}
+    template:
+      spec:
+        containers:
+        - name: web
+          image: nginx:{{ .tag }}
+`
+
+func TestAppCatalog(t *testing.T) {
+	//set env variables
+	env, present := os.LookupEnv(EnvLiqoPath)
+	liqoPath, err := filepath.Abs("test_catalog/liqo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalogPath := filepath.Join(liqoPath, AppCatalogDir)
+	assert.NoError(t, os.Setenv(EnvLiqoPath, liqoPath), "PRE-TEST: envLiqoPath not set")
+	assert.NoError(t, os.MkdirAll(catalogPath, 0777), "PRE-TEST: path for the catalog not created")
+	defer func() {
+		//POST TEST: delete files and reset env var
+		_ = os.RemoveAll("test_catalog")
+		if present {
+			_ = os.Setenv(EnvLiqoPath, env)
+		}
+	}()
+	catalog, err := LoadAppCatalog()
+	assert.NoError(t, err, "empty catalog not loaded")
+	assert.Empty(t, catalog, "empty catalog should not contain templates")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(catalogPath, "web.yaml"), []byte(testAppTemplate), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(catalogPath, "broken.yaml"), []byte("name: broken"), 0644))
+	catalog, err = LoadAppCatalog()
+	assert.Error(t, err, "invalid template not reported")
+	if !assert.Len(t, catalog, 1, "valid template not loaded") {
+		return
+	}
+	tmpl := catalog[0]
+	assert.Equal(t, filepath.Join(catalogPath, "web.yaml"), tmpl.Path(), "wrong template path")
+	objs, err := tmpl.Render()
+	if assert.NoError(t, err, "valid template not rendered") && assert.Len(t, objs, 1) {
+		deploy, ok := objs[0].(*appsv1.Deployment)
+		if assert.True(t, ok, "Deployment not rendered") {
+			assert.Equal(t, int32(2), *deploy.Spec.Replicas, "replicas parameter not applied")
+			assert.Equal(t, "nginx:1.19", deploy.Spec.Template.Spec.Containers[0].Image, "tag parameter not applied")
+		}
+	}
+	//missing parameters are refused
+	delete(tmpl.Parameters, "tag")
+	_, err = tmpl.Render()
+	assert.Error(t, err, "template rendered with a missing parameter")
+	//unknown peers are refused
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	tmpl.Parameters["tag"] = "latest"
+	tmpl.Peer = "unknown"
+	assert.Error(t, GetAgentController().DeployTemplate(tmpl), "template deployed on an unknown peer")
+}
@@ -5,10 +5,11 @@ import (
 )
 
 //createClusterConfigController creates a new CRDController for the Liqo ClusterConfig CRD.
-func createClusterConfigController(kubeconfig string) (*CRDController, error) {
+func (ctrl *AgentController) createClusterConfigController(kubeconfig string) (*CRDController, error) {
 	controller := &CRDController{
-		addFunc:    clusterConfigAddFunc,
-		updateFunc: clusterConfigUpdateFunc,
+		addFunc:    ctrl.clusterConfigAddFunc,
+		updateFunc: ctrl.clusterConfigUpdateFunc,
+		deleteFunc: ctrl.clusterConfigDeleteFunc,
 	}
 	//init client
 	newClient, err := clusterConfig.CreateClusterConfigClient(kubeconfig, false)
@@ -20,19 +21,79 @@ func createClusterConfigController(kubeconfig string) (*CRDController, error) {
 	return controller, nil
 }
 
+//NotifyDataClusterConfig is a NotifyDataGeneric sub-type used to exchange the ClusterConfig data relevant for
+//Liqo Agent.
+type NotifyDataClusterConfig struct {
+	//Deleted specifies whether the ClusterConfig has been deleted. In that case, all the other fields are unset.
+	Deleted bool
+	//ClusterName is the common name of the Liqo cluster the Agent is connected to.
+	ClusterName string
+	//Dashboard contains the parameters required to access LiqoDash.
+	Dashboard struct {
+		//Namespace of LiqoDash.
+		Namespace string
+		//AppLabel is the value of the 'app' label of all LiqoDash related resources.
+		AppLabel string
+	}
+	//Discovery contains the settings of the discovery process of the home cluster.
+	Discovery DiscoverySettings
+	//Sharing contains the policy used by the home cluster to share its resources.
+	Sharing SharingPolicy
+}
+
+//EventKey returns the key of the events regarding the configuration of the Liqo cluster.
+func (d *NotifyDataClusterConfig) EventKey() string {
+	//the configuration changes the resources offered to every peer
+	return PeersListEventKey
+}
+
+//loadClusterConfig loads the relevant data of a ClusterConfig CR.
+func (d *NotifyDataClusterConfig) loadClusterConfig(config *clusterConfig.ClusterConfig) {
+	spec := &config.Spec
+	d.ClusterName = spec.DiscoveryConfig.ClusterName
+	d.Dashboard.Namespace = spec.AgentConfig.DashboardConfig.Namespace
+	d.Dashboard.AppLabel = spec.AgentConfig.DashboardConfig.AppLabel
+	d.Discovery.loadDiscoveryConfig(config)
+	d.Sharing.Enabled = spec.AdvertisementConfig.OutgoingConfig.EnableBroadcaster
+	d.Sharing.Percentage = spec.AdvertisementConfig.OutgoingConfig.ResourceSharingPercentage
+}
+
+//ClusterConfigData returns the data of the ClusterConfig currently stored in the cache. If no ClusterConfig
+//is available, the data are marked as Deleted, as after the removal of the ClusterConfig.
+func (ctrl *AgentController) ClusterConfigData() *NotifyDataClusterConfig {
+	config, err := ctrl.clusterConfig()
+	if err != nil {
+		return &NotifyDataClusterConfig{Deleted: true}
+	}
+	data := &NotifyDataClusterConfig{}
+	data.loadClusterConfig(config)
+	return data
+}
+
+//			**** EVENT FUNCTIONS ****
+//	The following functions are the callbacks the ClusterConfig Controller uses to handle the events
+//	of the correspondent cache.
+
 //clusterConfigAddFunc is the ADD event handler for the ClusterConfig CRDController.
-func clusterConfigAddFunc(obj interface{}) {
+func (ctrl *AgentController) clusterConfigAddFunc(obj interface{}) {
 	config := obj.(*clusterConfig.ClusterConfig)
-	agentCtrl.NotifyChannel(ChanClusterName) <- getClusterName(config)
+	ctrl.loadClusterConfiguration(config)
+	data := &NotifyDataClusterConfig{}
+	data.loadClusterConfig(config)
+	ctrl.Notify(ChanClusterConfig, data)
 }
 
 //clusterConfigUpdateFunc is the UPDATE event handler for the ClusterConfig CRDController.
-func clusterConfigUpdateFunc(_ interface{}, newObj interface{}) {
+func (ctrl *AgentController) clusterConfigUpdateFunc(_ interface{}, newObj interface{}) {
 	config := newObj.(*clusterConfig.ClusterConfig)
-	agentCtrl.NotifyChannel(ChanClusterName) <- getClusterName(config)
+	ctrl.loadClusterConfiguration(config)
+	data := &NotifyDataClusterConfig{}
+	data.loadClusterConfig(config)
+	ctrl.Notify(ChanClusterConfig, data)
 }
 
-//getClusterName extracts the ClusterName from a ClusterConfig CR.
-func getClusterName(config *clusterConfig.ClusterConfig) string {
-	return config.Spec.DiscoveryConfig.ClusterName
+//clusterConfigDeleteFunc is the DELETE event handler for the ClusterConfig CRDController.
+func (ctrl *AgentController) clusterConfigDeleteFunc(_ interface{}) {
+	ctrl.invalidateClusterConfiguration()
+	ctrl.Notify(ChanClusterConfig, &NotifyDataClusterConfig{Deleted: true})
 }
@@ -1,11 +1,12 @@
 package client
 
 import (
+	"context"
 	"errors"
 	"github.com/liqotech/liqo/pkg/crdClient"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/tools/cache"
 	"os"
+	"sync"
 )
 
 //CustomResource defines the CRD managed by Liqo Agent.
@@ -48,19 +49,19 @@ func (ctrl *AgentController) initCRDManager() error {
 	var err error
 	var crdCtrl *CRDController
 	//	CLUSTERCONFIG
-	crdCtrl, err = createClusterConfigController(kubeconfig)
+	crdCtrl, err = ctrl.createClusterConfigController(kubeconfig)
 	if err != nil {
 		return errors.New("connection error on clusterconfigs client creation")
 	}
 	manager.clientMap[CRClusterConfig] = crdCtrl
 	//	ADVERTISEMENT
-	crdCtrl, err = createAdvertisementController(kubeconfig)
+	crdCtrl, err = ctrl.createAdvertisementController(kubeconfig)
 	if err != nil {
 		return errors.New("connection error on advertisements client creation")
 	}
 	manager.clientMap[CRAdvertisement] = crdCtrl
 	//	FOREIGNCLUSTER
-	crdCtrl, err = createForeignClusterController(kubeconfig)
+	crdCtrl, err = ctrl.createForeignClusterController(kubeconfig)
 	if err != nil {
 		return errors.New("connection error on foreignclusters client creation")
 	}
@@ -82,44 +83,63 @@ type CRDController struct {
 	resource string
 	//running specifies whether the CRD cache is running.
 	running bool
+	//mutex protects running, which is read by the handlers of the AgentController caches.
+	mutex sync.RWMutex
 	//addFunc is the handler for the 'resource added' event.
 	addFunc func(obj interface{})
 	//updateFunc is the handler for the 'resource updated' event.
 	updateFunc func(oldObj interface{}, newObj interface{})
 	//deleteFunc is the handler for the 'resource deleted' event.
 	deleteFunc func(obj interface{})
+	//cancel stops the cache.
+	cancel context.CancelFunc
 }
 
 //Running returns whether the controller cache is running.
 func (c *CRDController) Running() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 	return c.running
 }
 
 //StartCache starts the CRD cache and the sending of signals
-//on the Controller notifyChannels.
-func (c *CRDController) StartCache() error {
+//of the AgentController events. The cache is stopped by StopCache or when ctx is cancelled. The goroutines
+//of the cache are tracked by workers.
+func (c *CRDController) StartCache(ctx context.Context, workers *WorkerGroup) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	if c.running {
 		return nil
 	}
-	ehf := cache.ResourceEventHandlerFuncs{
-		AddFunc:    c.addFunc,
-		UpdateFunc: c.updateFunc,
-		DeleteFunc: c.deleteFunc,
-	}
+	//the informer never blocks on the handlers, which are executed by the workQueue
+	queue := newWorkQueue(c.addFunc, c.updateFunc, c.deleteFunc)
 	lo := metav1.ListOptions{}
 	var err error
 	c.Store, c.Stop, err = crdClient.WatchResources(
-		c.CRDClient, c.resource, "", 0, ehf, lo)
-	if err == nil {
-		c.running = true
+		c.CRDClient, c.resource, "", 0, queue.handlers(), lo)
+	if err != nil {
+		return err
 	}
-	return err
+	var cacheCtx context.Context
+	cacheCtx, c.cancel = context.WithCancel(ctx)
+	stop := c.Stop
+	workers.Go(func() {
+		queue.run(cacheCtx.Done())
+	})
+	workers.Go(func() {
+		<-cacheCtx.Done()
+		close(stop)
+	})
+	c.running = true
+	return nil
 }
 
 //StopCache stops (if running) the cache associated for the CRD.
 func (c *CRDController) StopCache() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	if c.running {
-		close(c.Stop)
+		c.cancel()
 		c.running = false
 	}
 }
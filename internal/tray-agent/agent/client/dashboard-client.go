@@ -1,143 +1,347 @@
 package client
 
 import (
-	"context"
 	"errors"
 	"fmt"
+	authv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
-	"os"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"net"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
 	//masterNodeLabel is the label name associated with master nodes.
 	masterNodeLabel = "node-role.kubernetes.io/master"
-	//EnvLiqoDashHost defines the env var for the HOST part of the LiqoDash address.
-	EnvLiqoDashHost = "LIQODASH_HOST"
-	//EnvLiqoDashPort defines the env var for the PORT part of the LiqoDash address.
-	EnvLiqoDashPort = "LIQODASH_PORT"
+	//dashboardDialTimeout is the timeout used to check whether the LiqoDash NodePort is reachable.
+	dashboardDialTimeout = time.Second * 2
+	//dashboardTokenExpiration is the lifetime (in seconds) requested for the LiqoDash access tokens.
+	dashboardTokenExpiration = int64(3600)
 )
 
-//AcquireDashboardConfig tries to retrieve required data to access the LiqoDash service.
-//
-//If a valid configuration is found, the EnvLiqoDashHost and EnvLiqoDashPort env vars are set.
-func (ctrl *AgentController) AcquireDashboardConfig() error {
-	//cleanup LiqoDash env vars
-	if !ctrl.Connected() || !ctrl.ValidConfiguration() {
-		return errors.New("cluster connection not available")
+//KubeResource ids for the LiqoDash resources. They are watched by the dashboardResolver instead of the
+//kubeManager, since their namespace and labels depend on the ClusterConfig.
+const (
+	//KRDashboardIngress is the resource id for the Ingresses of LiqoDash.
+	KRDashboardIngress KubeResource = "dashboardingresses"
+	//KRDashboardService is the resource id for the Services of LiqoDash.
+	KRDashboardService KubeResource = "dashboardservices"
+	//KRDashboardPod is the resource id for the pods of LiqoDash.
+	KRDashboardPod KubeResource = "dashboardpods"
+)
+
+//dashboardResources contains the KubeResource watched by the dashboardResolver, in starting order.
+var dashboardResources = []KubeResource{
+	KRDashboardIngress,
+	KRDashboardService,
+	KRDashboardPod,
+}
+
+//NotifyDataDashboard is a NotifyDataGeneric sub-type used to exchange data concerning the LiqoDash endpoint.
+type NotifyDataDashboard struct {
+	//Ready specifies whether the LiqoDash pods are running and ready to serve.
+	Ready bool
+	//URL is the resolved LiqoDash endpoint. It is empty until the endpoint is resolved
+	//and after the LiqoDash Ingress or Service change.
+	URL string
+	//Forwarded specifies whether the URL points to a port-forward to LiqoDash.
+	Forwarded bool
+}
+
+//EventKey returns the key of the events regarding the LiqoDash endpoint.
+func (d *NotifyDataDashboard) EventKey() string {
+	return "dashboard"
+}
+
+//dashboardResolver resolves the LiqoDash endpoint, caching it until the watched LiqoDash resources change.
+type dashboardResolver struct {
+	//controllers watch the LiqoDash Ingresses, Services and Pods.
+	controllers map[KubeResource]*KubeController
+	//endpoint is the current status of the LiqoDash endpoint.
+	endpoint NotifyDataDashboard
+	//generation is incremented each time the cached LiqoDash endpoint is invalidated.
+	generation uint64
+	//resolve retrieves the LiqoDash endpoint, returning whether it points to a port-forward.
+	resolve func() (string, bool, error)
+	//resolution is the resolution of the LiqoDash endpoint in progress, if any.
+	resolution *dashboardResolution
+	mutex      sync.Mutex
+}
+
+//dashboardResolution is a resolution of the LiqoDash endpoint, shared by all the callers that need the endpoint
+//while it is in progress.
+type dashboardResolution struct {
+	//generation of the LiqoDash endpoint the resolution started with.
+	generation uint64
+	//done is closed when the resolution is completed.
+	done chan struct{}
+	url  string
+	err  error
+	//stale specifies whether the endpoint has been invalidated while it was resolved, so that the url
+	//must be discarded.
+	stale bool
+}
+
+//dashboardResolveAttempts is the maximum number of resolutions of the LiqoDash endpoint performed by
+//DashboardEndpoint when the endpoint is invalidated while it is resolved.
+const dashboardResolveAttempts = 3
+
+//watchDashboard (re)starts the watch on the LiqoDash resources described by conf, invalidating the
+//cached LiqoDash endpoint.
+func (ctrl *AgentController) watchDashboard(conf dashConfig) error {
+	ctrl.stopDashboardWatch()
+	if conf.namespace == "" || conf.label == "" {
+		return errors.New("no LiqoDash configuration available")
 	}
-	var err error
-	if err = os.Unsetenv(EnvLiqoDashHost); err != nil {
-		return err
+	factory := informers.NewSharedInformerFactoryWithOptions(ctrl.kubeClient, 0,
+		informers.WithNamespace(conf.namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = "app=" + conf.label
+		}))
+	controllers := map[KubeResource]*KubeController{
+		KRDashboardService: newKubeController(KRDashboardService, factory,
+			func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return factory.Core().V1().Services().Informer()
+			}),
+		KRDashboardPod: newKubeController(KRDashboardPod, factory,
+			func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return factory.Core().V1().Pods().Informer()
+			}),
 	}
-	if err = os.Unsetenv(EnvLiqoDashPort); err != nil {
-		return err
+	//the Ingresses are not watched if the cluster does not serve any supported Ingress API
+	if ingressCtrl := ctrl.newDashboardIngressController(conf); ingressCtrl != nil {
+		controllers[KRDashboardIngress] = ingressCtrl
 	}
-	//preliminary check to verify the LiqoDash pod is running
-	var dashPodL *corev1.PodList
-	dashConf := ctrl.agentConf.dashboard
-	dashPodL, err = ctrl.kubeClient.CoreV1().Pods(dashConf.namespace).List(context.TODO(), metav1.ListOptions{
-		LabelSelector: "app=" + dashConf.label,
-		FieldSelector: fields.OneTermEqualSelector("status.phase", "Running").String(),
-	})
-	if err != nil {
-		return errors.New("cluster connection not available")
-	}
-	if len(dashPodL.Items) < 1 {
-		return errors.New("the LiqoDash is not currently available")
-	}
-	//check if all LiqoDash pods are ready to serve
-	ready := true
-loop:
-	for _, pod := range dashPodL.Items {
-		for _, condition := range pod.Status.Conditions {
-			if condition.Type == corev1.PodReady && condition.Status != corev1.ConditionTrue {
-				ready = false
-				break loop
-			}
+	for _, res := range []KubeResource{KRDashboardIngress, KRDashboardService} {
+		if _, present := controllers[res]; !present {
+			continue
 		}
+		controllers[res].addFunc = ctrl.dashboardResourceAddFunc
+		controllers[res].updateFunc = ctrl.dashboardResourceUpdateFunc
+		controllers[res].deleteFunc = ctrl.dashboardResourceDeleteFunc
 	}
-	if !ready {
-		return errors.New("the LiqoDash is not currently available")
-	}
-	/*-----------------------------------------------------------------------------------
-	CASE 1: check the presence of an ingress for the LiqoDash
-	-------------------------------------------------------------------------------------*/
-	var ok bool
-	if ok = ctrl.getDashboardConfigRemote(); !ok {
-		/*-----------------------------------------------------------------------------------
-		CASE 2: check the presence of a Service NodePort for the LiqoDash
-		-------------------------------------------------------------------------------------*/
-		if ok = ctrl.getDashboardConfigLocal(); !ok {
-			return errors.New("cannot establish a connection to LiqoDash")
+	controllers[KRDashboardPod].addFunc = ctrl.dashboardPodAddFunc
+	controllers[KRDashboardPod].updateFunc = ctrl.dashboardPodUpdateFunc
+	controllers[KRDashboardPod].deleteFunc = ctrl.dashboardPodDeleteFunc
+	r := ctrl.dashboard
+	r.mutex.Lock()
+	r.controllers = controllers
+	r.mutex.Unlock()
+	for _, res := range dashboardResources {
+		if _, present := controllers[res]; !present {
+			continue
+		}
+		if err := controllers[res].StartCache(ctrl.ctx, &ctrl.workers); err != nil {
+			ctrl.stopDashboardWatch()
+			return err
 		}
 	}
+	ctrl.refreshDashboard(true)
 	return nil
 }
 
-//getDashboardConfigRemote searches for a valid configuration required to
-//remotely connect with the LiqoDash.
-//
-//In case of success, it sets the env vars specified by EnvLiqoDashHost
-//and EnvLiqoDashPort with proper values.
-func (ctrl *AgentController) getDashboardConfigRemote() bool {
-	if !ctrl.Connected() || !ctrl.ValidConfiguration() {
-		return false
+//stopDashboardWatch stops the watch on the LiqoDash resources, invalidating the cached LiqoDash endpoint.
+func (ctrl *AgentController) stopDashboardWatch() {
+	r := ctrl.dashboard
+	r.mutex.Lock()
+	controllers := r.controllers
+	r.controllers = nil
+	r.mutex.Unlock()
+	for _, c := range controllers {
+		c.StopCache()
 	}
-	/*search for a LiqoDash Ingress. To increase security, it must contain
-	a 'tls' field with at least one explicitly specified 'host' (https connection)*/
-	dashConf := ctrl.agentConf.dashboard
-	ingrL, err := ctrl.kubeClient.NetworkingV1beta1().Ingresses(dashConf.namespace).List(context.TODO(), metav1.ListOptions{
-		LabelSelector: "app=" + dashConf.label,
-	})
-	if err != nil || len(ingrL.Items) < 1 {
+	ctrl.refreshDashboard(true)
+}
+
+//dashboardStore returns the cache of a LiqoDash resource, or nil if it is not watched.
+func (r *dashboardResolver) dashboardStore(resource KubeResource) cache.Store {
+	if c, present := r.controllers[resource]; present {
+		return c.Store
+	}
+	return nil
+}
+
+//podsReady returns whether there is at least a running LiqoDash pod and all of them are ready to serve.
+func (r *dashboardResolver) podsReady() bool {
+	store := r.dashboardStore(KRDashboardPod)
+	if store == nil {
 		return false
 	}
-	ingress := ingrL.Items[0]
-	if len(ingress.Spec.TLS) > 0 {
-		hosts := ingress.Spec.TLS[0].Hosts
-		if len(hosts) > 0 {
-			dashHost := hosts[0]
-			if err = os.Setenv(EnvLiqoDashHost, fmt.Sprintf(
-				"https://%s", dashHost)); err == nil {
-				if err = os.Setenv(EnvLiqoDashPort, ""); err == nil {
-					return true
-				}
-			}
+	running := 0
+	for _, obj := range store.List() {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		if !podReady(pod) {
+			return false
 		}
+		running++
 	}
-	return false
+	return running > 0
 }
 
-//getDashboardConfigLocal searches for a valid configuration required to
-//establish a local connection to the LiqoDash.
-//
-//In case of success, it sets the env vars specified by EnvLiqoDashHost
-//and EnvLiqoDashPort with proper values.
-func (ctrl *AgentController) getDashboardConfigLocal() bool {
+//refreshDashboard updates the readiness of LiqoDash and, if invalidate is true, removes the cached
+//LiqoDash endpoint. Any change is notified on the ChanDashboard NotifyChannel.
+func (ctrl *AgentController) refreshDashboard(invalidate bool) {
+	r := ctrl.dashboard
+	r.mutex.Lock()
+	old := r.endpoint
+	r.endpoint.Ready = r.podsReady()
+	if invalidate {
+		r.endpoint.URL = ""
+		r.endpoint.Forwarded = false
+		r.generation++
+	}
+	current := r.endpoint
+	r.mutex.Unlock()
+	if old.Forwarded && !current.Forwarded {
+		ctrl.stopDashboardForward()
+	}
+	if current != old {
+		ctrl.Notify(ChanDashboard, &current)
+	}
+}
+
+//DashboardStatus returns the current status of the LiqoDash endpoint.
+func (ctrl *AgentController) DashboardStatus() NotifyDataDashboard {
+	r := ctrl.dashboard
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.endpoint
+}
+
+//DashboardEndpoint returns the URL of LiqoDash. The URL is resolved on the first access, then it is cached
+//until the LiqoDash Ingress or Service change. Concurrent callers share the same resolution.
+func (ctrl *AgentController) DashboardEndpoint() (string, error) {
 	if !ctrl.Connected() || !ctrl.ValidConfiguration() {
-		return false
+		return "", errors.New("cluster connection not available")
 	}
-	c := ctrl.kubeClient
-	dashConf := ctrl.agentConf.dashboard
-	var nodePortNo, masterIP string
-	found := false
-	/*search for a LiqoDash Service of type NodePort*/
-	servL, err := c.CoreV1().Services(dashConf.namespace).List(context.TODO(), metav1.ListOptions{
-		LabelSelector: "app=" + dashConf.label,
-	})
-	if err != nil || len(servL.Items) < 1 {
-		return false
+	for attempt := 0; attempt < dashboardResolveAttempts; attempt++ {
+		res, started, err := ctrl.dashboardResolution()
+		if err != nil {
+			return "", err
+		}
+		if started {
+			ctrl.completeDashboardResolution(res)
+		}
+		<-res.done
+		if !res.stale {
+			return res.url, res.err
+		}
 	}
-	service := servL.Items[0]
-	if service.Spec.Type != corev1.ServiceTypeNodePort {
-		return false
+	return "", errors.New("the LiqoDash endpoint keeps changing")
+}
+
+//dashboardResolution returns the resolution of the LiqoDash endpoint that provides the URL to a caller. If the
+//endpoint is cached, the resolution is already completed; otherwise the caller shares the resolution in
+//progress or, if there is none, it has to complete the one just started (started = true).
+func (ctrl *AgentController) dashboardResolution() (res *dashboardResolution, started bool, err error) {
+	r := ctrl.dashboard
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.endpoint.Ready {
+		return nil, false, errors.New("the LiqoDash is not currently available")
+	}
+	if r.endpoint.URL != "" {
+		res = &dashboardResolution{generation: r.generation, done: make(chan struct{}), url: r.endpoint.URL}
+		close(res.done)
+		return res, false, nil
 	}
-	ports := service.Spec.Ports
-	for i := range ports {
-		port := ports[i]
+	//a single resolution at a time, so that a port-forward never replaces the one of another resolution
+	if r.resolution != nil {
+		return r.resolution, false, nil
+	}
+	r.resolution = &dashboardResolution{generation: r.generation, done: make(chan struct{})}
+	return r.resolution, true, nil
+}
+
+//completeDashboardResolution resolves the LiqoDash endpoint, caching it unless it has been invalidated
+//in the meanwhile.
+func (ctrl *AgentController) completeDashboardResolution(res *dashboardResolution) {
+	r := ctrl.dashboard
+	r.mutex.Lock()
+	resolve := r.resolve
+	r.mutex.Unlock()
+	url, forwarded, err := resolve()
+	r.mutex.Lock()
+	stale := res.generation != r.generation
+	old := r.endpoint
+	if err == nil && !stale {
+		r.endpoint.URL = url
+		r.endpoint.Forwarded = forwarded
+	}
+	current := r.endpoint
+	r.mutex.Unlock()
+	//the port-forward of an invalidated endpoint is stopped before another resolution can start
+	if stale && forwarded {
+		ctrl.stopDashboardForward()
+	}
+	r.mutex.Lock()
+	r.resolution = nil
+	r.mutex.Unlock()
+	res.url, res.err, res.stale = url, err, stale
+	close(res.done)
+	if current != old {
+		ctrl.Notify(ChanDashboard, &current)
+	}
+}
+
+//resolveDashboard tries to retrieve the URL of LiqoDash, returning whether it points to a port-forward.
+func (ctrl *AgentController) resolveDashboard() (string, bool, error) {
+	/*-----------------------------------------------------------------------------------
+	CASE 1: check the presence of an ingress for the LiqoDash
+	-------------------------------------------------------------------------------------*/
+	if url, ok, err := ctrl.dashboardIngressURL(); ok || err != nil {
+		return url, false, err
+	}
+	/*-----------------------------------------------------------------------------------
+	CASE 2: check the presence of a Service NodePort for the LiqoDash
+	-------------------------------------------------------------------------------------*/
+	if url, ok := ctrl.dashboardNodePortURL(); ok {
+		return url, false, nil
+	}
+	/*-----------------------------------------------------------------------------------
+	CASE 3: port-forward the LiqoDash Service through the API server
+	-------------------------------------------------------------------------------------*/
+	if url, ok := ctrl.dashboardForwardURL(); ok {
+		return url, true, nil
+	}
+	return "", false, errors.New("cannot establish a connection to LiqoDash")
+}
+
+//dashboardService returns the LiqoDash Service, if present.
+func (ctrl *AgentController) dashboardService() (*corev1.Service, bool) {
+	r := ctrl.dashboard
+	r.mutex.Lock()
+	store := r.dashboardStore(KRDashboardService)
+	r.mutex.Unlock()
+	if store == nil {
+		return nil, false
+	}
+	for _, obj := range store.List() {
+		if service, ok := obj.(*corev1.Service); ok {
+			return service, true
+		}
+	}
+	return nil, false
+}
+
+//dashboardNodePortURL searches for a valid configuration required to
+//establish a local connection to the LiqoDash.
+func (ctrl *AgentController) dashboardNodePortURL() (string, bool) {
+	/*search for a LiqoDash Service of type NodePort*/
+	service, ok := ctrl.dashboardService()
+	if !ok || service.Spec.Type != corev1.ServiceTypeNodePort {
+		return "", false
+	}
+	var nodePortNo, masterIP string
+	found := false
+	for _, port := range service.Spec.Ports {
 		if port.Name == "https" {
 			nodePortNo = fmt.Sprint(port.NodePort)
 			found = true
@@ -148,7 +352,9 @@ func (ctrl *AgentController) getDashboardConfigLocal() bool {
 	For the local connection, the master node IP address will be used.*/
 	if found {
 		found = false
-		nodeL, err := c.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
+		ctx, cancel := ctrl.requestContext()
+		defer cancel()
+		nodeL, err := ctrl.kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{
 			LabelSelector: masterNodeLabel,
 		})
 		if err == nil && len(nodeL.Items) > 0 {
@@ -161,37 +367,120 @@ func (ctrl *AgentController) getDashboardConfigLocal() bool {
 			}
 		}
 	}
-	if found {
-		/*having found both address and port, it is possible to set
-		the two env vars*/
-		if err = os.Setenv(EnvLiqoDashHost, fmt.Sprintf(
-			"https://%s", masterIP)); err == nil {
-			if err = os.Setenv(EnvLiqoDashPort, nodePortNo); err == nil {
-				return true
-			}
+	//the master node IP may not be reachable from the host, e.g. on kind or k3d clusters
+	if !found || !endpointReachable(masterIP, nodePortNo) {
+		return "", false
+	}
+	return fmt.Sprintf("https://%s", net.JoinHostPort(masterIP, nodePortNo)), true
+}
+
+//endpointReachable returns whether a TCP connection can be established with host:port.
+func endpointReachable(host string, port string) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), dashboardDialTimeout)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+//dashboardForwardURL exposes the LiqoDash Service on a random port of localhost, using a port-forward
+//through the API server. It allows to reach LiqoDash even with ClusterIP Services.
+//
+//The port-forward is kept alive until the LiqoDash endpoint is invalidated.
+func (ctrl *AgentController) dashboardForwardURL() (string, bool) {
+	service, ok := ctrl.dashboardService()
+	if !ok || len(service.Spec.Ports) < 1 {
+		return "", false
+	}
+	port := service.Spec.Ports[0].Port
+	for _, p := range service.Spec.Ports {
+		if p.Name == "https" {
+			port = p.Port
+			break
 		}
 	}
-	return false
+	fw, err := ctrl.ForwardService(service.Namespace, service.Name, port)
+	if err != nil {
+		return "", false
+	}
+	ctrl.portForwards.setDashboard(fw.Key)
+	return fmt.Sprintf("https://localhost:%d", fw.LocalPort), true
+}
+
+//			**** EVENT FUNCTIONS ****
+//	The following functions are the callbacks the dashboardResolver uses to handle the events
+//	of the LiqoDash caches.
+
+//dashboardResourceAddFunc is the ADD event handler for the LiqoDash Ingresses and Services.
+func (ctrl *AgentController) dashboardResourceAddFunc(_ interface{}) {
+	ctrl.refreshDashboard(true)
+}
+
+//dashboardResourceUpdateFunc is the UPDATE event handler for the LiqoDash Ingresses and Services.
+func (ctrl *AgentController) dashboardResourceUpdateFunc(_ interface{}, _ interface{}) {
+	ctrl.refreshDashboard(true)
 }
 
-//GetLiqoDashSecret returns the access token for the LiqoDash service.
-func (ctrl *AgentController) GetLiqoDashSecret() (*string, error) {
-	var token = ""
+//dashboardResourceDeleteFunc is the DELETE event handler for the LiqoDash Ingresses and Services.
+func (ctrl *AgentController) dashboardResourceDeleteFunc(_ interface{}) {
+	ctrl.refreshDashboard(true)
+}
+
+//dashboardPodAddFunc is the ADD event handler for the LiqoDash pods.
+func (ctrl *AgentController) dashboardPodAddFunc(_ interface{}) {
+	ctrl.refreshDashboard(false)
+}
+
+//dashboardPodUpdateFunc is the UPDATE event handler for the LiqoDash pods.
+func (ctrl *AgentController) dashboardPodUpdateFunc(_ interface{}, _ interface{}) {
+	ctrl.refreshDashboard(false)
+}
+
+//dashboardPodDeleteFunc is the DELETE event handler for the LiqoDash pods.
+func (ctrl *AgentController) dashboardPodDeleteFunc(_ interface{}) {
+	ctrl.refreshDashboard(false)
+}
+
+//DashboardToken is an access token for the LiqoDash service.
+type DashboardToken struct {
+	//Token is the bearer token.
+	Token string
+	//Expiration is the expiration time of a short-lived token. It is zero for a legacy Secret token.
+	Expiration time.Time
+}
+
+//GetLiqoDashToken returns an access token for the LiqoDash service.
+//
+//A short-lived token bound to the LiqoDash ServiceAccount is requested via the TokenRequest API. If it is
+//not available, the token stored in the legacy ServiceAccount Secret is returned.
+func (ctrl *AgentController) GetLiqoDashToken() (*DashboardToken, error) {
 	if !ctrl.Connected() || !ctrl.ValidConfiguration() {
-		return &token, errors.New("no connection to the cluster")
+		return nil, errors.New("no connection to the cluster")
 	}
 	errNoToken := errors.New("cannot retrieve token")
-	/*In order to better prune its search, the secret is retrieved by its name, using the
-	service account associated with it.*/
+	ctx, cancel := ctrl.requestContext()
+	defer cancel()
 	c := ctrl.kubeClient
-	dashConf := ctrl.agentConf.dashboard
-	ServiceAccountsL, err := c.CoreV1().ServiceAccounts(dashConf.namespace).List(context.TODO(), metav1.ListOptions{
+	dashConf := ctrl.agentConf.dashboardConfig()
+	ServiceAccountsL, err := c.CoreV1().ServiceAccounts(dashConf.namespace).List(ctx, metav1.ListOptions{
 		LabelSelector: "app=" + dashConf.label,
 	})
 	if err != nil || len(ServiceAccountsL.Items) < 1 {
-		return &token, errNoToken
+		return nil, errNoToken
 	}
 	liqoDashSA := ServiceAccountsL.Items[0]
+	expiration := dashboardTokenExpiration
+	tokenReq, err := c.CoreV1().ServiceAccounts(dashConf.namespace).CreateToken(ctx, liqoDashSA.Name,
+		&authv1.TokenRequest{Spec: authv1.TokenRequestSpec{ExpirationSeconds: &expiration}}, metav1.CreateOptions{})
+	if err == nil && tokenReq.Status.Token != "" {
+		return &DashboardToken{
+			Token:      tokenReq.Status.Token,
+			Expiration: tokenReq.Status.ExpirationTimestamp.Time,
+		}, nil
+	}
+	/*In order to better prune its search, the secret is retrieved by its name, using the
+	service account associated with it.*/
 	found := false
 	var secretName string
 	tokenPrefixName := liqoDashSA.Name + "-token"
@@ -204,10 +493,9 @@ func (ctrl *AgentController) GetLiqoDashSecret() (*string, error) {
 		}
 	}
 	if found {
-		if secret, err := c.CoreV1().Secrets(dashConf.namespace).Get(context.TODO(), secretName, metav1.GetOptions{}); err == nil {
-			token = fmt.Sprintf("%s", secret.Data["token"])
-			return &token, nil
+		if secret, err := c.CoreV1().Secrets(dashConf.namespace).Get(ctx, secretName, metav1.GetOptions{}); err == nil {
+			return &DashboardToken{Token: fmt.Sprintf("%s", secret.Data["token"])}, nil
 		}
 	}
-	return &token, errNoToken
+	return nil, errNoToken
 }
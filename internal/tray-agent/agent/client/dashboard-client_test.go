@@ -0,0 +1,255 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDashboardEndpointReachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	assert.True(t, endpointReachable("127.0.0.1", port), "listening endpoint not reachable")
+	_ = listener.Close()
+	assert.False(t, endpointReachable("127.0.0.1", port), "closed endpoint reachable")
+}
+
+func TestDashboardResolver(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	_, err := ctrl.DashboardEndpoint()
+	assert.Error(t, err, "LiqoDash endpoint resolved without a configuration")
+	conf, _ := createClusterConfig()
+	namespace := conf.Spec.AgentConfig.DashboardConfig.Namespace
+	label := map[string]string{"app": conf.Spec.AgentConfig.DashboardConfig.AppLabel}
+	ccCtrl := ctrl.Controller(CRClusterConfig)
+	if err = ccCtrl.Store.Add(conf); err != nil {
+		t.Fatal(err)
+	}
+	waitNotifyData(t, ctrl, ChanClusterConfig)
+	defer func() {
+		_ = ccCtrl.Store.Delete(conf)
+	}()
+	//the cluster serves the networking/v1 Ingresses: the watch is restarted to detect them
+	ctrl.kubeClient.(*fake.Clientset).Resources = []*metav1.APIResourceList{{
+		GroupVersion: dashboardIngressVersions[0].GroupVersion().String(),
+		APIResources: []metav1.APIResource{{Name: dashboardIngressVersions[0].Resource, Namespaced: true}},
+	}}
+	assert.NoError(t, ctrl.watchDashboard(ctrl.agentConf.dashboardConfig()), "LiqoDash watch not restarted")
+	assert.False(t, ctrl.DashboardStatus().Ready, "LiqoDash ready without pods")
+	//LiqoDash pod becomes ready
+	c := ctrl.kubeClient
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "liqodash", Namespace: namespace, Labels: label},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	if _, err = c.CoreV1().Pods(namespace).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data := waitNotifyData(t, ctrl, ChanDashboard).(*NotifyDataDashboard)
+	assert.True(t, data.Ready, "LiqoDash readiness not notified")
+	//no Ingress or Service available
+	_, err = ctrl.DashboardEndpoint()
+	assert.Error(t, err, "LiqoDash endpoint resolved without Ingress or Service")
+	//TLS Ingress
+	ingresses := ctrl.dynClient.Resource(dashboardIngressVersions[0]).Namespace(namespace)
+	ingress := testDashboardIngress("liqodash", label, "dash.liqo.io")
+	if _, err = ingresses.Create(context.TODO(), ingress, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Eventually(t, func() bool {
+		url, err := ctrl.DashboardEndpoint()
+		return err == nil && url == "https://dash.liqo.io"
+	}, time.Second, time.Millisecond*10, "LiqoDash Ingress not resolved")
+	data = waitNotifyData(t, ctrl, ChanDashboard).(*NotifyDataDashboard)
+	assert.Equal(t, "https://dash.liqo.io", data.URL, "LiqoDash endpoint not notified")
+	//several candidates: the user has to choose one
+	ingress = testDashboardIngress("liqodash-alt", label, "alt.liqo.io", "/dashboard/", "/liqo")
+	if _, err = ingresses.Create(context.TODO(), ingress, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitNotifyData(t, ctrl, ChanDashboard)
+	candidates := []string{"https://alt.liqo.io/dashboard", "https://alt.liqo.io/liqo", "https://dash.liqo.io"}
+	_, err = ctrl.DashboardEndpoint()
+	if choiceErr, ok := err.(*DashboardChoiceError); assert.True(t, ok, "LiqoDash candidates not returned") {
+		assert.Equal(t, candidates, choiceErr.Candidates, "wrong LiqoDash candidates")
+	}
+	_ = SaveDashboardChoice(candidates[1])
+	defer func() {
+		NewLocalConfig().Valid = false
+	}()
+	url, err := ctrl.DashboardEndpoint()
+	if assert.NoError(t, err, "chosen LiqoDash URL not resolved") {
+		assert.Equal(t, candidates[1], url, "chosen LiqoDash URL not remembered")
+	}
+	waitNotifyData(t, ctrl, ChanDashboard)
+	//the cached endpoint is invalidated when an Ingress is removed
+	if err = ingresses.Delete(context.TODO(), "liqodash-alt", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data = waitNotifyData(t, ctrl, ChanDashboard).(*NotifyDataDashboard)
+	assert.Empty(t, data.URL, "LiqoDash endpoint not invalidated")
+	assert.True(t, data.Ready, "LiqoDash readiness lost")
+}
+
+func TestLiqoDashToken(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	conf, _ := createClusterConfig()
+	namespace := conf.Spec.AgentConfig.DashboardConfig.Namespace
+	label := map[string]string{"app": conf.Spec.AgentConfig.DashboardConfig.AppLabel}
+	ccCtrl := ctrl.Controller(CRClusterConfig)
+	if err := ccCtrl.Store.Add(conf); err != nil {
+		t.Fatal(err)
+	}
+	waitNotifyData(t, ctrl, ChanClusterConfig)
+	defer func() {
+		_ = ccCtrl.Store.Delete(conf)
+	}()
+	_, err := ctrl.GetLiqoDashToken()
+	assert.Error(t, err, "token retrieved without a ServiceAccount")
+	c := ctrl.kubeClient.(*fake.Clientset)
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "liqodash", Namespace: namespace, Labels: label},
+		Secrets:    []corev1.ObjectReference{{Name: "liqodash-token-abcde"}},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "liqodash-token-abcde", Namespace: namespace},
+		Data:       map[string][]byte{"token": []byte("legacy")},
+	}
+	if _, err = c.CoreV1().ServiceAccounts(namespace).Create(context.TODO(), sa, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.CoreV1().Secrets(namespace).Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	//TokenRequest API not available: fall back to the legacy Secret
+	c.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("token requests not supported")
+	})
+	token, err := ctrl.GetLiqoDashToken()
+	if assert.NoError(t, err, "legacy token not retrieved") {
+		assert.Equal(t, "legacy", token.Token, "wrong legacy token")
+		assert.True(t, token.Expiration.IsZero(), "legacy token should not expire")
+	}
+	//short-lived token
+	expiration := metav1.NewTime(time.Now().Add(time.Hour))
+	c.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		req := action.(k8stesting.CreateAction).GetObject().(*authv1.TokenRequest)
+		assert.Equal(t, dashboardTokenExpiration, *req.Spec.ExpirationSeconds, "wrong token lifetime requested")
+		return true, &authv1.TokenRequest{Status: authv1.TokenRequestStatus{
+			Token:               "bound",
+			ExpirationTimestamp: expiration,
+		}}, nil
+	})
+	token, err = ctrl.GetLiqoDashToken()
+	if assert.NoError(t, err, "short-lived token not retrieved") {
+		assert.Equal(t, "bound", token.Token, "wrong short-lived token")
+		assert.False(t, token.Expiration.IsZero(), "short-lived token should expire")
+	}
+}
+
+//testDashboardIngress returns a networking/v1 Ingress exposing a TLS host on the given paths.
+func testDashboardIngress(name string, labels map[string]string, host string, paths ...string) *unstructured.Unstructured {
+	ingress := &unstructured.Unstructured{}
+	ingress.SetAPIVersion("networking.k8s.io/v1")
+	ingress.SetKind("Ingress")
+	ingress.SetName(name)
+	ingress.SetLabels(labels)
+	var httpPaths []interface{}
+	for _, path := range paths {
+		httpPaths = append(httpPaths, map[string]interface{}{"path": path})
+	}
+	ingress.Object["spec"] = map[string]interface{}{
+		"tls": []interface{}{map[string]interface{}{"hosts": []interface{}{host}}},
+		"rules": []interface{}{map[string]interface{}{
+			"host": host,
+			"http": map[string]interface{}{"paths": httpPaths},
+		}},
+	}
+	return ingress
+}
+
+func TestDashboardEndpointResolution(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	conf, _ := createClusterConfig()
+	namespace := conf.Spec.AgentConfig.DashboardConfig.Namespace
+	ccCtrl := ctrl.Controller(CRClusterConfig)
+	if err := ccCtrl.Store.Add(conf); err != nil {
+		t.Fatal(err)
+	}
+	waitNotifyData(t, ctrl, ChanClusterConfig)
+	defer func() {
+		_ = ccCtrl.Store.Delete(conf)
+	}()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "liqodash", Namespace: namespace,
+			Labels: map[string]string{"app": conf.Spec.AgentConfig.DashboardConfig.AppLabel}},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	if _, err := ctrl.kubeClient.CoreV1().Pods(namespace).Create(context.TODO(), pod,
+		metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitNotifyData(t, ctrl, ChanDashboard)
+	//the resolutions block until released
+	r := ctrl.dashboard
+	urls := make(chan string, 2)
+	calls := make(chan struct{}, 2)
+	r.mutex.Lock()
+	r.resolve = func() (string, bool, error) {
+		calls <- struct{}{}
+		return <-urls, false, nil
+	}
+	r.mutex.Unlock()
+	type result struct {
+		url string
+		err error
+	}
+	results := make(chan result, 3)
+	for j := 0; j < 3; j++ {
+		go func() {
+			url, err := ctrl.DashboardEndpoint()
+			results <- result{url, err}
+		}()
+	}
+	//the concurrent callers share a single resolution
+	<-calls
+	//the endpoint is invalidated while it is resolved: the stale URL is discarded and resolved again
+	ctrl.refreshDashboard(true)
+	urls <- "https://stale.liqo.io"
+	<-calls
+	urls <- "https://dash.liqo.io"
+	for j := 0; j < 3; j++ {
+		res := <-results
+		if assert.NoError(t, res.err, "LiqoDash endpoint not resolved") {
+			assert.Equal(t, "https://dash.liqo.io", res.url, "stale LiqoDash endpoint returned")
+		}
+	}
+	assert.Empty(t, calls, "LiqoDash endpoint resolved more than once per invalidation")
+	assert.Equal(t, "https://dash.liqo.io", ctrl.DashboardStatus().URL, "LiqoDash endpoint not cached")
+}
@@ -0,0 +1,171 @@
+package client
+
+import (
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"sort"
+	"strings"
+)
+
+//dashboardIngressVersions contains the Ingress API versions supported for the LiqoDash discovery,
+//in order of preference.
+var dashboardIngressVersions = []schema.GroupVersionResource{
+	{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+	{Group: "networking.k8s.io", Version: "v1beta1", Resource: "ingresses"},
+	{Group: "extensions", Version: "v1beta1", Resource: "ingresses"},
+}
+
+//dashboardIngress maps the fields of an Ingress used to discover the LiqoDash URLs. They are common
+//to all the supported Ingress API versions.
+type dashboardIngress struct {
+	Spec struct {
+		TLS []struct {
+			Hosts []string `json:"hosts,omitempty"`
+		} `json:"tls,omitempty"`
+		Rules []struct {
+			Host string `json:"host,omitempty"`
+			HTTP *struct {
+				Paths []struct {
+					Path string `json:"path,omitempty"`
+				} `json:"paths"`
+			} `json:"http,omitempty"`
+		} `json:"rules,omitempty"`
+	} `json:"spec"`
+}
+
+//DashboardChoiceError is returned when several LiqoDash URLs are available and none of them has been chosen
+//with SaveDashboardChoice.
+type DashboardChoiceError struct {
+	//Candidates are the available LiqoDash URLs.
+	Candidates []string
+}
+
+//Error returns the description of the DashboardChoiceError.
+func (e *DashboardChoiceError) Error() string {
+	return fmt.Sprintf("%d LiqoDash addresses are available", len(e.Candidates))
+}
+
+//dashboardIngressResource returns the most recent Ingress API version served by the cluster.
+func (ctrl *AgentController) dashboardIngressResource() (schema.GroupVersionResource, bool) {
+	for _, gvr := range dashboardIngressVersions {
+		resL, err := ctrl.kubeClient.Discovery().ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+		if err != nil {
+			continue
+		}
+		for _, res := range resL.APIResources {
+			if res.Name == gvr.Resource {
+				return gvr, true
+			}
+		}
+	}
+	return schema.GroupVersionResource{}, false
+}
+
+//newDashboardIngressController creates a KubeController for the LiqoDash Ingresses, using the most recent
+//Ingress API version served by the cluster. It returns nil if no Ingress API is available.
+func (ctrl *AgentController) newDashboardIngressController(conf dashConfig) *KubeController {
+	gvr, ok := ctrl.dashboardIngressResource()
+	if !ok {
+		return nil
+	}
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(ctrl.dynClient, 0, conf.namespace,
+		func(options *metav1.ListOptions) {
+			options.LabelSelector = "app=" + conf.label
+		})
+	informer := factory.ForResource(gvr).Informer()
+	return &KubeController{
+		Store:    informer.GetStore(),
+		informer: informer,
+		resource: KRDashboardIngress,
+	}
+}
+
+//dashboardIngressURLs returns the LiqoDash URLs exposed by the LiqoDash Ingresses, sorted.
+//
+//To increase security, only the hosts explicitly specified in a 'tls' field are considered (https connection).
+//If the rule of a host specifies some paths, an URL for each path is returned.
+func (ctrl *AgentController) dashboardIngressURLs() []string {
+	r := ctrl.dashboard
+	r.mutex.Lock()
+	store := r.dashboardStore(KRDashboardIngress)
+	r.mutex.Unlock()
+	if store == nil {
+		return nil
+	}
+	urlSet := make(map[string]bool)
+	for _, obj := range store.List() {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		ingress := &dashboardIngress{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, ingress); err != nil {
+			continue
+		}
+		for _, tls := range ingress.Spec.TLS {
+			for _, host := range tls.Hosts {
+				for _, path := range ingress.hostPaths(host) {
+					urlSet["https://"+host+path] = true
+				}
+			}
+		}
+	}
+	urls := make([]string, 0, len(urlSet))
+	for url := range urlSet {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+//hostPaths returns the paths the Ingress exposes for a host, without the trailing slash.
+func (ingress *dashboardIngress) hostPaths(host string) []string {
+	var paths []string
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != host || rule.HTTP == nil {
+			continue
+		}
+		for _, p := range rule.HTTP.Paths {
+			paths = append(paths, strings.TrimSuffix(p.Path, "/"))
+		}
+	}
+	if len(paths) == 0 {
+		paths = append(paths, "")
+	}
+	return paths
+}
+
+//dashboardIngressURL returns the LiqoDash URL exposed by the LiqoDash Ingresses. If several URLs are
+//available, the one chosen with SaveDashboardChoice is returned, otherwise a DashboardChoiceError.
+func (ctrl *AgentController) dashboardIngressURL() (string, bool, error) {
+	urls := ctrl.dashboardIngressURLs()
+	switch len(urls) {
+	case 0:
+		return "", false, nil
+	case 1:
+		return urls[0], true, nil
+	}
+	conf, _ := GetLocalConfig()
+	choice := conf.GetDashboardURL()
+	for _, url := range urls {
+		if url == choice {
+			return url, true, nil
+		}
+	}
+	return "", false, &DashboardChoiceError{Candidates: urls}
+}
+
+//SaveDashboardChoice saves the LiqoDash URL the user prefers among the available ones.
+func SaveDashboardChoice(url string) error {
+	conf, valid := GetLocalConfig()
+	if !valid {
+		conf = NewLocalConfig()
+		conf.Valid = true
+	}
+	conf.SetDashboardURL(url)
+	return SaveLocalConfig()
+}
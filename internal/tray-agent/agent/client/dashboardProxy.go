@@ -0,0 +1,272 @@
+package client
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// set of parameters of the LiqoDash authentication proxy
+const (
+	//dashboardProxyLogin is the path of the proxy that starts a browser session, redirecting to the
+	//'next' LiqoDash path.
+	dashboardProxyLogin = "/liqo-agent/login"
+	//dashboardProxyCookie is the name of the cookie storing the session key in the browser.
+	dashboardProxyCookie = "liqo-agent-session"
+	//dashboardTokenRenewal is the interval before its expiration in which the LiqoDash token is renewed.
+	dashboardTokenRenewal = time.Minute * 5
+)
+
+/*dashboardProxy is a reverse proxy, listening on the loopback interface, that forwards the requests
+to the LiqoDash backend injecting the LiqoDash access token.
+
+Since any local process can connect to the loopback interface, the requests are served only if they
+carry the session key, which is handed to the browser opened by the Agent through the login URL
+and then stored in an http-only cookie.*/
+type dashboardProxy struct {
+	//server is the http server of the proxy. It is nil when the proxy is not running.
+	server *http.Server
+	//address is the local address the proxy is listening on.
+	address string
+	//session is the key authorizing the browser requests for the current session.
+	session string
+	//target is the current LiqoDash endpoint.
+	target *url.URL
+	//token is the LiqoDash access token injected in the requests.
+	token *DashboardToken
+	//renew requests a new LiqoDash access token.
+	renew func() (*DashboardToken, error)
+	//renewal is the renewal of the token in progress, if any.
+	renewal *tokenRenewal
+	mutex   sync.Mutex
+}
+
+//tokenRenewal is a request for a new LiqoDash access token, shared by all the callers that need the token
+//while it is in progress.
+type tokenRenewal struct {
+	//done is closed when the renewal is completed.
+	done  chan struct{}
+	token *DashboardToken
+	err   error
+}
+
+//newSessionKey returns a random key for a dashboardProxy session.
+func newSessionKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+//DashboardProxyURL returns a local URL that opens the LiqoDash page at 'path' already authenticated.
+//The authentication proxy is started on the loopback interface at the first call and it forwards
+//the requests to 'endpoint', the current LiqoDash endpoint, until StopDashboardProxy is called.
+func (ctrl *AgentController) DashboardProxyURL(endpoint string, path string) (string, error) {
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	p := ctrl.dashProxy
+	p.mutex.Lock()
+	p.renew = ctrl.GetLiqoDashToken
+	p.mutex.Unlock()
+	if _, err := p.currentToken(); err != nil {
+		return "", err
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.target = target
+	if p.server == nil {
+		if err := ctrl.startDashboardProxy(); err != nil {
+			return "", err
+		}
+	}
+	if p.session == "" {
+		if p.session, err = newSessionKey(); err != nil {
+			return "", err
+		}
+	}
+	query := url.Values{}
+	query.Set("session", p.session)
+	query.Set("next", "/"+strings.TrimPrefix(path, "/"))
+	return fmt.Sprintf("http://%s%s?%s", p.address, dashboardProxyLogin, query.Encode()), nil
+}
+
+//startDashboardProxy starts the LiqoDash authentication proxy on a free port of the loopback interface.
+//The caller must hold the dashboardProxy mutex.
+func (ctrl *AgentController) startDashboardProxy() error {
+	p := ctrl.dashProxy
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	proxy := &httputil.ReverseProxy{
+		Director:  p.direct,
+		Transport: dashboardTransport{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(dashboardProxyLogin, p.login)
+	mux.Handle("/", p.authorize(proxy))
+	p.server = &http.Server{Handler: mux}
+	p.address = listener.Addr().String()
+	server := p.server
+	ctrl.workers.Go(func() {
+		_ = server.Serve(listener)
+	})
+	return nil
+}
+
+//forwardedDashboardTransport is the http.RoundTripper used to reach a port-forwarded LiqoDash, whose
+//certificate cannot match the local address.
+var forwardedDashboardTransport = &http.Transport{
+	TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+}
+
+//dashboardTransport is the http.RoundTripper of the LiqoDash authentication proxy.
+type dashboardTransport struct{}
+
+//RoundTrip executes a request for the LiqoDash backend.
+func (dashboardTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Hostname() == "localhost" {
+		return forwardedDashboardTransport.RoundTrip(r)
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+//StopDashboardProxy stops the LiqoDash authentication proxy, invalidating the browser sessions.
+func (ctrl *AgentController) StopDashboardProxy() {
+	p := ctrl.dashProxy
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.server != nil {
+		_ = p.server.Close()
+	}
+	p.server = nil
+	p.address = ""
+	p.session = ""
+	p.token = nil
+	p.renew = nil
+	p.renewal = nil
+	p.target = nil
+}
+
+//validSession checks whether key is the key of the current session.
+func (p *dashboardProxy) validSession(key string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.session != "" && subtle.ConstantTimeCompare([]byte(key), []byte(p.session)) == 1
+}
+
+//login starts a browser session, storing the session key in a cookie.
+func (p *dashboardProxy) login(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("session")
+	if !p.validSession(key) {
+		http.Error(w, "invalid Liqo Agent session", http.StatusForbidden)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     dashboardProxyCookie,
+		Value:    key,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	next := r.URL.Query().Get("next")
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") {
+		next = "/"
+	}
+	http.Redirect(w, r, next, http.StatusFound)
+}
+
+//authorize serves only the requests of the current browser session.
+func (p *dashboardProxy) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(dashboardProxyCookie)
+		if err != nil || !p.validSession(cookie.Value) {
+			http.Error(w, "invalid Liqo Agent session", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+//currentToken returns the LiqoDash access token, requesting a new one if it is going to expire.
+//The token is renewed without holding the dashboardProxy mutex, so that the requests of the browser session
+//are not blocked by the API server. Concurrent callers share the same renewal.
+func (p *dashboardProxy) currentToken() (*DashboardToken, error) {
+	p.mutex.Lock()
+	if p.token != nil && !p.token.expiring() {
+		token := p.token
+		p.mutex.Unlock()
+		return token, nil
+	}
+	if p.renew == nil {
+		p.mutex.Unlock()
+		return nil, errors.New("no LiqoDash access token available")
+	}
+	if r := p.renewal; r != nil {
+		p.mutex.Unlock()
+		<-r.done
+		return r.token, r.err
+	}
+	r := &tokenRenewal{done: make(chan struct{})}
+	p.renewal = r
+	renew := p.renew
+	p.mutex.Unlock()
+	r.token, r.err = renew()
+	p.mutex.Lock()
+	//the proxy may have been stopped in the meanwhile
+	if p.renewal == r {
+		p.renewal = nil
+		if r.err == nil {
+			p.token = r.token
+		}
+	}
+	p.mutex.Unlock()
+	close(r.done)
+	return r.token, r.err
+}
+
+//direct rewrites a request for the LiqoDash backend, injecting the access token.
+func (p *dashboardProxy) direct(r *http.Request) {
+	p.mutex.Lock()
+	target := p.target
+	p.mutex.Unlock()
+	if target == nil {
+		return
+	}
+	token, err := p.currentToken()
+	if err != nil {
+		return
+	}
+	r.URL.Scheme = target.Scheme
+	r.URL.Host = target.Host
+	r.URL.Path = strings.TrimSuffix(target.Path, "/") + "/" + strings.TrimPrefix(r.URL.Path, "/")
+	r.URL.RawPath = ""
+	r.Host = target.Host
+	r.Header.Set("Authorization", "Bearer "+token.Token)
+	//the session cookie is meaningful only for the proxy
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != dashboardProxyCookie {
+			r.AddCookie(c)
+		}
+	}
+}
+
+//expiring checks whether the DashboardToken is going to expire soon.
+func (t *DashboardToken) expiring() bool {
+	return !t.Expiration.IsZero() && time.Now().Add(dashboardTokenRenewal).After(t.Expiration)
+}
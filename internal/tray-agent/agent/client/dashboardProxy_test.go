@@ -0,0 +1,99 @@
+package client
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDashboardProxy(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path + " " + r.Header.Get("Authorization") + " " + r.Header.Get("Cookie")))
+	}))
+	defer backend.Close()
+	//the mocked AgentController has no connection to request a token
+	_, err := ctrl.DashboardProxyURL(backend.URL+"/dashboard", "/pods")
+	assert.Error(t, err, "proxy started without a token")
+	ctrl.dashProxy.token = &DashboardToken{Token: "test-token"}
+	loginURL, err := ctrl.DashboardProxyURL(backend.URL+"/dashboard", "/pods")
+	if !assert.NoError(t, err, "proxy not started") {
+		t.FailNow()
+	}
+	defer ctrl.StopDashboardProxy()
+	assert.True(t, strings.HasPrefix(loginURL, "http://127.0.0.1:"), "proxy not bound to loopback")
+	//requests outside the session are refused
+	resp, err := http.Get("http://" + ctrl.dashProxy.address + "/pods")
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, "request served without session")
+		_ = resp.Body.Close()
+	}
+	resp, err = http.Get("http://" + ctrl.dashProxy.address + dashboardProxyLogin + "?session=wrong")
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, "session started with a wrong key")
+		_ = resp.Body.Close()
+	}
+	//the browser session is authenticated
+	jar, _ := cookiejar.New(nil)
+	browser := &http.Client{Jar: jar}
+	resp, err = browser.Get(loginURL)
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "/dashboard/pods Bearer test-token ", string(body),
+			"request not forwarded with the access token")
+	}
+	ctrl.StopDashboardProxy()
+	_, err = browser.Get(loginURL)
+	assert.Error(t, err, "proxy still running after the session end")
+}
+
+func TestDashboardProxyRenewal(t *testing.T) {
+	p := &dashboardProxy{session: "key"}
+	var calls int32
+	release := make(chan struct{})
+	p.renew = func() (*DashboardToken, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &DashboardToken{Token: "renewed"}, nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := p.currentToken()
+			if assert.NoError(t, err, "token not renewed") {
+				assert.Equal(t, "renewed", token.Token, "wrong token returned")
+			}
+		}()
+	}
+	//the sessions are checked while the token is being renewed
+	checked := make(chan bool)
+	go func() {
+		checked <- p.validSession("key")
+	}()
+	select {
+	case valid := <-checked:
+		assert.True(t, valid, "valid session refused")
+	case <-time.After(time.Second * 5):
+		t.Fatal("session check blocked by the token renewal")
+	}
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "token renewed more than once")
+	token, err := p.currentToken()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "renewed", token.Token, "renewed token not stored")
+	}
+}
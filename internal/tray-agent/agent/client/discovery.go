@@ -0,0 +1,60 @@
+package client
+
+import (
+	"errors"
+	clusterConfig "github.com/liqotech/liqo/apis/config/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//DiscoverySettings contains the settings of the discovery process of the home cluster,
+//stored in the DiscoveryConfig of the ClusterConfig CR.
+type DiscoverySettings struct {
+	//EnableDiscovery specifies whether the home cluster discovers peers inside its LAN.
+	EnableDiscovery bool
+	//EnableAdvertisement specifies whether the home cluster advertises itself inside its LAN.
+	EnableAdvertisement bool
+	//AutoJoin specifies whether the home cluster automatically peers with discovered trusted clusters.
+	AutoJoin bool
+	//AutoJoinUntrusted specifies whether the home cluster automatically peers with discovered untrusted clusters.
+	AutoJoinUntrusted bool
+}
+
+//loadDiscoveryConfig loads the DiscoverySettings from the spec of a ClusterConfig CR.
+func (s *DiscoverySettings) loadDiscoveryConfig(config *clusterConfig.ClusterConfig) {
+	discConf := &config.Spec.DiscoveryConfig
+	s.EnableDiscovery = discConf.EnableDiscovery
+	s.EnableAdvertisement = discConf.EnableAdvertisement
+	s.AutoJoin = discConf.AutoJoin
+	s.AutoJoinUntrusted = discConf.AutoJoinUntrusted
+}
+
+//DiscoverySettings returns the current DiscoverySettings of the home cluster.
+func (ctrl *AgentController) DiscoverySettings() (*DiscoverySettings, error) {
+	conf, err := ctrl.clusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	settings := &DiscoverySettings{}
+	settings.loadDiscoveryConfig(conf)
+	return settings, nil
+}
+
+//SetDiscoverySettings writes new DiscoverySettings to the ClusterConfig CR.
+func (ctrl *AgentController) SetDiscoverySettings(settings *DiscoverySettings) error {
+	if settings == nil {
+		return errors.New("nil discovery settings")
+	}
+	conf, err := ctrl.clusterConfig()
+	if err != nil {
+		return err
+	}
+	newConf := conf.DeepCopy()
+	discConf := &newConf.Spec.DiscoveryConfig
+	discConf.EnableDiscovery = settings.EnableDiscovery
+	discConf.EnableAdvertisement = settings.EnableAdvertisement
+	discConf.AutoJoin = settings.AutoJoin
+	discConf.AutoJoinUntrusted = settings.AutoJoinUntrusted
+	_, err = ctrl.Controller(CRClusterConfig).Resource(string(CRClusterConfig)).Update(newConf.Name, newConf,
+		metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDiscoverySettings(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	_, err := ctrl.DiscoverySettings()
+	assert.Error(t, err, "discovery settings retrieved without a ClusterConfig")
+	assert.Error(t, ctrl.SetDiscoverySettings(nil), "nil discovery settings accepted")
+	conf, _ := createClusterConfig()
+	conf.Spec.DiscoveryConfig.EnableDiscovery = true
+	ccCtrl := ctrl.Controller(CRClusterConfig)
+	if err = ccCtrl.Store.Add(conf); err != nil {
+		t.Fatal(err)
+	}
+	//wait for the cache events to be handled before removing the ClusterConfig
+	waitNotifyData(t, ctrl, ChanClusterConfig)
+	defer func() {
+		_ = ccCtrl.Store.Delete(conf)
+	}()
+	settings, err := ctrl.DiscoverySettings()
+	if assert.NoError(t, err, "discovery settings not retrieved") {
+		assert.Equal(t, DiscoverySettings{EnableDiscovery: true}, *settings, "wrong discovery settings")
+	}
+	settings.EnableAdvertisement = true
+	settings.AutoJoin = true
+	if assert.NoError(t, ctrl.SetDiscoverySettings(settings), "discovery settings not updated") {
+		waitNotifyData(t, ctrl, ChanClusterConfig)
+	}
+	newSettings, err := ctrl.DiscoverySettings()
+	if assert.NoError(t, err, "discovery settings not retrieved after update") {
+		assert.Equal(t, *settings, *newSettings, "discovery settings not correctly updated")
+	}
+}
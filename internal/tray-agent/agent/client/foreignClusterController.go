@@ -7,11 +7,11 @@ import (
 )
 
 //createForeignClusterController creates a new CRDController for the Liqo ForeignCluster CRD.
-func createForeignClusterController(kubeconfig string) (*CRDController, error) {
+func (ctrl *AgentController) createForeignClusterController(kubeconfig string) (*CRDController, error) {
 	controller := &CRDController{
-		addFunc:    foreignclusterAddFunc,
-		updateFunc: foreignclusterUpdateFunc,
-		deleteFunc: foreignclusterDeleteFunc,
+		addFunc:    ctrl.foreignclusterAddFunc,
+		updateFunc: ctrl.foreignclusterUpdateFunc,
+		deleteFunc: ctrl.foreignclusterDeleteFunc,
 	}
 	newClient, err := discovery.CreateForeignClusterClient(kubeconfig)
 	if err != nil {
@@ -53,6 +53,11 @@ type NotifyDataForeignCluster struct {
 	}
 }
 
+//EventKey returns the key of the events regarding the peer.
+func (d *NotifyDataForeignCluster) EventKey() string {
+	return PeerEventKey(d.ClusterID)
+}
+
 //			**** HELPERS ****
 //	The following functions are helpers used to simplify the loading and sharing of information.
 
@@ -70,12 +75,12 @@ func (d *NotifyDataForeignCluster) loadPeerInfo(fc *discovery.ForeignCluster) {
 }
 
 //loadPeeringInfo loads useful data about peerings established with a ForeignCluster.
-func (d *NotifyDataForeignCluster) loadPeeringInfo(fc *discovery.ForeignCluster) {
+func (d *NotifyDataForeignCluster) loadPeeringInfo(ctrl *AgentController, fc *discovery.ForeignCluster) {
 	//OUTGOING PEERING
 	if fc.Status.Outgoing.Joined && fc.Status.Outgoing.AdvertisementStatus == sharing.AdvertisementAccepted {
 		d.OutPeering.Connected = true
 		//try to recover details on shared resources
-		if advCtl := GetAgentController().Controller(CRAdvertisement); advCtl.Running() {
+		if advCtl := ctrl.Controller(CRAdvertisement); advCtl.Running() {
 			if obj, exist, err := advCtl.Store.GetByKey(fc.Status.Outgoing.Advertisement.Name); exist && err == nil {
 				if foreignAdv, ok := obj.(*sharing.Advertisement); ok {
 					quotas := foreignAdv.Spec.ResourceQuota.Hard
@@ -96,7 +101,7 @@ func (d *NotifyDataForeignCluster) loadPeeringInfo(fc *discovery.ForeignCluster)
 //	of the correspondent cache.
 
 //foreignclusterAddFunc is the ADD event handler for the ForeignCluster CRDController.
-func foreignclusterAddFunc(obj interface{}) {
+func (ctrl *AgentController) foreignclusterAddFunc(obj interface{}) {
 	fc := obj.(*discovery.ForeignCluster)
 	/*There are some cases when a just created ForeignCluster already contains information about a peering
 	(pending or accepted), e.g. for a FC discovered due to an incoming peering request or with a peering
@@ -115,27 +120,27 @@ func foreignclusterAddFunc(obj interface{}) {
 	}
 	data := &NotifyDataForeignCluster{}
 	data.loadPeerInfo(fc)
-	data.loadPeeringInfo(fc)
-	agentCtrl.NotifyChannel(ChanPeerAddedOrUpdated) <- data
+	data.loadPeeringInfo(ctrl, fc)
+	ctrl.Notify(ChanPeerAddedOrUpdated, data)
 }
 
 //foreignclusterUpdateFunc is the UPDATE event handler for the ForeignCluster CRDController.
-func foreignclusterUpdateFunc(_ interface{}, newObj interface{}) {
+func (ctrl *AgentController) foreignclusterUpdateFunc(_ interface{}, newObj interface{}) {
 	fcNew := newObj.(*discovery.ForeignCluster)
 	if fcNew.Spec.ClusterIdentity.ClusterID == "" {
 		return
 	}
 	data := &NotifyDataForeignCluster{}
 	data.loadPeerInfo(fcNew)
-	data.loadPeeringInfo(fcNew)
-	agentCtrl.NotifyChannel(ChanPeerAddedOrUpdated) <- data
+	data.loadPeeringInfo(ctrl, fcNew)
+	ctrl.Notify(ChanPeerAddedOrUpdated, data)
 }
 
 //foreignclusterDeleteFunc is the DELETE event handler for the ForeignCluster CRDController.
-func foreignclusterDeleteFunc(obj interface{}) {
+func (ctrl *AgentController) foreignclusterDeleteFunc(obj interface{}) {
 	fc := obj.(*discovery.ForeignCluster)
 	data := &NotifyDataForeignCluster{}
 	data.loadPeerInfo(fc)
-	data.loadPeeringInfo(fc)
-	agentCtrl.NotifyChannel(ChanPeerDeleted) <- data
+	data.loadPeeringInfo(ctrl, fc)
+	ctrl.Notify(ChanPeerDeleted, data)
 }
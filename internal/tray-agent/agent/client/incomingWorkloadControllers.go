@@ -0,0 +1,298 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	discovery "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//incomingPodLabel is the label set by the virtual kubelet of a foreign cluster on the pods it
+//offloads to the home cluster.
+const incomingPodLabel = "virtualkubelet.liqo.io/outgoing"
+
+//clusterIDSuffix matches the ClusterID suffix Liqo appends to the name of the namespaces reflected
+//in the home cluster by a foreign virtual kubelet.
+var clusterIDSuffix = regexp.MustCompile(`-([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+
+//createNamespaceController creates a new KubeController for the namespaces of the home cluster.
+func (ctrl *AgentController) createNamespaceController() *KubeController {
+	factory := informers.NewSharedInformerFactory(ctrl.kubeClient, 0)
+	controller := newKubeController(KRNamespace, factory,
+		func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Core().V1().Namespaces().Informer()
+		})
+	controller.addFunc = ctrl.namespaceAddFunc
+	controller.updateFunc = ctrl.namespaceUpdateFunc
+	controller.deleteFunc = ctrl.namespaceDeleteFunc
+	return controller
+}
+
+//createIncomingPodController creates a new KubeController for the pods offloaded to the home cluster
+//by the foreign clusters.
+func (ctrl *AgentController) createIncomingPodController() *KubeController {
+	factory := informers.NewSharedInformerFactoryWithOptions(ctrl.kubeClient, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = incomingPodLabel
+		}))
+	controller := newKubeController(KRIncomingPod, factory,
+		func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Core().V1().Pods().Informer()
+		})
+	controller.addFunc = ctrl.incomingPodAddFunc
+	controller.updateFunc = ctrl.incomingPodUpdateFunc
+	controller.deleteFunc = ctrl.incomingPodDeleteFunc
+	return controller
+}
+
+//NotifyDataNamespace is a NotifyDataGeneric sub-type used to exchange data concerning a namespace
+//of the home cluster.
+type NotifyDataNamespace struct {
+	//Name of the namespace.
+	Name string
+	//IncomingClusterID is the ClusterID of the foreign cluster that created the namespace to offload its
+	//workloads. It is empty for the namespaces not reflected by a peer.
+	IncomingClusterID string
+	//OffloadingEnabled specifies whether the pods of the namespace can be offloaded to the peers.
+	OffloadingEnabled bool
+}
+
+//EventKey returns the key of the events regarding the namespace. The events regarding a namespace
+//offloaded to the home cluster are ordered with the other events of the same peer.
+func (d *NotifyDataNamespace) EventKey() string {
+	if d.IncomingClusterID != "" {
+		return PeerEventKey(d.IncomingClusterID)
+	}
+	return "namespace/" + d.Name
+}
+
+//loadNamespace loads useful data about a namespace.
+func (d *NotifyDataNamespace) loadNamespace(ctrl *AgentController, ns *corev1.Namespace) {
+	d.Name = ns.Name
+	d.IncomingClusterID, _ = ctrl.incomingClusterID(ns.Name)
+	d.OffloadingEnabled = ns.Labels[namespaceOffloadingLabel] == namespaceOffloadingEnabled
+}
+
+//NotifyDataIncomingPod is a NotifyDataGeneric sub-type used to exchange data concerning a pod
+//offloaded to the home cluster by a foreign cluster.
+type NotifyDataIncomingPod struct {
+	//Name of the pod.
+	Name string
+	//Namespace of the pod.
+	Namespace string
+	//ClusterID of the foreign cluster that offloaded the pod.
+	ClusterID string
+	//Terminated specifies whether the pod has already completed its execution.
+	Terminated bool
+	//Cpu is the effective amount of CPU requested by the pod (see podRequests).
+	Cpu resource.Quantity
+	//Memory is the effective amount of memory requested by the pod (see podRequests).
+	Memory resource.Quantity
+}
+
+//EventKey returns the key of the events regarding the peer that offloaded the pod.
+func (d *NotifyDataIncomingPod) EventKey() string {
+	return PeerEventKey(d.ClusterID)
+}
+
+//loadIncomingPod loads useful data about an incoming pod.
+func (d *NotifyDataIncomingPod) loadIncomingPod(pod *corev1.Pod, clusterID string) {
+	d.Name = pod.Name
+	d.Namespace = pod.Namespace
+	d.ClusterID = clusterID
+	d.Terminated = pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+	req := podRequests(pod)
+	d.Cpu = *req.Cpu()
+	d.Memory = *req.Memory()
+}
+
+//incomingClusterID returns the ClusterID of the foreign cluster that reflected a namespace in the home cluster.
+//The known ForeignClusters are checked first; otherwise the ClusterID is recovered from the namespace name.
+func (ctrl *AgentController) incomingClusterID(namespace string) (clusterID string, incoming bool) {
+	if fcCtrl := ctrl.Controller(CRForeignCluster); fcCtrl != nil && fcCtrl.Running() {
+		for _, obj := range fcCtrl.Store.List() {
+			fc, ok := obj.(*discovery.ForeignCluster)
+			if !ok {
+				continue
+			}
+			id := fc.Spec.ClusterIdentity.ClusterID
+			if id != "" && strings.HasSuffix(namespace, "-"+id) {
+				return id, true
+			}
+		}
+	}
+	if match := clusterIDSuffix.FindStringSubmatch(namespace); match != nil {
+		return match[1], true
+	}
+	return "", false
+}
+
+//ListNamespaces returns the data of the namespaces currently stored in the cache, sorted by name.
+func (ctrl *AgentController) ListNamespaces() []*NotifyDataNamespace {
+	var namespaces []*NotifyDataNamespace
+	nsCtrl := ctrl.KubeController(KRNamespace)
+	if nsCtrl == nil || !nsCtrl.Running() {
+		return namespaces
+	}
+	for _, obj := range nsCtrl.Store.List() {
+		ns, ok := obj.(*corev1.Namespace)
+		if !ok {
+			continue
+		}
+		data := &NotifyDataNamespace{}
+		data.loadNamespace(ctrl, ns)
+		namespaces = append(namespaces, data)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+	return namespaces
+}
+
+//ListIncomingPods returns the data of the pods offloaded by the foreign clusters currently stored in the cache,
+//sorted by namespace and name. As for the incoming pod events, the pods whose foreign cluster is not known
+//are ignored.
+func (ctrl *AgentController) ListIncomingPods() []*NotifyDataIncomingPod {
+	var pods []*NotifyDataIncomingPod
+	podCtrl := ctrl.KubeController(KRIncomingPod)
+	if podCtrl == nil || !podCtrl.Running() {
+		return pods
+	}
+	for _, obj := range podCtrl.Store.List() {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			continue
+		}
+		clusterID, incoming := ctrl.incomingClusterID(pod.Namespace)
+		if !incoming {
+			continue
+		}
+		data := &NotifyDataIncomingPod{}
+		data.loadIncomingPod(pod, clusterID)
+		pods = append(pods, data)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].Name < pods[j].Name
+	})
+	return pods
+}
+
+//EvictIncomingWorkloads deletes all the pods offloaded to the home cluster by the foreign cluster with the
+//given ClusterID.
+//
+//This is an emergency command: the foreign cluster may schedule the workloads again as long as
+//the incoming peering is active.
+func (ctrl *AgentController) EvictIncomingWorkloads(clusterID string) error {
+	if !ctrl.connected {
+		return errors.New("no connection available")
+	}
+	if clusterID == "" {
+		return errors.New("no ClusterID provided")
+	}
+	podCtrl := ctrl.KubeController(KRIncomingPod)
+	if podCtrl == nil || !podCtrl.Running() {
+		return errors.New("the incoming pods cache is not running")
+	}
+	ctx, cancel := ctrl.requestContext()
+	defer cancel()
+	var failed []string
+	for _, obj := range podCtrl.Store.List() {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			continue
+		}
+		if id, incoming := ctrl.incomingClusterID(pod.Namespace); !incoming || id != clusterID {
+			continue
+		}
+		err := ctrl.kubeClient.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+		if err != nil {
+			failed = append(failed, pod.Namespace+"/"+pod.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not evict pods %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+//			**** EVENT FUNCTIONS ****
+//	The following functions are the callbacks the Namespace and IncomingPod Controllers use to handle the events
+//	of the correspondent cache.
+
+//namespaceAddFunc is the ADD event handler for the Namespace KubeController.
+func (ctrl *AgentController) namespaceAddFunc(obj interface{}) {
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return
+	}
+	data := &NotifyDataNamespace{}
+	data.loadNamespace(ctrl, ns)
+	ctrl.Notify(ChanNamespaceAddedOrUpdated, data)
+}
+
+//namespaceUpdateFunc is the UPDATE event handler for the Namespace KubeController.
+func (ctrl *AgentController) namespaceUpdateFunc(_ interface{}, newObj interface{}) {
+	ctrl.namespaceAddFunc(newObj)
+}
+
+//namespaceDeleteFunc is the DELETE event handler for the Namespace KubeController.
+func (ctrl *AgentController) namespaceDeleteFunc(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return
+	}
+	data := &NotifyDataNamespace{}
+	data.loadNamespace(ctrl, ns)
+	ctrl.Notify(ChanNamespaceDeleted, data)
+}
+
+//incomingPodAddFunc is the ADD event handler for the IncomingPod KubeController.
+func (ctrl *AgentController) incomingPodAddFunc(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	clusterID, incoming := ctrl.incomingClusterID(pod.Namespace)
+	if !incoming {
+		return
+	}
+	data := &NotifyDataIncomingPod{}
+	data.loadIncomingPod(pod, clusterID)
+	ctrl.Notify(ChanIncomingPodAddedOrUpdated, data)
+}
+
+//incomingPodUpdateFunc is the UPDATE event handler for the IncomingPod KubeController.
+func (ctrl *AgentController) incomingPodUpdateFunc(_ interface{}, newObj interface{}) {
+	ctrl.incomingPodAddFunc(newObj)
+}
+
+//incomingPodDeleteFunc is the DELETE event handler for the IncomingPod KubeController.
+func (ctrl *AgentController) incomingPodDeleteFunc(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	clusterID, incoming := ctrl.incomingClusterID(pod.Namespace)
+	if !incoming {
+		return
+	}
+	data := &NotifyDataIncomingPod{}
+	data.loadIncomingPod(pod, clusterID)
+	ctrl.Notify(ChanIncomingPodDeleted, data)
+}
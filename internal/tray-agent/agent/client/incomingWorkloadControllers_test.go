@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestIncomingClusterID(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	clusterID := "4d7a8a71-1d5e-4a5a-9b1d-3e4b5c6d7e8f"
+	id, incoming := ctrl.incomingClusterID("default-" + clusterID)
+	assert.True(t, incoming, "reflected namespace not recognized")
+	assert.Equal(t, clusterID, id, "wrong ClusterID for a reflected namespace")
+	_, incoming = ctrl.incomingClusterID("kube-system")
+	assert.False(t, incoming, "local namespace recognized as reflected")
+}
+
+func TestIncomingWorkloads(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	clusterID := "4d7a8a71-1d5e-4a5a-9b1d-3e4b5c6d7e8f"
+	nsName := "default-" + clusterID
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName}}
+	if _, err := ctrl.kubeClient.CoreV1().Namespaces().Create(context.TODO(), ns, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	nsData := waitNotifyData(t, ctrl, ChanNamespaceAddedOrUpdated).(*NotifyDataNamespace)
+	assert.Equal(t, clusterID, nsData.IncomingClusterID, "reflected namespace not associated to the peer")
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "guest",
+			Namespace: nsName,
+			Labels:    map[string]string{incomingPodLabel: "liqo-home"},
+		},
+		Spec: corev1.PodSpec{
+			//the init container requests more CPU than the containers
+			InitContainers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("500m"),
+				}},
+			}},
+			Containers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("250m"),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				}},
+			}},
+		},
+	}
+	pods := ctrl.kubeClient.CoreV1().Pods(nsName)
+	if _, err := pods.Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	podData := waitNotifyData(t, ctrl, ChanIncomingPodAddedOrUpdated).(*NotifyDataIncomingPod)
+	assert.Equal(t, clusterID, podData.ClusterID, "incoming pod not associated to the peer")
+	assert.Equal(t, "500m", podData.Cpu.String(), "wrong requested CPU")
+	assert.Equal(t, "128Mi", podData.Memory.String(), "wrong requested memory")
+	//evict workloads
+	assert.Error(t, ctrl.EvictIncomingWorkloads(""), "eviction without a ClusterID accepted")
+	assert.NoError(t, ctrl.EvictIncomingWorkloads(clusterID), "workloads not evicted")
+	podData = waitNotifyData(t, ctrl, ChanIncomingPodDeleted).(*NotifyDataIncomingPod)
+	assert.Equal(t, "guest", podData.Name, "wrong pod evicted")
+	podL, err := pods.List(context.TODO(), metav1.ListOptions{})
+	if assert.NoError(t, err) {
+		assert.Empty(t, podL.Items, "incoming pods still present after eviction")
+	}
+}
+
+func TestEvictIncomingWorkloadsWithoutCache(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	ctrl.StopCaches()
+	defer DestroyMockedAgentController()
+	assert.Error(t, ctrl.EvictIncomingWorkloads("4d7a8a71-1d5e-4a5a-9b1d-3e4b5c6d7e8f"),
+		"workloads evicted without a running cache")
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"sync"
+)
+
+//KubeResource defines the standard kubernetes resources watched by Liqo Agent.
+type KubeResource string
+
+const (
+	//KRVirtualNode is the resource id for the virtual nodes created by Liqo for the outgoing peerings.
+	KRVirtualNode KubeResource = "virtualnodes"
+	//KROffloadedPod is the resource id for the pods of the home cluster offloaded to a foreign cluster. They are
+	//watched by a KubeController for each virtual node, started by the handlers of the KRVirtualNode one.
+	KROffloadedPod KubeResource = "offloadedpods"
+	//KRNamespace is the resource id for the namespaces of the home cluster.
+	KRNamespace KubeResource = "namespaces"
+	//KRIncomingPod is the resource id for the pods offloaded to the home cluster by a foreign cluster.
+	KRIncomingPod KubeResource = "incomingpods"
+	//KRAppPod is the resource id for the pods of the applications launched by the Agent.
+	KRAppPod KubeResource = "apppods"
+)
+
+//kubeResources contains all the registered KubeResource managed by the AgentController.
+//It is used for init and testing purposes.
+//
+//The caches are started following this order, since the handlers of a resource may rely on the
+//caches of the previous ones.
+var kubeResources = []KubeResource{
+	KRVirtualNode,
+	KRNamespace,
+	KRIncomingPod,
+	KRAppPod,
+}
+
+//kubeManager stores the resources necessary to watch standard kubernetes resources.
+type kubeManager struct {
+	//kubeClientMap contains the Controllers for the kubernetes resources watched by the Agent.
+	kubeClientMap map[KubeResource]*KubeController
+	//offloadedPodMap contains the Controllers for the pods offloaded through each virtual node, by node name.
+	offloadedPodMap map[string]*KubeController
+	//offloadedPodMutex protects offloadedPodMap, which is updated by the handlers of the virtual nodes.
+	offloadedPodMutex sync.Mutex
+}
+
+//initKubeManager creates and initializes the kubeManager, loading the KubeController for each
+//required kubernetes resource.
+func (ctrl *AgentController) initKubeManager() error {
+	if ctrl.kubeClient == nil {
+		return errors.New("no kubernetes client available")
+	}
+	manager := &kubeManager{
+		kubeClientMap:   make(map[KubeResource]*KubeController),
+		offloadedPodMap: make(map[string]*KubeController),
+	}
+	ctrl.kubeManager = manager
+	//	VIRTUAL NODES
+	manager.kubeClientMap[KRVirtualNode] = ctrl.createVirtualNodeController()
+	//	NAMESPACES
+	manager.kubeClientMap[KRNamespace] = ctrl.createNamespaceController()
+	//	INCOMING PODS
+	manager.kubeClientMap[KRIncomingPod] = ctrl.createIncomingPodController()
+	//	APPLICATION PODS
+	manager.kubeClientMap[KRAppPod] = ctrl.createAppPodController()
+	return nil
+}
+
+//KubeController returns (if present) the KubeController for a specific kubernetes resource.
+func (m *kubeManager) KubeController(resource KubeResource) *KubeController {
+	return m.kubeClientMap[resource]
+}
+
+//KubeController handles the Agent watch on a specific set of standard kubernetes resources.
+type KubeController struct {
+	//Store is the cache of the watched resources.
+	Store cache.Store
+	//cancel stops the informer.
+	cancel context.CancelFunc
+	//informer watching the resources.
+	informer cache.SharedIndexInformer
+	//resource is the KubeResource literal identifier.
+	resource KubeResource
+	//running specifies whether the informer is running.
+	running bool
+	//mutex protects running, which is read by the handlers of the AgentController caches.
+	mutex sync.RWMutex
+	//addFunc is the handler for the 'resource added' event.
+	addFunc func(obj interface{})
+	//updateFunc is the handler for the 'resource updated' event.
+	updateFunc func(oldObj interface{}, newObj interface{})
+	//deleteFunc is the handler for the 'resource deleted' event.
+	deleteFunc func(obj interface{})
+}
+
+//newKubeController creates a KubeController for the informer returned by informerFunc, using a dedicated
+//SharedInformerFactory configured with the provided options.
+func newKubeController(resource KubeResource, factory informers.SharedInformerFactory,
+	informerFunc func(factory informers.SharedInformerFactory) cache.SharedIndexInformer) *KubeController {
+	informer := informerFunc(factory)
+	return &KubeController{
+		Store:    informer.GetStore(),
+		informer: informer,
+		resource: resource,
+	}
+}
+
+//Running returns whether the controller informer is running.
+func (c *KubeController) Running() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.running
+}
+
+//StartCache starts the informer and the sending of signals
+//of the AgentController events. The informer is stopped by StopCache or when ctx is cancelled. The goroutines
+//of the informer are tracked by workers.
+func (c *KubeController) StartCache(ctx context.Context, workers *WorkerGroup) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.running {
+		return nil
+	}
+	//the informer never blocks on the handlers, which are executed by the workQueue
+	queue := newWorkQueue(c.addFunc, c.updateFunc, c.deleteFunc)
+	c.informer.AddEventHandler(queue.handlers())
+	var cacheCtx context.Context
+	cacheCtx, c.cancel = context.WithCancel(ctx)
+	workers.Go(func() {
+		queue.run(cacheCtx.Done())
+	})
+	workers.Go(func() {
+		c.informer.Run(cacheCtx.Done())
+	})
+	if !cache.WaitForCacheSync(cacheCtx.Done(), c.informer.HasSynced) {
+		c.cancel()
+		return errors.New("could not sync the " + string(c.resource) + " cache")
+	}
+	c.running = true
+	return nil
+}
+
+//StopCache stops (if running) the informer associated to the resource.
+func (c *KubeController) StopCache() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.running {
+		c.cancel()
+		c.running = false
+	}
+}
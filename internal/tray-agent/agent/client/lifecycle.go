@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//kubeRequestTimeout is the maximum duration of the requests to the API server performed by a single operation
+//of the AgentController.
+const kubeRequestTimeout = 15 * time.Second
+
+//WorkerGroup tracks a set of goroutines, so that their termination can be awaited.
+type WorkerGroup struct {
+	wg sync.WaitGroup
+}
+
+//Go executes f in a goroutine of the WorkerGroup. If the WorkerGroup is nil, the goroutine is not tracked.
+func (g *WorkerGroup) Go(f func()) {
+	g.GoDetachable(func(func()) {
+		f()
+	})
+}
+
+//GoDetachable executes f in a goroutine of the WorkerGroup, like Go. f receives a function that stops tracking
+//the goroutine before its exit, so that the goroutine can wait for the rest of the WorkerGroup.
+func (g *WorkerGroup) GoDetachable(f func(detach func())) {
+	if g == nil {
+		go f(func() {})
+		return
+	}
+	g.wg.Add(1)
+	once := sync.Once{}
+	detach := func() {
+		once.Do(g.wg.Done)
+	}
+	go func() {
+		defer detach()
+		f(detach)
+	}()
+}
+
+//Wait waits for the goroutines of the WorkerGroup to exit. It returns the error of ctx if it expires before.
+func (g *WorkerGroup) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//Context returns the root context of the AgentController, which is cancelled by Shutdown.
+func (ctrl *AgentController) Context() context.Context {
+	return ctrl.ctx
+}
+
+//requestContext returns the context for the requests to the API server of an operation. The context expires
+//after kubeRequestTimeout or when the AgentController is shut down.
+func (ctrl *AgentController) requestContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctrl.ctx, kubeRequestTimeout)
+}
+
+//Shutdown stops the caches, the port-forwards and the LiqoDash proxy of the AgentController, cancelling its root
+//context. It then waits for all the AgentController goroutines to exit, until ctx expires.
+//
+//After Shutdown, the AgentController no more interacts with the cluster.
+func (ctrl *AgentController) Shutdown(ctx context.Context) error {
+	ctrl.cancel()
+	ctrl.StopPortForwards()
+	ctrl.StopCaches()
+	return ctrl.workers.Wait(ctx)
+}
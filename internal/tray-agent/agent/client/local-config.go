@@ -19,6 +19,8 @@ var fileConfig = &LocalConfiguration{}
 type LocalConfig struct {
 	//Kubeconfig contains the path of the kubeconfig file.
 	Kubeconfig string `yaml:"kubeconfig,omitempty"`
+	//DashboardURL is the LiqoDash URL chosen by the user when several ones are available.
+	DashboardURL string `yaml:"dashboardUrl,omitempty"`
 }
 
 //LocalConfiguration stores the LocalConfig configuration acquired from a local config file and a validity flag.
@@ -116,3 +118,25 @@ func (lc *LocalConfiguration) SetKubeconfig(path string) {
 	}
 	lc.Content.Kubeconfig = path
 }
+
+//GetDashboardURL returns the 'dashboardUrl' field for the local configuration.
+func (lc *LocalConfiguration) GetDashboardURL() string {
+	lc.RLock()
+	defer lc.RUnlock()
+	if lc.Content == nil {
+		return ""
+	}
+	return lc.Content.DashboardURL
+}
+
+//SetDashboardURL sets the 'dashboardUrl' field for the local configuration. Use SaveLocalConfig to write the updated
+//configuration to the ConfigFileName file.
+func (lc *LocalConfiguration) SetDashboardURL(url string) {
+	lc.Lock()
+	defer lc.Unlock()
+	if lc.Content == nil {
+		lc.Content = &LocalConfig{DashboardURL: url}
+		return
+	}
+	lc.Content.DashboardURL = url
+}
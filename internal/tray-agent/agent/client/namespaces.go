@@ -0,0 +1,45 @@
+package client
+
+import (
+	"errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	//namespaceOffloadingLabel is the label that enables Liqo to offload the pods of a namespace.
+	namespaceOffloadingLabel = "liqo.io/enabled"
+	//namespaceOffloadingEnabled is the value of namespaceOffloadingLabel that enables the offloading.
+	namespaceOffloadingEnabled = "true"
+)
+
+//SetNamespaceOffloading enables or disables the offloading of the pods of a namespace of the home cluster,
+//by applying or removing the Liqo namespace label.
+func (ctrl *AgentController) SetNamespaceOffloading(namespace string, enabled bool) error {
+	if !ctrl.connected {
+		return errors.New("no connection available")
+	}
+	if _, incoming := ctrl.incomingClusterID(namespace); incoming {
+		return errors.New("the namespace " + namespace + " is managed by a peer")
+	}
+	ctx, cancel := ctrl.requestContext()
+	defer cancel()
+	nsClient := ctrl.kubeClient.CoreV1().Namespaces()
+	ns, err := nsClient.Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	newNs := ns.DeepCopy()
+	if enabled {
+		if newNs.Labels == nil {
+			newNs.Labels = make(map[string]string)
+		}
+		newNs.Labels[namespaceOffloadingLabel] = namespaceOffloadingEnabled
+	} else {
+		if _, present := newNs.Labels[namespaceOffloadingLabel]; !present {
+			return nil
+		}
+		delete(newNs.Labels, namespaceOffloadingLabel)
+	}
+	_, err = nsClient.Update(ctx, newNs, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestSetNamespaceOffloading(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	nsClient := ctrl.kubeClient.CoreV1().Namespaces()
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	if _, err := nsClient.Create(context.TODO(), ns, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data := waitNotifyData(t, ctrl, ChanNamespaceAddedOrUpdated).(*NotifyDataNamespace)
+	assert.False(t, data.OffloadingEnabled, "offloading should be disabled by default")
+	//enable offloading
+	assert.NoError(t, ctrl.SetNamespaceOffloading("apps", true), "offloading not enabled")
+	data = waitNotifyData(t, ctrl, ChanNamespaceAddedOrUpdated).(*NotifyDataNamespace)
+	assert.True(t, data.OffloadingEnabled, "offloading enabling not notified")
+	ns, err := nsClient.Get(context.TODO(), "apps", metav1.GetOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, namespaceOffloadingEnabled, ns.Labels[namespaceOffloadingLabel], "offloading label not set")
+	}
+	//disable offloading
+	assert.NoError(t, ctrl.SetNamespaceOffloading("apps", false), "offloading not disabled")
+	data = waitNotifyData(t, ctrl, ChanNamespaceAddedOrUpdated).(*NotifyDataNamespace)
+	assert.False(t, data.OffloadingEnabled, "offloading disabling not notified")
+	//errors
+	assert.Error(t, ctrl.SetNamespaceOffloading("missing", true), "offloading enabled on a missing namespace")
+	assert.Error(t, ctrl.SetNamespaceOffloading("default-4d7a8a71-1d5e-4a5a-9b1d-3e4b5c6d7e8f", true),
+		"offloading enabled on a reflected namespace")
+}
@@ -1,7 +1,9 @@
 package client
 
-//notifyBuffLength is the buffer length for the NotifyChannel channels of a cache.
-const notifyBuffLength = 100
+import "strings"
+
+//notifyBuffLength is the buffer length of the stream of NotifyEvent sent by the AgentController.
+const notifyBuffLength = 1000
 
 //NotifyChannel identifies a notification channel for a specific event.
 type NotifyChannel int
@@ -12,15 +14,69 @@ const (
 	ChanPeerAddedOrUpdated NotifyChannel = iota
 	//Notification channel id for the removal of an available peer.
 	ChanPeerDeleted
-	//ChanClusterName os the NotifyChannel used to transmit the current ClusterName of the Liqo cluster the Agent is
-	//connected to.
-	ChanClusterName
+	//ChanClusterConfig is the NotifyChannel used to transmit the current configuration (NotifyDataClusterConfig)
+	//of the Liqo cluster the Agent is connected to.
+	ChanClusterConfig
+	//Notification channel id for an update of a virtual node.
+	ChanVirtualNodeAddedOrUpdated
+	//Notification channel id for the removal of a virtual node.
+	ChanVirtualNodeDeleted
+	//Notification channel id for an update of a pod offloaded to a foreign cluster.
+	ChanOffloadedPodAddedOrUpdated
+	//Notification channel id for the removal of a pod offloaded to a foreign cluster.
+	ChanOffloadedPodDeleted
+	//Notification channel id for an update of a namespace.
+	ChanNamespaceAddedOrUpdated
+	//Notification channel id for the removal of a namespace.
+	ChanNamespaceDeleted
+	//Notification channel id for an update of a pod offloaded to the home cluster by a foreign cluster.
+	ChanIncomingPodAddedOrUpdated
+	//Notification channel id for the removal of a pod offloaded to the home cluster by a foreign cluster.
+	ChanIncomingPodDeleted
+	//Notification channel id for an update of a pod of an application launched by the Agent.
+	ChanAppPodAddedOrUpdated
+	//Notification channel id for the removal of a pod of an application launched by the Agent.
+	ChanAppPodDeleted
+	//Notification channel id for a change in the status of a port-forward.
+	ChanPortForward
+	//Notification channel id for a change in the status of the LiqoDash endpoint.
+	ChanDashboard
 )
 
-//notifyChannelNames contains all the registered NotifyChannel managed by the AgentController.
-//It is used for init and testing purposes.
-var notifyChannelNames = []NotifyChannel{
-	ChanPeerAddedOrUpdated,
-	ChanPeerDeleted,
-	ChanClusterName,
+//NotifyEvent is a notification sent by the AgentController on a NotifyChannel.
+type NotifyEvent struct {
+	Channel NotifyChannel
+	Data    NotifyDataGeneric
+}
+
+//Notify sends a notification on a NotifyChannel. The notifications of all the NotifyChannel are delivered
+//in the same order they are sent. Notify blocks while the stream is full: the handlers of the caches are
+//executed by a workQueue, so that they never block the informers.
+func (ctrl *AgentController) Notify(channel NotifyChannel, data NotifyDataGeneric) {
+	select {
+	case ctrl.events <- NotifyEvent{Channel: channel, Data: data}:
+	case <-ctrl.ctx.Done():
+	}
+}
+
+//Events returns the stream of the notifications sent by the AgentController.
+func (ctrl *AgentController) Events() <-chan NotifyEvent {
+	return ctrl.events
+}
+
+//peerEventKeyPrefix is the prefix of the keys of the events regarding a single peer.
+const peerEventKeyPrefix = "peer/"
+
+//PeersListEventKey is the key of the events regarding the whole peers list (e.g. its resynchronization).
+//They are never handled together with the events regarding a single peer (see PeerEventKey).
+const PeersListEventKey = "peers"
+
+//PeerEventKey returns the key of the events regarding the peer having the given ClusterID.
+func PeerEventKey(clusterID string) string {
+	return peerEventKeyPrefix + clusterID
+}
+
+//IsPeerEventKey returns whether key is the key of the events regarding a single peer.
+func IsPeerEventKey(key string) bool {
+	return strings.HasPrefix(key, peerEventKeyPrefix)
 }
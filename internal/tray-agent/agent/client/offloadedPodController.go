@@ -0,0 +1,200 @@
+package client
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"sort"
+)
+
+/*createOffloadedPodController creates a new KubeController for the pods of the home cluster scheduled
+on the virtual node nodeName, which represents the foreign cluster clusterID.
+
+Since an informer cannot select the pods of a set of nodes, each virtual node has its own KubeController,
+selecting the pods by their spec.nodeName: the pods running on the home cluster are never cached.*/
+func (ctrl *AgentController) createOffloadedPodController(nodeName string, clusterID string) *KubeController {
+	factory := informers.NewSharedInformerFactoryWithOptions(ctrl.kubeClient, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+		}))
+	controller := newKubeController(KROffloadedPod, factory,
+		func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Core().V1().Pods().Informer()
+		})
+	controller.addFunc = func(obj interface{}) {
+		ctrl.offloadedPodAddFunc(obj, nodeName, clusterID)
+	}
+	controller.updateFunc = func(_ interface{}, newObj interface{}) {
+		ctrl.offloadedPodAddFunc(newObj, nodeName, clusterID)
+	}
+	controller.deleteFunc = func(obj interface{}) {
+		ctrl.offloadedPodDeleteFunc(obj, nodeName, clusterID)
+	}
+	return controller
+}
+
+//watchOffloadedPods starts (if not running yet) the KubeController of the pods offloaded through a virtual node.
+func (ctrl *AgentController) watchOffloadedPods(node *corev1.Node) error {
+	m := ctrl.kubeManager
+	m.offloadedPodMutex.Lock()
+	defer m.offloadedPodMutex.Unlock()
+	if _, present := m.offloadedPodMap[node.Name]; present {
+		return nil
+	}
+	podCtrl := ctrl.createOffloadedPodController(node.Name, node.Annotations[virtualNodeClusterIDAnnotation])
+	if err := podCtrl.StartCache(ctrl.ctx, &ctrl.workers); err != nil {
+		return err
+	}
+	m.offloadedPodMap[node.Name] = podCtrl
+	return nil
+}
+
+//unwatchOffloadedPods stops the KubeController of the pods offloaded through a removed virtual node. Since
+//its pods are no more watched, their removal is notified.
+func (ctrl *AgentController) unwatchOffloadedPods(nodeName string) {
+	m := ctrl.kubeManager
+	m.offloadedPodMutex.Lock()
+	podCtrl, present := m.offloadedPodMap[nodeName]
+	delete(m.offloadedPodMap, nodeName)
+	m.offloadedPodMutex.Unlock()
+	if !present {
+		return
+	}
+	podCtrl.StopCache()
+	for _, obj := range podCtrl.Store.List() {
+		podCtrl.deleteFunc(obj)
+	}
+}
+
+//stopOffloadedPodCaches stops the KubeControllers of the pods offloaded through all the virtual nodes.
+func (m *kubeManager) stopOffloadedPodCaches() {
+	m.offloadedPodMutex.Lock()
+	defer m.offloadedPodMutex.Unlock()
+	for nodeName, podCtrl := range m.offloadedPodMap {
+		podCtrl.StopCache()
+		delete(m.offloadedPodMap, nodeName)
+	}
+}
+
+//NotifyDataOffloadedPod is a NotifyDataGeneric sub-type used to exchange data concerning a pod
+//offloaded to a foreign cluster.
+type NotifyDataOffloadedPod struct {
+	//Name of the pod.
+	Name string
+	//Namespace of the pod.
+	Namespace string
+	//NodeName is the name of the virtual node the pod is scheduled on.
+	NodeName string
+	//ClusterID of the foreign cluster running the pod.
+	ClusterID string
+	//Phase is the current PodPhase of the pod.
+	Phase string
+	//Restarts is the total number of restarts of the pod containers.
+	Restarts int32
+}
+
+//EventKey returns the key of the events regarding the peer the pod is offloaded to.
+func (d *NotifyDataOffloadedPod) EventKey() string {
+	return PeerEventKey(d.ClusterID)
+}
+
+//loadOffloadedPod loads useful data about an offloaded pod.
+func (d *NotifyDataOffloadedPod) loadOffloadedPod(pod *corev1.Pod, clusterID string) {
+	d.Name = pod.Name
+	d.Namespace = pod.Namespace
+	d.NodeName = pod.Spec.NodeName
+	d.ClusterID = clusterID
+	d.Phase = string(pod.Status.Phase)
+	d.Restarts = 0
+	for _, status := range pod.Status.ContainerStatuses {
+		d.Restarts += status.RestartCount
+	}
+}
+
+//ListOffloadedPods returns the data of the pods currently stored in the caches of the virtual nodes,
+//sorted by namespace and name.
+func (ctrl *AgentController) ListOffloadedPods() []*NotifyDataOffloadedPod {
+	var pods []*NotifyDataOffloadedPod
+	m := ctrl.kubeManager
+	if m == nil {
+		return pods
+	}
+	m.offloadedPodMutex.Lock()
+	defer m.offloadedPodMutex.Unlock()
+	for nodeName, podCtrl := range m.offloadedPodMap {
+		for _, obj := range podCtrl.Store.List() {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok || pod.Spec.NodeName != nodeName {
+				continue
+			}
+			clusterID, offloaded := ctrl.offloadingClusterID(pod)
+			if !offloaded {
+				continue
+			}
+			data := &NotifyDataOffloadedPod{}
+			data.loadOffloadedPod(pod, clusterID)
+			pods = append(pods, data)
+		}
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].Name < pods[j].Name
+	})
+	return pods
+}
+
+//offloadingClusterID returns the ClusterID of the foreign cluster a pod is offloaded to, retrieving it
+//from the cache of the virtual nodes. If the pod is not scheduled on a virtual node, offloaded == false.
+func (ctrl *AgentController) offloadingClusterID(pod *corev1.Pod) (clusterID string, offloaded bool) {
+	if pod.Spec.NodeName == "" {
+		return "", false
+	}
+	nodeCtrl := ctrl.KubeController(KRVirtualNode)
+	if nodeCtrl == nil {
+		return "", false
+	}
+	obj, exists, err := nodeCtrl.Store.GetByKey(pod.Spec.NodeName)
+	if err != nil || !exists {
+		return "", false
+	}
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return "", false
+	}
+	clusterID = node.Annotations[virtualNodeClusterIDAnnotation]
+	return clusterID, clusterID != ""
+}
+
+//			**** EVENT FUNCTIONS ****
+//	The following functions are the callbacks the OffloadedPod Controllers use to handle the events
+//	of the correspondent cache. Each Controller watches the pods of the virtual node nodeName, representing
+//	the foreign cluster clusterID.
+
+//offloadedPodAddFunc is the ADD and UPDATE event handler for the OffloadedPod KubeControllers.
+func (ctrl *AgentController) offloadedPodAddFunc(obj interface{}, nodeName string, clusterID string) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || pod.Spec.NodeName != nodeName {
+		return
+	}
+	data := &NotifyDataOffloadedPod{}
+	data.loadOffloadedPod(pod, clusterID)
+	ctrl.Notify(ChanOffloadedPodAddedOrUpdated, data)
+}
+
+//offloadedPodDeleteFunc is the DELETE event handler for the OffloadedPod KubeControllers.
+func (ctrl *AgentController) offloadedPodDeleteFunc(obj interface{}, nodeName string, clusterID string) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || pod.Spec.NodeName != nodeName {
+		return
+	}
+	data := &NotifyDataOffloadedPod{}
+	data.loadOffloadedPod(pod, clusterID)
+	ctrl.Notify(ChanOffloadedPodDeleted, data)
+}
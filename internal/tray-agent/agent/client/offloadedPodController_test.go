@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"testing"
+	"time"
+)
+
+func TestOffloadedPodNotifications(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "liqo-cl1",
+			Labels:      map[string]string{"type": "virtual-node"},
+			Annotations: map[string]string{virtualNodeClusterIDAnnotation: "cl1"},
+		},
+	}
+	if _, err := ctrl.kubeClient.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitNotifyData(t, ctrl, ChanVirtualNodeAddedOrUpdated)
+	//only the pods of the virtual node are listed and watched
+	assert.Eventually(t, func() bool {
+		return podsWatchedWithSelector(ctrl, "spec.nodeName="+node.Name)
+	}, time.Second, time.Millisecond*10, "pods of the virtual node not selected by node name")
+	pods := ctrl.kubeClient.CoreV1().Pods("default")
+	//pods running on the home cluster are ignored, also on deletion
+	local := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "local", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "physical"},
+	}
+	if _, err := pods.Create(context.TODO(), local, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pods.Delete(context.TODO(), local.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "remote", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: node.Name},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{RestartCount: 1}, {RestartCount: 2},
+			},
+		},
+	}
+	if _, err := pods.Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data := waitNotifyData(t, ctrl, ChanOffloadedPodAddedOrUpdated).(*NotifyDataOffloadedPod)
+	assert.Equal(t, "remote", data.Name, "wrong offloaded pod notified")
+	assert.Equal(t, "cl1", data.ClusterID, "wrong ClusterID for the offloaded pod")
+	assert.Equal(t, string(corev1.PodRunning), data.Phase, "wrong pod phase")
+	assert.Equal(t, int32(3), data.Restarts, "wrong restart count")
+	if err := pods.Delete(context.TODO(), pod.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data = waitNotifyData(t, ctrl, ChanOffloadedPodDeleted).(*NotifyDataOffloadedPod)
+	assert.Equal(t, "remote", data.Name, "wrong offloaded pod deleted")
+	//the removal of the virtual node stops the watch on its pods, notifying their removal
+	pod.ResourceVersion = ""
+	if _, err := pods.Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitNotifyData(t, ctrl, ChanOffloadedPodAddedOrUpdated)
+	if err := ctrl.kubeClient.CoreV1().Nodes().Delete(context.TODO(), node.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data = waitNotifyData(t, ctrl, ChanOffloadedPodDeleted).(*NotifyDataOffloadedPod)
+	assert.Equal(t, "remote", data.Name, "pod of the removed virtual node not deleted")
+	assert.Equal(t, "cl1", data.ClusterID, "wrong ClusterID for the pod of the removed virtual node")
+	waitNotifyData(t, ctrl, ChanVirtualNodeDeleted)
+	ctrl.offloadedPodMutex.Lock()
+	assert.Empty(t, ctrl.offloadedPodMap, "pods of the removed virtual node still watched")
+	ctrl.offloadedPodMutex.Unlock()
+}
+
+//podsWatchedWithSelector returns whether the pods of the cluster have been watched with the given field selector.
+func podsWatchedWithSelector(ctrl *AgentController, selector string) bool {
+	for _, action := range ctrl.kubeClient.(*fake.Clientset).Actions() {
+		if watch, ok := action.(k8stesting.WatchAction); ok && watch.GetResource().Resource == "pods" &&
+			watch.GetWatchRestrictions().Fields.String() == selector {
+			return true
+		}
+	}
+	return false
+}
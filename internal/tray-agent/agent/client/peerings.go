@@ -4,6 +4,7 @@ import (
 	"errors"
 	discovery "github.com/liqotech/liqo/apis/discovery/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
 )
 
 //StartStopOutPeering interacts with a ForeignCluster to trigger the procedure to establish a peering towards
@@ -22,3 +23,27 @@ func (ctrl *AgentController) StartStopOutPeering(foreignCluster string, start bo
 	_, err = fcCtrl.Resource(string(CRForeignCluster)).Update(foreignCluster, fc, metav1.UpdateOptions{})
 	return err
 }
+
+//ListPeers returns the data of the peers currently stored in the ForeignCluster cache, sorted by ClusterID.
+//As for the ForeignCluster events, the ForeignClusters whose ClusterID is not known yet are ignored.
+func (ctrl *AgentController) ListPeers() []*NotifyDataForeignCluster {
+	var peers []*NotifyDataForeignCluster
+	fcCtrl := ctrl.Controller(CRForeignCluster)
+	if fcCtrl == nil || !fcCtrl.Running() {
+		return peers
+	}
+	for _, obj := range fcCtrl.Store.List() {
+		fc, ok := obj.(*discovery.ForeignCluster)
+		if !ok || fc.Spec.ClusterIdentity.ClusterID == "" {
+			continue
+		}
+		data := &NotifyDataForeignCluster{}
+		data.loadPeerInfo(fc)
+		data.loadPeeringInfo(ctrl, fc)
+		peers = append(peers, data)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].ClusterID < peers[j].ClusterID
+	})
+	return peers
+}
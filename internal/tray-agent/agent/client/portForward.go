@@ -0,0 +1,444 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+//portForwardRetryInterval is the interval between two attempts to restore a broken port-forward.
+const portForwardRetryInterval = time.Second * 3
+
+//ServicePort identifies a port of a Service of the home cluster that can be forwarded to localhost.
+type ServicePort struct {
+	//Namespace of the Service.
+	Namespace string
+	//Service is the name of the Service.
+	Service string
+	//Name of the port (optional).
+	Name string
+	//Port is the number of the Service port.
+	Port int32
+}
+
+//String returns a human readable description of the ServicePort.
+func (p ServicePort) String() string {
+	str := fmt.Sprintf("%s/%s:%d", p.Namespace, p.Service, p.Port)
+	if p.Name != "" {
+		str += " (" + p.Name + ")"
+	}
+	return str
+}
+
+//NotifyDataPortForward contains the status of a port-forward.
+type NotifyDataPortForward struct {
+	//Key identifies the port-forward.
+	Key string
+	//Namespace of the forwarded Service.
+	Namespace string
+	//Service is the name of the forwarded Service.
+	Service string
+	//ServicePort is the forwarded port of the Service.
+	ServicePort int32
+	//LocalPort is the port on localhost the Service is reachable at.
+	LocalPort uint16
+	//Pod is the pod currently serving the port-forward. It is empty while the port-forward is
+	//trying to recover from a broken connection.
+	Pod string
+	//Stopped specifies whether the port-forward has been stopped.
+	Stopped bool
+	//Error is the last error occurred while restoring the port-forward.
+	Error string
+}
+
+//EventKey returns the key of the events regarding a port-forward.
+func (d *NotifyDataPortForward) EventKey() string {
+	return "port-forward/" + d.Key
+}
+
+//Address returns the local address the forwarded Service is reachable at.
+func (d *NotifyDataPortForward) Address() string {
+	return fmt.Sprintf("localhost:%d", d.LocalPort)
+}
+
+//PortForwardKey returns the key identifying the port-forward of a Service port.
+func PortForwardKey(namespace, service string, port int32) string {
+	return fmt.Sprintf("%s/%s:%d", namespace, service, port)
+}
+
+//portForward stores the state of a running port-forward.
+type portForward struct {
+	//status is the current status of the port-forward.
+	status NotifyDataPortForward
+	//stopChan is closed to stop the port-forward.
+	stopChan chan struct{}
+	stopOnce sync.Once
+	//ready is closed once the first connection of the port-forward has been either established or refused.
+	ready chan struct{}
+	//err is the reason why the first connection has been refused.
+	err   error
+	mutex sync.RWMutex
+}
+
+//stop stops the portForward. It can be called multiple times.
+func (f *portForward) stop() {
+	f.stopOnce.Do(func() {
+		close(f.stopChan)
+	})
+}
+
+//snapshot returns a copy of the current status of the portForward.
+func (f *portForward) snapshot() *NotifyDataPortForward {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	status := f.status
+	return &status
+}
+
+//portForwardManager stores the port-forwards started by the Agent.
+type portForwardManager struct {
+	//forwards contains the running port-forwards, indexed by key.
+	forwards map[string]*portForward
+	//dashboard is the key of the port-forward used to reach LiqoDash, if any.
+	dashboard string
+	mutex     sync.Mutex
+}
+
+//setDashboard marks the port-forward identified by key as the one used to reach LiqoDash.
+func (m *portForwardManager) setDashboard(key string) {
+	m.mutex.Lock()
+	m.dashboard = key
+	m.mutex.Unlock()
+}
+
+//ServicePorts returns the ports of the Services in the given namespaces, sorted by namespace, name and port.
+func (ctrl *AgentController) ServicePorts(namespaces []string) ([]ServicePort, error) {
+	if !ctrl.Connected() {
+		return nil, errors.New("no connection available")
+	}
+	ctx, cancel := ctrl.requestContext()
+	defer cancel()
+	var ports []ServicePort
+	for _, namespace := range namespaces {
+		svcL, err := ctrl.kubeClient.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range svcL.Items {
+			svc := &svcL.Items[i]
+			//Services without selectors cannot be resolved to a pod
+			if len(svc.Spec.Selector) == 0 {
+				continue
+			}
+			for _, p := range svc.Spec.Ports {
+				if p.Protocol != "" && p.Protocol != corev1.ProtocolTCP {
+					continue
+				}
+				ports = append(ports, ServicePort{Namespace: namespace, Service: svc.Name, Name: p.Name, Port: p.Port})
+			}
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		return ports[i].String() < ports[j].String()
+	})
+	return ports, nil
+}
+
+//portForwardTarget resolves a Service port to a ready pod backing the Service and the related container port.
+func (ctrl *AgentController) portForwardTarget(namespace, service string, port int32) (string, int32, error) {
+	ctx, cancel := ctrl.requestContext()
+	defer cancel()
+	svc, err := ctrl.kubeClient.CoreV1().Services(namespace).Get(ctx, service, metav1.GetOptions{})
+	if err != nil {
+		return "", 0, err
+	}
+	var svcPort *corev1.ServicePort
+	for i := range svc.Spec.Ports {
+		if svc.Spec.Ports[i].Port == port {
+			svcPort = &svc.Spec.Ports[i]
+			break
+		}
+	}
+	if svcPort == nil {
+		return "", 0, fmt.Errorf("service %s/%s does not expose port %d", namespace, service, port)
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return "", 0, fmt.Errorf("service %s/%s has no selector", namespace, service)
+	}
+	podL, err := ctrl.kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	for i := range podL.Items {
+		pod := &podL.Items[i]
+		if pod.DeletionTimestamp != nil || !podReady(pod) {
+			continue
+		}
+		target := svcPort.TargetPort
+		if target.IntValue() != 0 {
+			return pod.Name, int32(target.IntValue()), nil
+		}
+		if target.StrVal == "" {
+			return pod.Name, svcPort.Port, nil
+		}
+		//named port: look for it among the ports of the containers
+		for _, c := range pod.Spec.Containers {
+			for _, cp := range c.Ports {
+				if cp.Name == target.StrVal {
+					return pod.Name, cp.ContainerPort, nil
+				}
+			}
+		}
+	}
+	return "", 0, fmt.Errorf("no ready pod is serving %s/%s:%d", namespace, service, port)
+}
+
+//podReady returns whether a pod is running and ready.
+func podReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+//ForwardService forwards a Service port to a port of localhost, through the API server of the home cluster.
+//The local port is randomly chosen. If the port is already forwarded, the current status is returned, waiting
+//for the port-forward to be established if it is still starting.
+//
+//When the pod serving the port-forward is no longer available, the Service is resolved again and the
+//port-forward is restored on the same local port.
+func (ctrl *AgentController) ForwardService(namespace, service string, port int32) (*NotifyDataPortForward, error) {
+	if !ctrl.Connected() || ctrl.restConfig == nil {
+		return nil, errors.New("no connection available")
+	}
+	key := PortForwardKey(namespace, service, port)
+	manager := ctrl.portForwards
+	manager.mutex.Lock()
+	if f, present := manager.forwards[key]; present {
+		manager.mutex.Unlock()
+		<-f.ready
+		if f.err != nil {
+			return nil, f.err
+		}
+		return f.snapshot(), nil
+	}
+	f := &portForward{
+		status: NotifyDataPortForward{
+			Key:         key,
+			Namespace:   namespace,
+			Service:     service,
+			ServicePort: port,
+		},
+		stopChan: make(chan struct{}),
+		ready:    make(chan struct{}),
+	}
+	//the key is reserved while connecting, without blocking the other port-forwards
+	manager.forwards[key] = f
+	manager.mutex.Unlock()
+	errChan, err := ctrl.dialPortForward(f)
+	if err != nil {
+		manager.mutex.Lock()
+		if manager.forwards[key] == f {
+			delete(manager.forwards, key)
+		}
+		manager.mutex.Unlock()
+		f.stop()
+		f.err = err
+		close(f.ready)
+		return nil, err
+	}
+	close(f.ready)
+	ctrl.workers.Go(func() {
+		ctrl.supervisePortForward(f, errChan)
+	})
+	return f.snapshot(), nil
+}
+
+//dialPortForward starts the port-forward to a pod serving the Service, waiting for the local port to be ready.
+//The returned channel receives the result of the port-forward once it terminates.
+func (ctrl *AgentController) dialPortForward(f *portForward) (<-chan error, error) {
+	status := f.snapshot()
+	pod, podPort, err := ctrl.portForwardTarget(status.Namespace, status.Service, status.ServicePort)
+	if err != nil {
+		return nil, err
+	}
+	transport, upgrader, err := spdy.RoundTripperFor(ctrl.restConfig)
+	if err != nil {
+		return nil, err
+	}
+	req := ctrl.kubeClient.CoreV1().RESTClient().Post().Resource("pods").Namespace(status.Namespace).
+		Name(pod).SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+	readyChan := make(chan struct{})
+	//each connection has its own stop channel, so that it can be closed without stopping the portForward
+	fwStop := make(chan struct{})
+	fw, err := portforward.NewOnAddresses(dialer, []string{"localhost"},
+		[]string{fmt.Sprintf("%d:%d", status.LocalPort, podPort)}, fwStop, readyChan, ioutil.Discard,
+		ioutil.Discard)
+	if err != nil {
+		return nil, err
+	}
+	errChan := make(chan error, 1)
+	done := make(chan struct{})
+	abort := make(chan struct{})
+	ctrl.workers.Go(func() {
+		errChan <- fw.ForwardPorts()
+		close(done)
+	})
+	ctrl.workers.Go(func() {
+		select {
+		case <-f.stopChan:
+		case <-abort:
+		case <-done:
+		}
+		close(fwStop)
+	})
+	select {
+	case <-readyChan:
+	case err = <-errChan:
+		if err == nil {
+			err = errors.New("port-forward closed")
+		}
+		return nil, err
+	}
+	ports, err := fw.GetPorts()
+	if err != nil || len(ports) < 1 {
+		close(abort)
+		return nil, errors.New("port-forward not ready")
+	}
+	f.mutex.Lock()
+	f.status.LocalPort = ports[0].Local
+	f.status.Pod = pod
+	f.status.Error = ""
+	f.mutex.Unlock()
+	return errChan, nil
+}
+
+//supervisePortForward waits for the termination of a port-forward, restoring it until it is stopped.
+func (ctrl *AgentController) supervisePortForward(f *portForward, errChan <-chan error) {
+	ctrl.Notify(ChanPortForward, f.snapshot())
+	for {
+		select {
+		case <-f.stopChan:
+			f.mutex.Lock()
+			f.status.Stopped = true
+			f.status.Pod = ""
+			f.mutex.Unlock()
+			ctrl.Notify(ChanPortForward, f.snapshot())
+			return
+		case <-errChan:
+			//the connection to the pod has been lost
+			f.mutex.Lock()
+			f.status.Pod = ""
+			f.mutex.Unlock()
+			ctrl.Notify(ChanPortForward, f.snapshot())
+			errChan = ctrl.restorePortForward(f)
+			if errChan != nil {
+				ctrl.Notify(ChanPortForward, f.snapshot())
+			}
+		}
+	}
+}
+
+//restorePortForward periodically tries to restore a broken port-forward. It returns nil if the
+//port-forward is stopped in the meanwhile.
+func (ctrl *AgentController) restorePortForward(f *portForward) <-chan error {
+	for {
+		select {
+		case <-f.stopChan:
+			return nil
+		case <-time.After(portForwardRetryInterval):
+		}
+		errChan, err := ctrl.dialPortForward(f)
+		if err == nil {
+			return errChan
+		}
+		f.mutex.Lock()
+		changed := f.status.Error != err.Error()
+		f.status.Error = err.Error()
+		f.mutex.Unlock()
+		if changed {
+			ctrl.Notify(ChanPortForward, f.snapshot())
+		}
+	}
+}
+
+//PortForwards returns the status of the established port-forwards, sorted by key.
+func (ctrl *AgentController) PortForwards() []*NotifyDataPortForward {
+	manager := ctrl.portForwards
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	var forwards []*NotifyDataPortForward
+	for _, f := range manager.forwards {
+		//skip the port-forwards still connecting
+		select {
+		case <-f.ready:
+			forwards = append(forwards, f.snapshot())
+		default:
+		}
+	}
+	sort.Slice(forwards, func(i, j int) bool {
+		return forwards[i].Key < forwards[j].Key
+	})
+	return forwards
+}
+
+//StopPortForward stops the port-forward identified by key. If it is used to reach LiqoDash, the
+//cached LiqoDash endpoint is invalidated as well.
+func (ctrl *AgentController) StopPortForward(key string) error {
+	manager := ctrl.portForwards
+	manager.mutex.Lock()
+	f, present := manager.forwards[key]
+	if !present {
+		manager.mutex.Unlock()
+		return fmt.Errorf("no port-forward %s is running", key)
+	}
+	f.stop()
+	delete(manager.forwards, key)
+	dashboard := manager.dashboard == key
+	manager.mutex.Unlock()
+	if dashboard {
+		ctrl.refreshDashboard(true)
+	}
+	return nil
+}
+
+//stopDashboardForward stops the port-forward used to reach LiqoDash, if any.
+func (ctrl *AgentController) stopDashboardForward() {
+	manager := ctrl.portForwards
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	if f, present := manager.forwards[manager.dashboard]; present {
+		f.stop()
+		delete(manager.forwards, manager.dashboard)
+	}
+	manager.dashboard = ""
+}
+
+//StopPortForwards stops all the running port-forwards.
+func (ctrl *AgentController) StopPortForwards() {
+	manager := ctrl.portForwards
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	for key, f := range manager.forwards {
+		f.stop()
+		delete(manager.forwards, key)
+	}
+	manager.dashboard = ""
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/rest"
+	"sync"
+	"testing"
+)
+
+func TestPortForwardTarget(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	c := ctrl.kubeClient.CoreV1()
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "web"},
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80, TargetPort: intstr.FromString("http")},
+				{Name: "metrics", Port: 9090},
+			},
+		},
+	}
+	if _, err := c.Services("apps").Create(context.TODO(), svc, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	ports, err := ctrl.ServicePorts([]string{"apps"})
+	if assert.NoError(t, err, "service ports not listed") && assert.Len(t, ports, 2) {
+		assert.Equal(t, "apps/web:80 (http)", ports[0].String(), "wrong service port description")
+	}
+	//no pod serving the Service
+	_, _, err = ctrl.portForwardTarget("apps", "web", 80)
+	assert.Error(t, err, "service resolved without ready pods")
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "apps", Labels: map[string]string{"app": "web"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "web",
+			Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+		}}},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	if _, err = c.Pods("apps").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	name, port, err := ctrl.portForwardTarget("apps", "web", 80)
+	if assert.NoError(t, err, "service not resolved") {
+		assert.Equal(t, "web-1", name, "wrong pod selected")
+		assert.Equal(t, int32(8080), port, "named target port not resolved")
+	}
+	_, port, err = ctrl.portForwardTarget("apps", "web", 9090)
+	if assert.NoError(t, err, "service not resolved") {
+		assert.Equal(t, int32(9090), port, "default target port not resolved")
+	}
+	_, _, err = ctrl.portForwardTarget("apps", "web", 443)
+	assert.Error(t, err, "service resolved on a missing port")
+	//the mocked AgentController cannot reach any API server
+	_, err = ctrl.ForwardService("apps", "web", 80)
+	assert.Error(t, err, "port-forward started without a connection")
+	assert.Empty(t, ctrl.PortForwards(), "failed port-forward registered")
+	assert.Error(t, ctrl.StopPortForward(PortForwardKey("apps", "web", 80)), "missing port-forward stopped")
+}
+
+func TestForwardServiceFailure(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	c := ctrl.kubeClient.CoreV1()
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "web"},
+			Ports:    []corev1.ServicePort{{Port: 80}},
+		},
+	}
+	if _, err := c.Services("apps").Create(context.TODO(), svc, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	//the connection is available, but no pod is serving the Service
+	ctrl.restConfig = &rest.Config{Host: "http://127.0.0.1:1"}
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ctrl.ForwardService("apps", "web", 80)
+			assert.Error(t, err, "port-forward started without a ready pod")
+		}()
+	}
+	wg.Wait()
+	assert.Empty(t, ctrl.PortForwards(), "failed port-forward registered")
+	//a broken port-forward that cannot be restored reports the error
+	f := &portForward{
+		status: NotifyDataPortForward{
+			Key:         PortForwardKey("apps", "web", 80),
+			Namespace:   "apps",
+			Service:     "web",
+			ServicePort: 80,
+		},
+		stopChan: make(chan struct{}),
+		ready:    make(chan struct{}),
+	}
+	go ctrl.restorePortForward(f)
+	data := waitNotifyData(t, ctrl, ChanPortForward).(*NotifyDataPortForward)
+	assert.NotEmpty(t, data.Error, "restore error not notified")
+	f.stop()
+	f.stop()
+}
@@ -0,0 +1,257 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	clusterConfig "github.com/liqotech/liqo/apis/config/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"strconv"
+	"strings"
+)
+
+const (
+	//virtualNodeSelector is the label selector used to identify the Liqo virtual nodes.
+	virtualNodeSelector = "type=virtual-node"
+	//physicalNodeSelector is the label selector used to identify the physical nodes of the home cluster.
+	physicalNodeSelector = "type!=virtual-node"
+	//maxSharingPercentage is the maximum value allowed for the ResourceSharingPercentage.
+	maxSharingPercentage = 100
+)
+
+//SharingPolicy describes the policy the home cluster applies to offer its resources to the foreign clusters.
+//
+//The SharingPolicy is global: it is stored in the AdvertisementConfig of the ClusterConfig CR and Liqo applies it
+//to every peer. Liqo does not support per-peer sharing policies yet, so the Agent cannot offer a different
+//amount of resources to a single peer.
+type SharingPolicy struct {
+	//Enabled specifies whether the home cluster is offering its resources to the peers, i.e. whether it
+	//broadcasts its Advertisement.
+	Enabled bool
+	//Percentage is the percentage of the available resources of the home cluster offered to each peer.
+	Percentage int32
+}
+
+//SharedResources contains the effective amount of resources the home cluster offers to each peer
+//according to the current SharingPolicy.
+type SharedResources struct {
+	//Cpu is the amount of CPU offered to each peer.
+	Cpu resource.Quantity
+	//Memory is the amount of memory offered to each peer.
+	Memory resource.Quantity
+	//Pods is the number of pods each peer can run on the home cluster.
+	Pods resource.Quantity
+}
+
+//clusterConfig returns the ClusterConfig CR currently stored in the cache of the ClusterConfig CRDController.
+func (ctrl *AgentController) clusterConfig() (*clusterConfig.ClusterConfig, error) {
+	if !ctrl.connected {
+		return nil, errors.New("no connection available")
+	}
+	objL := ctrl.Controller(CRClusterConfig).Store.List()
+	if len(objL) < 1 {
+		return nil, errors.New("no ClusterConfig is present")
+	}
+	conf, ok := objL[0].(*clusterConfig.ClusterConfig)
+	if !ok {
+		return nil, errors.New("cannot cast received object to ClusterConfig")
+	}
+	return conf, nil
+}
+
+//SharingPolicy returns the current SharingPolicy of the home cluster.
+func (ctrl *AgentController) SharingPolicy() (*SharingPolicy, error) {
+	conf, err := ctrl.clusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	outConf := conf.Spec.AdvertisementConfig.OutgoingConfig
+	return &SharingPolicy{
+		Enabled:    outConf.EnableBroadcaster,
+		Percentage: outConf.ResourceSharingPercentage,
+	}, nil
+}
+
+//SetSharingPolicy writes a new SharingPolicy to the ClusterConfig CR, after validating it.
+func (ctrl *AgentController) SetSharingPolicy(policy *SharingPolicy) error {
+	if policy == nil {
+		return errors.New("nil sharing policy")
+	}
+	if err := validateSharingPercentage(policy.Percentage); err != nil {
+		return err
+	}
+	conf, err := ctrl.clusterConfig()
+	if err != nil {
+		return err
+	}
+	newConf := conf.DeepCopy()
+	outConf := &newConf.Spec.AdvertisementConfig.OutgoingConfig
+	outConf.EnableBroadcaster = policy.Enabled
+	outConf.ResourceSharingPercentage = policy.Percentage
+	_, err = ctrl.Controller(CRClusterConfig).Resource(string(CRClusterConfig)).Update(newConf.Name, newConf,
+		metav1.UpdateOptions{})
+	return err
+}
+
+//validateSharingPercentage checks whether a value is a valid ResourceSharingPercentage.
+func validateSharingPercentage(percentage int32) error {
+	if percentage < 0 || percentage > maxSharingPercentage {
+		return fmt.Errorf("the sharing percentage must be between 0 and %d", maxSharingPercentage)
+	}
+	return nil
+}
+
+//ParseSharingPercentage converts a user provided text (e.g. "30" or "30%") into a valid ResourceSharingPercentage.
+func ParseSharingPercentage(input string) (int32, error) {
+	str := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(input), "%"))
+	value, err := strconv.ParseInt(str, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a valid percentage", input)
+	}
+	percentage := int32(value)
+	if err = validateSharingPercentage(percentage); err != nil {
+		return 0, err
+	}
+	return percentage, nil
+}
+
+//availableResources computes the resources of the home cluster physical nodes not yet requested by
+//the pods scheduled on them.
+func (ctrl *AgentController) availableResources() (corev1.ResourceList, error) {
+	if !ctrl.connected {
+		return nil, errors.New("no connection available")
+	}
+	ctx, cancel := ctrl.requestContext()
+	defer cancel()
+	c := ctrl.kubeClient
+	nodeL, err := c.CoreV1().Nodes().List(ctx, metav1.ListOptions{
+		LabelSelector: physicalNodeSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cpu := resource.NewMilliQuantity(0, resource.DecimalSI)
+	mem := resource.NewQuantity(0, resource.BinarySI)
+	pods := resource.NewQuantity(0, resource.DecimalSI)
+	physicalNodes := make(map[string]bool, len(nodeL.Items))
+	for i := range nodeL.Items {
+		node := &nodeL.Items[i]
+		physicalNodes[node.Name] = true
+		alloc := node.Status.Allocatable
+		cpu.Add(*alloc.Cpu())
+		mem.Add(*alloc.Memory())
+		pods.Add(*alloc.Pods())
+	}
+	//only the pods scheduled on the physical nodes consume home resources: pending pods are not
+	//counted, as they may be scheduled on a virtual node.
+	podL, err := c.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.AndSelectors(
+			fields.OneTermNotEqualSelector("spec.nodeName", ""),
+			fields.OneTermNotEqualSelector("status.phase", string(corev1.PodSucceeded)),
+			fields.OneTermNotEqualSelector("status.phase", string(corev1.PodFailed)),
+		).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for j := range podL.Items {
+		pod := &podL.Items[j]
+		if !physicalNodes[pod.Spec.NodeName] || pod.Status.Phase == corev1.PodSucceeded ||
+			pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		req := podRequests(pod)
+		cpu.Sub(*req.Cpu())
+		mem.Sub(*req.Memory())
+		pods.Sub(*resource.NewQuantity(1, resource.DecimalSI))
+	}
+	zero := resource.Quantity{}
+	for _, q := range []*resource.Quantity{cpu, mem, pods} {
+		if q.Cmp(zero) < 0 {
+			q.Set(0)
+		}
+	}
+	return corev1.ResourceList{
+		corev1.ResourceCPU:    *cpu,
+		corev1.ResourceMemory: *mem,
+		corev1.ResourcePods:   *pods,
+	}, nil
+}
+
+//podRequests computes the effective resource requests of a pod, as the scheduler does: the init containers
+//run one at a time before the containers, so each resource is the maximum between the requests of any init
+//container and the sum of the requests of the containers.
+func podRequests(pod *corev1.Pod) corev1.ResourceList {
+	reqs := corev1.ResourceList{}
+	for i := range pod.Spec.Containers {
+		for name, q := range pod.Spec.Containers[i].Resources.Requests {
+			sum := reqs[name]
+			sum.Add(q)
+			reqs[name] = sum
+		}
+	}
+	for i := range pod.Spec.InitContainers {
+		for name, q := range pod.Spec.InitContainers[i].Resources.Requests {
+			if current, ok := reqs[name]; !ok || q.Cmp(current) > 0 {
+				reqs[name] = q.DeepCopy()
+			}
+		}
+	}
+	return reqs
+}
+
+//SharedResources returns the effective amount of resources currently offered to each peer.
+func (ctrl *AgentController) SharedResources() (*SharedResources, error) {
+	policy, err := ctrl.SharingPolicy()
+	if err != nil {
+		return nil, err
+	}
+	available, err := ctrl.availableResources()
+	if err != nil {
+		return nil, err
+	}
+	shared := &SharedResources{}
+	if !policy.Enabled {
+		return shared, nil
+	}
+	pct := int64(policy.Percentage)
+	cpu, mem, pods := available[corev1.ResourceCPU], available[corev1.ResourceMemory], available[corev1.ResourcePods]
+	shared.Cpu = *resource.NewMilliQuantity(cpu.MilliValue()*pct/100, resource.DecimalSI)
+	shared.Memory = *resource.NewQuantity(mem.Value()*pct/100, resource.BinarySI)
+	shared.Pods = *resource.NewQuantity(pods.Value()*pct/100, resource.DecimalSI)
+	return shared, nil
+}
+
+//SharingPercentageForAmount returns the lowest ResourceSharingPercentage that allows the home cluster to offer
+//at least the requested amount of a resource (CPU, memory or pods) to each peer.
+//
+//Since the ResourceSharingPercentage is unique for all resources, the amount of the other ones changes accordingly.
+func (ctrl *AgentController) SharingPercentageForAmount(name corev1.ResourceName, amount string) (int32, error) {
+	requested, err := resource.ParseQuantity(strings.TrimSpace(amount))
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a valid %s amount", amount, name)
+	}
+	if requested.Sign() < 0 {
+		return 0, fmt.Errorf("the %s amount cannot be negative", name)
+	}
+	available, err := ctrl.availableResources()
+	if err != nil {
+		return 0, err
+	}
+	total, ok := available[name]
+	if !ok {
+		return 0, fmt.Errorf("resource %s cannot be shared", name)
+	}
+	if total.IsZero() {
+		return 0, fmt.Errorf("no %s is currently available", name)
+	}
+	req, tot := requested.MilliValue(), total.MilliValue()
+	//round up to the closest integer percentage
+	pct := (req*100 + tot - 1) / tot
+	if pct > maxSharingPercentage {
+		return 0, fmt.Errorf("the requested %s amount exceeds the available one (%s)", name, total.String())
+	}
+	return int32(pct), nil
+}
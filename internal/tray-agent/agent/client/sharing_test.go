@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestParseSharingPercentage(t *testing.T) {
+	valid := map[string]int32{"0": 0, "30": 30, " 45% ": 45, "100%": 100}
+	for input, expected := range valid {
+		pct, err := ParseSharingPercentage(input)
+		assert.NoErrorf(t, err, "valid percentage '%s' refused", input)
+		assert.Equalf(t, expected, pct, "percentage '%s' wrongly parsed", input)
+	}
+	for _, input := range []string{"", "-1", "101", "abc", "3.5"} {
+		_, err := ParseSharingPercentage(input)
+		assert.Errorf(t, err, "invalid percentage '%s' accepted", input)
+	}
+}
+
+func TestSharingPolicy(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	//no ClusterConfig available
+	_, err := ctrl.SharingPolicy()
+	assert.Error(t, err, "sharing policy retrieved without a ClusterConfig")
+	conf, _ := createClusterConfig()
+	conf.Spec.AdvertisementConfig.OutgoingConfig.ResourceSharingPercentage = 30
+	conf.Spec.AdvertisementConfig.OutgoingConfig.EnableBroadcaster = true
+	ccCtrl := ctrl.Controller(CRClusterConfig)
+	if err = ccCtrl.Store.Add(conf); err != nil {
+		t.Fatal(err)
+	}
+	//wait for the cache events to be handled before removing the ClusterConfig
+	waitNotifyData(t, ctrl, ChanClusterConfig)
+	defer func() {
+		_ = ccCtrl.Store.Delete(conf)
+	}()
+	policy, err := ctrl.SharingPolicy()
+	if assert.NoError(t, err, "sharing policy not retrieved") {
+		assert.True(t, policy.Enabled, "sharing policy should be enabled")
+		assert.Equal(t, int32(30), policy.Percentage, "wrong sharing percentage")
+	}
+	//update policy
+	assert.Error(t, ctrl.SetSharingPolicy(&SharingPolicy{Enabled: true, Percentage: 150}),
+		"invalid sharing policy accepted")
+	if assert.NoError(t, ctrl.SetSharingPolicy(&SharingPolicy{Enabled: false, Percentage: 50}),
+		"valid sharing policy refused") {
+		waitNotifyData(t, ctrl, ChanClusterConfig)
+	}
+	policy, err = ctrl.SharingPolicy()
+	if assert.NoError(t, err, "sharing policy not retrieved after update") {
+		assert.False(t, policy.Enabled, "sharing policy should be disabled")
+		assert.Equal(t, int32(50), policy.Percentage, "sharing percentage not updated")
+	}
+	//effective resources on a cluster without nodes
+	shared, err := ctrl.SharedResources()
+	if assert.NoError(t, err, "shared resources not computed") {
+		assert.True(t, shared.Cpu.IsZero(), "no CPU should be shared")
+	}
+	_, err = ctrl.SharingPercentageForAmount(corev1.ResourceCPU, "1")
+	assert.Error(t, err, "sharing percentage computed without available resources")
+}
+
+func TestAvailableResources(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	c := ctrl.kubeClient.CoreV1()
+	requests := func(cpu string, mem string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(mem),
+		}}
+	}
+	physical := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "physical"},
+		Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("4"),
+			corev1.ResourceMemory: resource.MustParse("8Gi"),
+			corev1.ResourcePods:   resource.MustParse("10"),
+		}},
+	}
+	physical2 := physical.DeepCopy()
+	physical2.Name = "physical2"
+	virtual := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "liqo-cl1",
+		Labels: map[string]string{"type": "virtual-node"}}}
+	pods := []*corev1.Pod{
+		//the init container requests more CPU than the containers, which request more memory
+		{ObjectMeta: metav1.ObjectMeta{Name: "home", Namespace: "default"},
+			Spec: corev1.PodSpec{NodeName: physical.Name,
+				InitContainers: []corev1.Container{{Name: "init", Resources: requests("2", "1Gi")}},
+				Containers: []corev1.Container{{Name: "c1", Resources: requests("500m", "1Gi")},
+					{Name: "c2", Resources: requests("500m", "1Gi")}}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+		//pending and offloaded pods do not consume home resources
+		{ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"},
+			Spec:   corev1.PodSpec{Containers: []corev1.Container{{Name: "c", Resources: requests("1", "1Gi")}}},
+			Status: corev1.PodStatus{Phase: corev1.PodPending}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "offloaded", Namespace: "default"},
+			Spec: corev1.PodSpec{NodeName: virtual.Name,
+				Containers: []corev1.Container{{Name: "c", Resources: requests("1", "1Gi")}}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+		//completed pods release their resources
+		{ObjectMeta: metav1.ObjectMeta{Name: "completed", Namespace: "default"},
+			Spec: corev1.PodSpec{NodeName: physical.Name,
+				Containers: []corev1.Container{{Name: "c", Resources: requests("1", "1Gi")}}},
+			Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "home2", Namespace: "apps"},
+			Spec: corev1.PodSpec{NodeName: physical2.Name,
+				Containers: []corev1.Container{{Name: "c", Resources: requests("1", "2Gi")}}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+	}
+	for _, node := range []*corev1.Node{physical, physical2, virtual} {
+		if _, err := c.Nodes().Create(context.TODO(), node, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, pod := range pods {
+		if _, err := c.Pods(pod.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fakeClient := ctrl.kubeClient.(*fake.Clientset)
+	fakeClient.ClearActions()
+	available, err := ctrl.availableResources()
+	if !assert.NoError(t, err, "available resources not computed") {
+		return
+	}
+	podLists := 0
+	for _, action := range fakeClient.Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "pods" {
+			podLists++
+		}
+	}
+	assert.Equal(t, 1, podLists, "the pods should be listed once for all the physical nodes")
+	cpu, mem, podCount := available[corev1.ResourceCPU], available[corev1.ResourceMemory],
+		available[corev1.ResourcePods]
+	assert.Equal(t, "5", cpu.String(), "wrong available CPU")
+	assert.Equal(t, "12Gi", mem.String(), "wrong available memory")
+	assert.Equal(t, "18", podCount.String(), "wrong available pods")
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"sort"
+)
+
+//virtualNodeClusterIDAnnotation is the annotation of a virtual node containing the ClusterID of the
+//foreign cluster it represents.
+const virtualNodeClusterIDAnnotation = "cluster-id"
+
+//createVirtualNodeController creates a new KubeController for the Liqo virtual nodes.
+func (ctrl *AgentController) createVirtualNodeController() *KubeController {
+	factory := informers.NewSharedInformerFactoryWithOptions(ctrl.kubeClient, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = virtualNodeSelector
+		}))
+	controller := newKubeController(KRVirtualNode, factory,
+		func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Core().V1().Nodes().Informer()
+		})
+	controller.addFunc = ctrl.virtualNodeAddFunc
+	controller.updateFunc = ctrl.virtualNodeUpdateFunc
+	controller.deleteFunc = ctrl.virtualNodeDeleteFunc
+	return controller
+}
+
+//NotifyDataVirtualNode is a NotifyDataGeneric sub-type used to exchange data concerning the virtual node
+//associated to an outgoing peering.
+type NotifyDataVirtualNode struct {
+	//Name of the virtual node.
+	Name string
+	//ClusterID of the foreign cluster represented by the virtual node.
+	ClusterID string
+	//Ready specifies whether the virtual node is ready to run pods.
+	Ready bool
+	//Cpu is the literal representation of the allocatable CPU of the virtual node.
+	Cpu string
+	//Memory is the literal representation of the allocatable memory of the virtual node.
+	Memory string
+	//Pods is the literal representation of the maximum number of pods of the virtual node.
+	Pods string
+}
+
+//EventKey returns the key of the events regarding the peer represented by the virtual node.
+func (d *NotifyDataVirtualNode) EventKey() string {
+	return PeerEventKey(d.ClusterID)
+}
+
+//loadVirtualNode loads useful data about a virtual node.
+func (d *NotifyDataVirtualNode) loadVirtualNode(node *corev1.Node) {
+	d.Name = node.Name
+	d.ClusterID = node.Annotations[virtualNodeClusterIDAnnotation]
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			d.Ready = condition.Status == corev1.ConditionTrue
+			break
+		}
+	}
+	alloc := node.Status.Allocatable
+	d.Cpu = alloc.Cpu().String()
+	d.Memory = alloc.Memory().String()
+	d.Pods = alloc.Pods().String()
+}
+
+//ListVirtualNodes returns the data of the virtual nodes currently stored in the cache, sorted by name.
+//As for the virtual node events, the nodes with no ClusterID are ignored.
+func (ctrl *AgentController) ListVirtualNodes() []*NotifyDataVirtualNode {
+	var nodes []*NotifyDataVirtualNode
+	nodeCtrl := ctrl.KubeController(KRVirtualNode)
+	if nodeCtrl == nil || !nodeCtrl.Running() {
+		return nodes
+	}
+	for _, obj := range nodeCtrl.Store.List() {
+		node, ok := obj.(*corev1.Node)
+		if !ok || node.Annotations[virtualNodeClusterIDAnnotation] == "" {
+			continue
+		}
+		data := &NotifyDataVirtualNode{}
+		data.loadVirtualNode(node)
+		nodes = append(nodes, data)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	return nodes
+}
+
+//			**** EVENT FUNCTIONS ****
+//	The following functions are the callbacks the VirtualNode Controller uses to handle the events
+//	of the correspondent cache.
+
+//virtualNodeAddFunc is the ADD event handler for the VirtualNode KubeController.
+func (ctrl *AgentController) virtualNodeAddFunc(obj interface{}) {
+	node, ok := obj.(*corev1.Node)
+	if !ok || node.Annotations[virtualNodeClusterIDAnnotation] == "" {
+		return
+	}
+	data := &NotifyDataVirtualNode{}
+	data.loadVirtualNode(node)
+	ctrl.Notify(ChanVirtualNodeAddedOrUpdated, data)
+	//the watch can only fail when the AgentController is being shut down
+	_ = ctrl.watchOffloadedPods(node)
+}
+
+//virtualNodeUpdateFunc is the UPDATE event handler for the VirtualNode KubeController.
+func (ctrl *AgentController) virtualNodeUpdateFunc(_ interface{}, newObj interface{}) {
+	ctrl.virtualNodeAddFunc(newObj)
+}
+
+//virtualNodeDeleteFunc is the DELETE event handler for the VirtualNode KubeController.
+func (ctrl *AgentController) virtualNodeDeleteFunc(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return
+	}
+	ctrl.unwatchOffloadedPods(node.Name)
+	data := &NotifyDataVirtualNode{}
+	data.loadVirtualNode(node)
+	ctrl.Notify(ChanVirtualNodeDeleted, data)
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestVirtualNodeNotifications(t *testing.T) {
+	UseMockedAgentController()
+	DestroyMockedAgentController()
+	ctrl := GetAgentController()
+	nodes := ctrl.kubeClient.CoreV1().Nodes()
+	//physical nodes are not watched
+	physical := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "physical"}}
+	if _, err := nodes.Create(context.TODO(), physical, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "liqo-cl1",
+			Labels:      map[string]string{"type": "virtual-node"},
+			Annotations: map[string]string{virtualNodeClusterIDAnnotation: "cl1"},
+		},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("2"),
+				corev1.ResourceMemory: resource.MustParse("4Gi"),
+				corev1.ResourcePods:   resource.MustParse("110"),
+			},
+		},
+	}
+	node, err := nodes.Create(context.TODO(), node, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := waitNotifyData(t, ctrl, ChanVirtualNodeAddedOrUpdated).(*NotifyDataVirtualNode)
+	assert.Equal(t, "cl1", data.ClusterID, "wrong ClusterID for the virtual node")
+	assert.False(t, data.Ready, "virtual node should not be ready")
+	assert.Equal(t, "2", data.Cpu, "wrong allocatable CPU")
+	assert.Equal(t, "110", data.Pods, "wrong allocatable pods")
+	//readiness change
+	node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+	if _, err = nodes.Update(context.TODO(), node, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data = waitNotifyData(t, ctrl, ChanVirtualNodeAddedOrUpdated).(*NotifyDataVirtualNode)
+	assert.True(t, data.Ready, "virtual node should be ready")
+	//deletion
+	if err = nodes.Delete(context.TODO(), node.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data = waitNotifyData(t, ctrl, ChanVirtualNodeDeleted).(*NotifyDataVirtualNode)
+	assert.Equal(t, node.Name, data.Name, "wrong virtual node deleted")
+}
@@ -0,0 +1,198 @@
+package client
+
+import (
+	"k8s.io/client-go/tools/cache"
+	"sync"
+)
+
+//queueItem contains the pending changes of an object watched by a cache.
+type queueItem struct {
+	//key identifies the object in the cache.
+	key string
+	//added specifies whether the object has been created since the last time it was handled.
+	added bool
+	//deleted specifies whether the object has been deleted.
+	deleted bool
+	//oldObj is the state of the object the last time it was handled.
+	oldObj interface{}
+	//obj is the latest state of the object.
+	obj interface{}
+}
+
+/*workQueue decouples the informer of a cache from the handlers of its events. The informer only records the
+events in the queue, without blocking, while a worker executes the handlers. Like the workqueues of the kubernetes
+controllers, the queue keeps only the latest state of each object: a burst of events regarding the same object
+collapses into a single call of the proper handler, e.g.
+
+	ADD + UPDATE    -> addFunc(latest)
+	UPDATE + UPDATE -> updateFunc(handled, latest)
+	UPDATE + DELETE -> deleteFunc(latest)
+	ADD + DELETE    -> (none)
+	DELETE + ADD    -> updateFunc(deleted, latest)
+
+The objects are handled in the order of their first pending event.*/
+type workQueue struct {
+	//addFunc is the handler for the 'resource added' event.
+	addFunc func(obj interface{})
+	//updateFunc is the handler for the 'resource updated' event.
+	updateFunc func(oldObj interface{}, newObj interface{})
+	//deleteFunc is the handler for the 'resource deleted' event.
+	deleteFunc func(obj interface{})
+	//items contains the pending objects, in FIFO order.
+	items []*queueItem
+	//pending contains the pending objects, by key.
+	pending map[string]*queueItem
+	//signal wakes the worker up when an object is queued.
+	signal chan struct{}
+	mutex  sync.Mutex
+}
+
+//newWorkQueue creates a workQueue for the handlers of a cache. Nil handlers are ignored.
+func newWorkQueue(addFunc func(obj interface{}), updateFunc func(oldObj interface{}, newObj interface{}),
+	deleteFunc func(obj interface{})) *workQueue {
+	return &workQueue{
+		addFunc:    addFunc,
+		updateFunc: updateFunc,
+		deleteFunc: deleteFunc,
+		pending:    make(map[string]*queueItem),
+		signal:     make(chan struct{}, 1),
+	}
+}
+
+//handlers returns the event handlers to be registered on the informer.
+func (q *workQueue) handlers() cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			q.enqueue(obj, func(item *queueItem) bool {
+				if item.deleted {
+					//the object has been recreated: it is handled as updated
+					item.deleted = false
+					if item.oldObj == nil {
+						item.oldObj = item.obj
+					}
+				} else if item.obj == nil {
+					item.added = true
+				}
+				item.obj = obj
+				return true
+			})
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			q.enqueue(newObj, func(item *queueItem) bool {
+				if item.obj == nil {
+					item.oldObj = oldObj
+				}
+				item.obj = newObj
+				return true
+			})
+		},
+		DeleteFunc: func(obj interface{}) {
+			q.enqueue(obj, func(item *queueItem) bool {
+				item.obj = obj
+				item.deleted = true
+				//an object created and deleted before being handled is ignored
+				return !item.added
+			})
+		},
+	}
+}
+
+//enqueue applies an event to the pending changes of obj. If merge returns false, the object is no more pending.
+func (q *workQueue) enqueue(obj interface{}, merge func(item *queueItem) bool) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	item, present := q.pending[key]
+	if !present {
+		item = &queueItem{key: key}
+	}
+	if !merge(item) {
+		if present {
+			q.remove(item)
+		}
+		return
+	}
+	if !present {
+		q.pending[key] = item
+		q.items = append(q.items, item)
+	}
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+//remove removes a pending object. It must be called holding the mutex.
+func (q *workQueue) remove(item *queueItem) {
+	delete(q.pending, item.key)
+	for n, it := range q.items {
+		if it == item {
+			q.items = append(q.items[:n], q.items[n+1:]...)
+			return
+		}
+	}
+}
+
+//pop removes and returns the first pending object, if any.
+func (q *workQueue) pop() (*queueItem, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	item := q.items[0]
+	q.items = q.items[1:]
+	delete(q.pending, item.key)
+	return item, true
+}
+
+//Len returns the number of pending objects.
+func (q *workQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.items)
+}
+
+//run executes the handlers of the pending objects until stop is closed.
+func (q *workQueue) run(stop <-chan struct{}) {
+	for {
+		select {
+		case <-q.signal:
+		case <-stop:
+			return
+		}
+		for {
+			item, ok := q.pop()
+			if !ok {
+				break
+			}
+			q.handle(item)
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+	}
+}
+
+//handle executes the handler for the pending changes of an object.
+func (q *workQueue) handle(item *queueItem) {
+	switch {
+	case item.deleted:
+		if q.deleteFunc != nil {
+			q.deleteFunc(item.obj)
+		}
+	case item.added:
+		if q.addFunc != nil {
+			q.addFunc(item.obj)
+		}
+	default:
+		if q.updateFunc != nil {
+			q.updateFunc(item.oldObj, item.obj)
+		}
+	}
+}
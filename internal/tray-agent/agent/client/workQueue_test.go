@@ -0,0 +1,121 @@
+package client
+
+import (
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sync"
+	"testing"
+	"time"
+)
+
+//queueRecorder records the handlers called by a workQueue.
+type queueRecorder struct {
+	calls []string
+	mutex sync.Mutex
+}
+
+func (r *queueRecorder) record(call string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *queueRecorder) Calls() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+//queuedPod returns a pod whose version is stored in the ResourceVersion.
+func queuedPod(name string, version string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", ResourceVersion: version}}
+}
+
+func newRecordedWorkQueue(r *queueRecorder, block chan struct{}) *workQueue {
+	version := func(obj interface{}) string {
+		pod := obj.(*corev1.Pod)
+		return pod.Name + "@" + pod.ResourceVersion
+	}
+	return newWorkQueue(func(obj interface{}) {
+		<-block
+		r.record("add " + version(obj))
+	}, func(oldObj interface{}, newObj interface{}) {
+		<-block
+		r.record("update " + version(oldObj) + " " + version(newObj))
+	}, func(obj interface{}) {
+		<-block
+		r.record("delete " + version(obj))
+	})
+}
+
+func TestWorkQueue_Coalescing(t *testing.T) {
+	r := &queueRecorder{}
+	block := make(chan struct{})
+	close(block)
+	q := newRecordedWorkQueue(r, block)
+	h := q.handlers()
+	//ADD + UPDATE
+	h.OnAdd(queuedPod("a", "1"))
+	h.OnUpdate(queuedPod("a", "1"), queuedPod("a", "2"))
+	//UPDATE + UPDATE
+	h.OnUpdate(queuedPod("b", "1"), queuedPod("b", "2"))
+	h.OnUpdate(queuedPod("b", "2"), queuedPod("b", "3"))
+	//ADD + DELETE
+	h.OnAdd(queuedPod("c", "1"))
+	h.OnDelete(queuedPod("c", "1"))
+	//UPDATE + DELETE
+	h.OnUpdate(queuedPod("d", "1"), queuedPod("d", "2"))
+	h.OnDelete(queuedPod("d", "2"))
+	//DELETE + ADD
+	h.OnDelete(queuedPod("e", "1"))
+	h.OnAdd(queuedPod("e", "2"))
+	assert.Equal(t, 4, q.Len(), "events not coalesced")
+	stop := make(chan struct{})
+	defer close(stop)
+	go q.run(stop)
+	assert.Eventually(t, func() bool {
+		return len(r.Calls()) == 4
+	}, 5*time.Second, time.Millisecond, "events not handled")
+	assert.Equal(t, []string{
+		"add a@2",
+		"update b@1 b@3",
+		"delete d@2",
+		"update e@1 e@2",
+	}, r.Calls(), "wrong handlers called")
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestWorkQueue_NonBlocking(t *testing.T) {
+	r := &queueRecorder{}
+	block := make(chan struct{})
+	q := newRecordedWorkQueue(r, block)
+	h := q.handlers()
+	stop := make(chan struct{})
+	defer close(stop)
+	go q.run(stop)
+	//the worker blocks on the first event, while the informer keeps sending updates
+	h.OnAdd(queuedPod("a", "0"))
+	assert.Eventually(t, func() bool {
+		return q.Len() == 0
+	}, 5*time.Second, time.Millisecond, "first event not handled")
+	done := make(chan struct{})
+	go func() {
+		for n := 1; n <= 1000; n++ {
+			h.OnUpdate(queuedPod("a", "0"), queuedPod("a", "1000"))
+			h.OnUpdate(queuedPod("b", "0"), queuedPod("b", "1000"))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("informer blocked by the handlers")
+	}
+	assert.Equal(t, 2, q.Len(), "updates not coalesced")
+	close(block)
+	assert.Eventually(t, func() bool {
+		return len(r.Calls()) == 3
+	}, 5*time.Second, time.Millisecond, "events not handled")
+	assert.Equal(t, []string{"add a@0", "update a@0 a@1000", "update b@0 b@1000"}, r.Calls())
+}
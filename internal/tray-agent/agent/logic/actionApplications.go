@@ -0,0 +1,529 @@
+package logic
+
+import (
+	"fmt"
+	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
+	app "github.com/liqotech/liqo-agent/internal/tray-agent/app-indicator"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*This file contains internal variables and helper functions for the ACTION aApps in charge of launching
+applications on the home cluster (and therefore on its peers) and tracking their execution.*/
+
+//aApps is the tag of the ACTION "Applications".
+const aApps = "A_APPS"
+
+// set of frequently used tags for menu entries regarding applications
+const (
+	tagAppRunImage      = "appRunImage"
+	tagAppRunManifest   = "appRunManifest"
+	tagAppReloadCatalog = "appReloadCatalog"
+	//tagAppCatalogPrefix is the prefix of the tags of the OPTIONS associated to the catalog templates.
+	tagAppCatalogPrefix = "appCatalog/"
+)
+
+// set of frequently used title strings for menu entries regarding applications
+const (
+	titleApps             = "Applications"
+	titleAppRunImage      = "Run container image..."
+	titleAppRunManifest   = "Run saved manifest..."
+	titleAppReloadCatalog = "Reload application catalog"
+)
+
+// set of frequently used text strings for menu entries regarding applications
+const (
+	//labelAppStarting is the status of an application whose pods are not created yet.
+	labelAppStarting = "Starting"
+	//labelAppRunning is the status of an application with at least a running pod.
+	labelAppRunning = "Running"
+	//labelAppAnyPeer is the choice to let Kubernetes schedule the application pods.
+	labelAppAnyPeer = "Any cluster"
+	//labelAppHome is the label used for the pods running on the home cluster.
+	labelAppHome = "home cluster"
+)
+
+//appLaunchGrace is the time an application launched by the Agent is displayed while it has no pods.
+const appLaunchGrace = time.Minute
+
+//appState stores the status of an application launched by the Agent.
+type appState struct {
+	//name of the application.
+	name string
+	//namespace of the application.
+	namespace string
+	//pods of the application, indexed by name.
+	pods map[string]*client.NotifyDataAppPod
+	//clusters records the names of the clusters that ran at least a pod of the application.
+	clusters map[string]bool
+	//running specifies whether the application has already been notified as running.
+	running bool
+	//launched is the time the application has been launched by the Agent. It is zero for the applications
+	//launched by a previous Agent execution.
+	launched time.Time
+}
+
+//appCache stores the applications launched by the Agent, indexed by namespace/name.
+var appCache = struct {
+	apps map[string]*appState
+	sync.RWMutex
+}{apps: make(map[string]*appState)}
+
+//appCatalog stores the templates of the application catalog currently displayed in the ACTION aApps,
+//indexed by the tag of the related OPTION.
+var appCatalog = struct {
+	templates map[string]*client.AppTemplate
+	sync.RWMutex
+}{templates: make(map[string]*client.AppTemplate)}
+
+//newAppState returns an empty appState.
+func newAppState(namespace, name string) *appState {
+	return &appState{
+		name:      name,
+		namespace: namespace,
+		pods:      make(map[string]*client.NotifyDataAppPod),
+		clusters:  make(map[string]bool),
+	}
+}
+
+//justLaunched returns whether the application has been launched by the Agent in the last appLaunchGrace.
+func (s *appState) justLaunched() bool {
+	return !s.launched.IsZero() && time.Since(s.launched) < appLaunchGrace
+}
+
+//appKey returns the key of an application in the appCache.
+func appKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+//startActionApplications is the wrapper function to register the ACTION "Applications".
+func startActionApplications(i *app.Indicator) {
+	a := i.AddAction(titleApps, aApps, nil)
+	a.AddOption(titleAppRunImage, tagAppRunImage, "", false, func(args ...interface{}) {
+		appHelperRunImage(args[0].(*app.Indicator))
+	}, i)
+	a.AddOption(titleAppRunManifest, tagAppRunManifest, "", false, func(args ...interface{}) {
+		appHelperRunManifest(args[0].(*app.Indicator))
+	}, i)
+	a.AddOption(titleAppReloadCatalog, tagAppReloadCatalog, "", false, func(args ...interface{}) {
+		loadAppCatalog(args[0].(*app.Indicator), true)
+	}, i)
+	loadAppCatalog(i, false)
+}
+
+//loadAppCatalog reads the application catalog, adding an OPTION for each template to the ACTION aApps.
+//The OPTIONS of the templates no longer available are hidden. If warn is true, the invalid templates
+//are reported to the user.
+func loadAppCatalog(i *app.Indicator, warn bool) {
+	a, present := i.Action(aApps)
+	if !present {
+		return
+	}
+	catalog, err := client.LoadAppCatalog()
+	if err != nil && warn {
+		i.ShowWarning("LIQO AGENT", "Liqo Agent could not load the whole application catalog:\n"+err.Error())
+	}
+	available := make(map[string]*client.AppTemplate)
+	for _, t := range catalog {
+		available[tagAppCatalogPrefix+appKey(t.Namespace, t.Name)] = t
+	}
+	appCatalog.Lock()
+	for tag := range appCatalog.templates {
+		if _, ok := available[tag]; !ok {
+			delete(appCatalog.templates, tag)
+			if opt, ok := a.Option(tag); ok {
+				opt.SetIsVisible(false)
+			}
+		}
+	}
+	for tag, t := range available {
+		appCatalog.templates[tag] = t
+	}
+	appCatalog.Unlock()
+	for tag, t := range available {
+		opt, present := a.Option(tag)
+		if !present {
+			opt = a.AddOption(t.Name, tag, t.Description, true, func(args ...interface{}) {
+				appHelperToggleTemplate(args[0].(*app.Indicator), args[1].(string))
+			}, i, tag)
+		}
+		opt.SetTooltip(t.Description)
+		opt.SetIsVisible(true)
+		refreshApplication(i, t.Namespace, t.Name)
+	}
+}
+
+//trackApplication registers a just launched application, displaying it in the ACTION aApps.
+func trackApplication(i *app.Indicator, namespace, name string) {
+	appCache.Lock()
+	key := appKey(namespace, name)
+	state, present := appCache.apps[key]
+	if !present {
+		state = newAppState(namespace, name)
+		appCache.apps[key] = state
+	}
+	state.launched = time.Now()
+	appCache.Unlock()
+	refreshApplication(i, namespace, name)
+}
+
+//untrackApplication removes a stopped application from the ACTION aApps.
+func untrackApplication(i *app.Indicator, namespace, name string) {
+	appCache.Lock()
+	delete(appCache.apps, appKey(namespace, name))
+	appCache.Unlock()
+	refreshApplication(i, namespace, name)
+}
+
+/*storeAppPod saves (add = true) or removes a pod of an application. It returns whether the application
+has just started running and whether the pod has just failed.
+
+The pods being deleted (e.g. the ones of a stopped application) are removed as well. An application is
+dropped together with its last pod, unless it has been launched in the last appLaunchGrace.*/
+func storeAppPod(data *client.NotifyDataAppPod, add bool) (started bool, failed bool) {
+	appCache.Lock()
+	defer appCache.Unlock()
+	if data.Terminating {
+		add = false
+	}
+	key := appKey(data.Namespace, data.App)
+	state, present := appCache.apps[key]
+	if !present {
+		//applications launched by a previous Agent execution are tracked as well
+		if !add {
+			return false, false
+		}
+		state = newAppState(data.Namespace, data.App)
+		appCache.apps[key] = state
+	}
+	old, oldPresent := state.pods[data.Name]
+	if !add {
+		delete(state.pods, data.Name)
+		if len(state.pods) == 0 && !state.justLaunched() {
+			delete(appCache.apps, key)
+		}
+		return false, false
+	}
+	state.pods[data.Name] = data
+	if data.ClusterID != "" {
+		state.clusters[data.ClusterID] = true
+	} else if data.Phase == string(corev1.PodRunning) {
+		state.clusters[""] = true
+	}
+	if data.Phase == string(corev1.PodRunning) && !state.running {
+		state.running = true
+		started = true
+	}
+	failed = data.Phase == string(corev1.PodFailed) && (!oldPresent || old.Phase != string(corev1.PodFailed))
+	return started, failed
+}
+
+/*resyncApplications replaces the stored pods of the applications with the current ones, refreshing their entries
+in the ACTION aApps. The applications with no pods are dropped, unless they have just been launched.
+
+The applications that started or failed in the meantime are not notified.*/
+func resyncApplications(i *app.Indicator, pods []*client.NotifyDataAppPod) {
+	//the applications to refresh, by key
+	apps := make(map[string]*appState)
+	appCache.Lock()
+	for key, state := range appCache.apps {
+		state.pods = make(map[string]*client.NotifyDataAppPod)
+		apps[key] = state
+	}
+	appCache.Unlock()
+	for _, data := range pods {
+		storeAppPod(data, true)
+	}
+	appCache.Lock()
+	for key, state := range appCache.apps {
+		if len(state.pods) == 0 && !state.justLaunched() {
+			delete(appCache.apps, key)
+		}
+		apps[key] = state
+	}
+	appCache.Unlock()
+	for _, state := range apps {
+		refreshApplication(i, state.namespace, state.name)
+	}
+}
+
+//describeApplication returns the status of an application and the names of the clusters that ran its pods.
+func describeApplication(i *app.Indicator, state *appState) (status string, clusters string) {
+	var list []string
+	for clusterID := range state.clusters {
+		if clusterID == "" {
+			list = append(list, labelAppHome)
+		} else {
+			list = append(list, peerName(i, clusterID))
+		}
+	}
+	sort.Strings(list)
+	clusters = strings.Join(list, ", ")
+	if len(state.pods) == 0 {
+		return labelAppStarting, clusters
+	}
+	phases := make(map[string]int)
+	for _, pod := range state.pods {
+		phases[pod.Phase]++
+	}
+	if phases[string(corev1.PodRunning)] > 0 {
+		status = fmt.Sprintf("%s %d/%d", labelAppRunning, phases[string(corev1.PodRunning)], len(state.pods))
+	} else {
+		var list []string
+		for phase := range phases {
+			list = append(list, phase)
+		}
+		sort.Strings(list)
+		status = strings.Join(list, ", ")
+	}
+	return status, clusters
+}
+
+//peerName returns the name of a peer given its ClusterID.
+func peerName(i *app.Indicator, clusterID string) string {
+	if peer, present := i.Status().Peer(clusterID); present {
+		peer.RLock()
+		defer peer.RUnlock()
+		if !peer.Unknown && peer.ClusterName != "" {
+			return peer.ClusterName
+		}
+	}
+	return clusterID
+}
+
+//refreshApplication updates the entry of an application inside the ACTION aApps. The applications of the
+//catalog are displayed by their OPTION, the other ones by a LIST entry.
+func refreshApplication(i *app.Indicator, namespace, name string) {
+	a, present := i.Action(aApps)
+	if !present {
+		return
+	}
+	key := appKey(namespace, name)
+	appCache.RLock()
+	state, present := appCache.apps[key]
+	var status, clusters string
+	if present {
+		status, clusters = describeApplication(i, state)
+	}
+	appCache.RUnlock()
+	title := name
+	if present {
+		title = fmt.Sprintf("%s (%s) [%s]", name, namespace, status)
+		if clusters != "" {
+			title += " on " + clusters
+		}
+	}
+	if opt, ok := a.Option(tagAppCatalogPrefix + key); ok && opt.IsVisible() {
+		a.FreeListChild(key)
+		opt.SetTitle(title)
+		opt.SetIsChecked(present)
+		return
+	}
+	if !present {
+		a.FreeListChild(key)
+		return
+	}
+	appNode, present := a.ListChild(key)
+	if !present {
+		appNode = a.UseListChild("", key)
+		appNode.Connect(false, appHelperStop, i, namespace, name)
+	}
+	appNode.SetTitle(title)
+}
+
+//notifyApplication raises the notifications regarding the execution of an application.
+func notifyApplication(i *app.Indicator, data *client.NotifyDataAppPod, started bool, failed bool) {
+	if started {
+		where := labelAppHome
+		if data.ClusterID != "" {
+			where = peerName(i, data.ClusterID)
+		}
+		i.Notify("APPLICATION RUNNING", fmt.Sprintf("%s is running on %s", data.App, where),
+			app.NotifyIconDefault, app.IconLiqoNil)
+	}
+	if failed {
+		i.Notify("APPLICATION FAILED", fmt.Sprintf("a pod of %s failed", data.App),
+			app.NotifyIconWarning, app.IconLiqoWarning)
+	}
+}
+
+//The following functions are the callbacks associated to the entries of the tray menu "Applications" sub-section.
+
+//appHelperRunImage asks the user the parameters of a container image to run, then launches it.
+func appHelperRunImage(i *app.Indicator) {
+	ctrl := i.AgentCtrl()
+	gui := app.GetGuiProvider()
+	if !ctrl.Connected() || gui.Mocked() {
+		return
+	}
+	image, ok := gui.Prompt("RUN APPLICATION", "Insert the container image to run (e.g. nginx:latest)", "")
+	if !ok || strings.TrimSpace(image) == "" {
+		return
+	}
+	name, ok := gui.Prompt("RUN APPLICATION", "Insert the name of the application", defaultAppName(image))
+	if !ok {
+		return
+	}
+	var kinds []string
+	for _, k := range client.AppKinds {
+		kinds = append(kinds, string(k))
+	}
+	kind, ok := gui.Choose("RUN APPLICATION", "Choose how to run the application:\n"+
+		"Deployment for services, Job for tasks that run to completion", kinds)
+	if !ok {
+		return
+	}
+	objs, err := client.NewAppObjects(&client.AppSpec{
+		Name:  strings.TrimSpace(name),
+		Image: image,
+		Kind:  client.AppKind(kind),
+	})
+	if err != nil {
+		i.ShowWarning("LIQO AGENT", "Invalid application:\n"+err.Error())
+		return
+	}
+	appHelperDeploy(i, strings.TrimSpace(name), objs)
+}
+
+//appHelperRunManifest asks the user a file containing the Kubernetes manifests of an application, then launches it.
+func appHelperRunManifest(i *app.Indicator) {
+	ctrl := i.AgentCtrl()
+	gui := app.GetGuiProvider()
+	if !ctrl.Connected() || gui.Mocked() {
+		return
+	}
+	path, ok := gui.SelectFile("Select the manifest of the application", "*.yaml *.yml *.json")
+	if !ok {
+		return
+	}
+	objs, err := client.LoadManifestFile(path)
+	if err != nil {
+		i.ShowWarning("LIQO AGENT", "Invalid manifest:\n"+err.Error())
+		return
+	}
+	name, ok := gui.Prompt("RUN APPLICATION", "Insert the name of the application", defaultAppName(path))
+	if !ok {
+		return
+	}
+	appHelperDeploy(i, strings.TrimSpace(name), objs)
+}
+
+//appHelperDeploy asks the user where to run an application, then deploys it.
+func appHelperDeploy(i *app.Indicator, name string, objs []runtime.Object) {
+	target, ok := appHelperChooseTarget(i)
+	if !ok {
+		return
+	}
+	if err := i.AgentCtrl().DeployApplication(name, objs, target); err != nil {
+		i.ShowWarning("LIQO AGENT", "Liqo Agent could not run the application:\n"+err.Error())
+		return
+	}
+	trackApplication(i, target.Namespace, name)
+}
+
+//appHelperChooseTarget asks the user the namespace and the (optional) peer for an application.
+func appHelperChooseTarget(i *app.Indicator) (*client.AppTarget, bool) {
+	namespaces := i.AgentCtrl().OffloadingNamespaces()
+	if len(namespaces) == 0 {
+		i.ShowWarning("LIQO AGENT", "No namespace is enabled to the offloading.\n"+
+			"Enable one from the \""+titleNamespaces+"\" menu.")
+		return nil, false
+	}
+	sort.Strings(namespaces)
+	namespace, ok := app.GetGuiProvider().Choose("RUN APPLICATION", "Choose the namespace of the application",
+		namespaces)
+	if !ok {
+		return nil, false
+	}
+	target := &client.AppTarget{Namespace: namespace}
+	choices := []string{labelAppAnyPeer}
+	peers := make(map[string]string)
+	virtualNodeCache.RLock()
+	for clusterID := range virtualNodeCache.nodes {
+		name := peerName(i, clusterID)
+		peers[name] = clusterID
+		choices = append(choices, name)
+	}
+	virtualNodeCache.RUnlock()
+	sort.Strings(choices[1:])
+	choice, ok := app.GetGuiProvider().Choose("RUN APPLICATION", "Choose the cluster that will run the application",
+		choices)
+	if !ok {
+		return nil, false
+	}
+	target.ClusterID = peers[choice]
+	return target, true
+}
+
+//appHelperStop asks the user to confirm the stop of an application, then removes it.
+func appHelperStop(args ...interface{}) {
+	if len(args) < 3 {
+		panic("wrong function arity: missing app-indicator.*Indicator, namespace and name parameters")
+	}
+	i := args[0].(*app.Indicator)
+	namespace := args[1].(string)
+	name := args[2].(string)
+	ctrl := i.AgentCtrl()
+	if !ctrl.Connected() {
+		return
+	}
+	if !app.GetGuiProvider().Mocked() {
+		confirm := app.GetGuiProvider().Confirm("STOP APPLICATION", fmt.Sprintf("Do you want to stop %s (%s)?",
+			name, namespace))
+		if !confirm {
+			return
+		}
+	}
+	if err := ctrl.StopApplication(name, namespace); err != nil {
+		i.ShowWarning("LIQO AGENT", "Liqo Agent could not stop the application:\n"+err.Error())
+		return
+	}
+	untrackApplication(i, namespace, name)
+}
+
+//appHelperToggleTemplate deploys the application of a catalog template or, if already running, stops it.
+func appHelperToggleTemplate(i *app.Indicator, tag string) {
+	ctrl := i.AgentCtrl()
+	appCatalog.RLock()
+	t, present := appCatalog.templates[tag]
+	appCatalog.RUnlock()
+	if !present || !ctrl.Connected() {
+		return
+	}
+	appCache.RLock()
+	_, running := appCache.apps[appKey(t.Namespace, t.Name)]
+	appCache.RUnlock()
+	if running {
+		if err := ctrl.StopApplication(t.Name, t.Namespace); err != nil {
+			i.ShowWarning("LIQO AGENT", "Liqo Agent could not stop the application:\n"+err.Error())
+			refreshApplication(i, t.Namespace, t.Name)
+			return
+		}
+		untrackApplication(i, t.Namespace, t.Name)
+		return
+	}
+	if err := ctrl.DeployTemplate(t); err != nil {
+		i.ShowWarning("LIQO AGENT", "Liqo Agent could not run the application:\n"+err.Error())
+		refreshApplication(i, t.Namespace, t.Name)
+		return
+	}
+	trackApplication(i, t.Namespace, t.Name)
+}
+
+//defaultAppName proposes an application name from an image name or a file path.
+func defaultAppName(source string) string {
+	name := source
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	for _, sep := range []string{":", "@", "."} {
+		if idx := strings.Index(name, sep); idx >= 0 {
+			name = name[:idx]
+		}
+	}
+	return strings.ToLower(name)
+}
@@ -0,0 +1,107 @@
+package logic
+
+import (
+	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
+	app "github.com/liqotech/liqo-agent/internal/tray-agent/app-indicator"
+)
+
+/*This file contains internal variables and helper functions for the ACTION aDiscovery in charge of displaying
+and editing the discovery settings of the home cluster.*/
+
+//aDiscovery is the tag of the ACTION "Discovery settings".
+const aDiscovery = "A_DISCOVERY"
+
+// set of frequently used tags for menu entries regarding the discovery settings
+const (
+	tagDiscoveryEnable     = "discoveryEnable"
+	tagDiscoveryAdvertise  = "discoveryAdvertise"
+	tagDiscoveryAutoJoin   = "discoveryAutoJoin"
+	tagDiscoveryAutoJoinUn = "discoveryAutoJoinUntrusted"
+)
+
+// set of frequently used title strings for menu entries regarding the discovery settings
+const (
+	titleDiscovery           = "Discovery settings"
+	titleDiscoveryEnable     = "Discover peers in the LAN"
+	titleDiscoveryAdvertise  = "Advertise this cluster in the LAN"
+	titleDiscoveryAutoJoin   = "Auto-join trusted peers"
+	titleDiscoveryAutoJoinUn = "Auto-join untrusted peers"
+)
+
+//discoveryFlag is a function returning a pointer to the single DiscoverySettings flag managed by a menu entry.
+type discoveryFlag func(s *client.DiscoverySettings) *bool
+
+//discoveryFlags associates each menu entry of the ACTION aDiscovery with the flag it controls.
+var discoveryFlags = map[string]discoveryFlag{
+	tagDiscoveryEnable: func(s *client.DiscoverySettings) *bool {
+		return &s.EnableDiscovery
+	},
+	tagDiscoveryAdvertise: func(s *client.DiscoverySettings) *bool {
+		return &s.EnableAdvertisement
+	},
+	tagDiscoveryAutoJoin: func(s *client.DiscoverySettings) *bool {
+		return &s.AutoJoin
+	},
+	tagDiscoveryAutoJoinUn: func(s *client.DiscoverySettings) *bool {
+		return &s.AutoJoinUntrusted
+	},
+}
+
+//startActionDiscovery is the wrapper function to register the ACTION "Discovery settings".
+func startActionDiscovery(i *app.Indicator) {
+	a := i.AddAction(titleDiscovery, aDiscovery, nil)
+	options := []struct{ tag, title string }{
+		{tagDiscoveryEnable, titleDiscoveryEnable},
+		{tagDiscoveryAdvertise, titleDiscoveryAdvertise},
+		{tagDiscoveryAutoJoin, titleDiscoveryAutoJoin},
+		{tagDiscoveryAutoJoinUn, titleDiscoveryAutoJoinUn},
+	}
+	for _, o := range options {
+		a.AddOption(o.title, o.tag, "", true, func(args ...interface{}) {
+			discoveryHelperToggle(args[0].(*app.Indicator), args[1].(string))
+		}, i, o.tag)
+	}
+	var settings *client.DiscoverySettings
+	if ctrl := i.AgentCtrl(); ctrl.Connected() {
+		settings, _ = ctrl.DiscoverySettings()
+	}
+	refreshDiscovery(i, settings)
+}
+
+//refreshDiscovery updates the content of the ACTION aDiscovery according to the current DiscoverySettings.
+//If settings is nil, the discovery settings are considered unavailable and the menu entries are disabled.
+func refreshDiscovery(i *app.Indicator, settings *client.DiscoverySettings) {
+	a, present := i.Action(aDiscovery)
+	if !present {
+		return
+	}
+	for tag, flag := range discoveryFlags {
+		opt, ok := a.Option(tag)
+		if !ok {
+			continue
+		}
+		opt.SetIsEnabled(settings != nil)
+		if settings != nil {
+			opt.SetIsChecked(*flag(settings))
+		}
+	}
+}
+
+//discoveryHelperToggle switches the value of the DiscoverySettings flag associated with the menu entry tag.
+func discoveryHelperToggle(i *app.Indicator, tag string) {
+	ctrl := i.AgentCtrl()
+	flag, ok := discoveryFlags[tag]
+	if !ctrl.Connected() || !ok {
+		return
+	}
+	settings, err := ctrl.DiscoverySettings()
+	if err == nil {
+		*flag(settings) = !*flag(settings)
+		err = ctrl.SetDiscoverySettings(settings)
+	}
+	if err != nil {
+		i.ShowWarning("LIQO AGENT", "Liqo Agent could not change the discovery settings:\n"+err.Error())
+		return
+	}
+	refreshDiscovery(i, settings)
+}
@@ -0,0 +1,88 @@
+package logic
+
+import (
+	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
+	app "github.com/liqotech/liqo-agent/internal/tray-agent/app-indicator"
+)
+
+/*This file contains internal variables and helper functions for the ACTION aNamespaces in charge of displaying
+and editing which namespaces of the home cluster can be offloaded to the peers.*/
+
+const (
+	//aNamespaces is the tag of the ACTION "Namespaces".
+	aNamespaces = "A_NAMESPACES"
+	//titleNamespaces is the title of the ACTION "Namespaces".
+	titleNamespaces = "Namespaces offloading"
+)
+
+//startActionNamespaces is the wrapper function to register the ACTION "Namespaces".
+//The namespaces are then listed by the namespace listeners.
+func startActionNamespaces(i *app.Indicator) {
+	a := i.AddAction(titleNamespaces, aNamespaces, nil)
+	a.UseCheckboxListChildren()
+}
+
+//refreshNamespace updates the entry of a namespace inside the ACTION aNamespaces. The namespaces reflected
+//by the peers are not listed, since their offloading is managed by Liqo.
+func refreshNamespace(i *app.Indicator, data *client.NotifyDataNamespace) {
+	a, present := i.Action(aNamespaces)
+	if !present || data.IncomingClusterID != "" {
+		return
+	}
+	nsNode, present := a.ListChild(data.Name)
+	if !present {
+		nsNode = a.UseListChild(data.Name, data.Name)
+		nsNode.Connect(false, namespaceHelperToggle, i, data.Name)
+	}
+	nsNode.SetIsChecked(data.OffloadingEnabled)
+}
+
+//removeNamespace removes the entry of a deleted namespace from the ACTION aNamespaces.
+func removeNamespace(i *app.Indicator, data *client.NotifyDataNamespace) {
+	if a, present := i.Action(aNamespaces); present {
+		a.FreeListChild(data.Name)
+	}
+}
+
+//resyncNamespaces rebuilds the entries of the ACTION aNamespaces from the current namespaces.
+func resyncNamespaces(i *app.Indicator, namespaces []*client.NotifyDataNamespace) {
+	a, present := i.Action(aNamespaces)
+	if !present {
+		return
+	}
+	current := make(map[string]bool)
+	for _, data := range namespaces {
+		if data.IncomingClusterID == "" {
+			current[data.Name] = true
+			refreshNamespace(i, data)
+		}
+	}
+	for _, nsNode := range a.ListChildren() {
+		if !current[nsNode.Tag()] {
+			a.FreeListChild(nsNode.Tag())
+		}
+	}
+}
+
+//namespaceHelperToggle enables or disables the offloading of a namespace. The checkbox of the entry
+//is then updated by the namespace listeners.
+func namespaceHelperToggle(args ...interface{}) {
+	if len(args) < 2 {
+		panic("wrong function arity: missing app-indicator.*Indicator and namespace parameters")
+	}
+	i := args[0].(*app.Indicator)
+	namespace := args[1].(string)
+	a, present := i.Action(aNamespaces)
+	if !present {
+		return
+	}
+	nsNode, present := a.ListChild(namespace)
+	ctrl := i.AgentCtrl()
+	if !present || !ctrl.Connected() {
+		return
+	}
+	if err := ctrl.SetNamespaceOffloading(namespace, !nsNode.IsChecked()); err != nil {
+		i.ShowWarning("LIQO AGENT", "Liqo Agent could not change the offloading of namespace "+namespace+
+			":\n"+err.Error())
+	}
+}
@@ -0,0 +1,174 @@
+package logic
+
+import (
+	"fmt"
+	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
+	app "github.com/liqotech/liqo-agent/internal/tray-agent/app-indicator"
+	"github.com/skratchdot/open-golang/open"
+)
+
+/*This file contains internal variables and helper functions for the ACTION aPortForward in charge of
+forwarding the Services of the home cluster (whose pods may run on a peer) to localhost.*/
+
+//aPortForward is the tag of the ACTION "Port forwarding".
+const aPortForward = "A_PORT_FORWARD"
+
+//tagPortForwardNew is the tag of the OPTION to start a new port-forward.
+const tagPortForwardNew = "portForwardNew"
+
+// set of frequently used title strings for menu entries regarding port-forwards
+const (
+	titlePortForward    = "Port forwarding"
+	titlePortForwardNew = "Forward service to localhost..."
+)
+
+// set of frequently used text strings for menu entries regarding port-forwards
+const (
+	//labelPortForwardRestoring is the status of a port-forward whose pod is no longer available.
+	labelPortForwardRestoring = "restoring"
+	portForwardActionCopy     = "Copy address"
+	portForwardActionOpen     = "Open in browser"
+	portForwardActionStop     = "Stop forwarding"
+)
+
+//startActionPortForward is the wrapper function to register the ACTION "Port forwarding".
+func startActionPortForward(i *app.Indicator) {
+	a := i.AddAction(titlePortForward, aPortForward, nil)
+	a.AddOption(titlePortForwardNew, tagPortForwardNew, "", false, func(args ...interface{}) {
+		portForwardHelperNew(args[0].(*app.Indicator))
+	}, i)
+}
+
+//refreshPortForward updates the entry of a port-forward inside the ACTION aPortForward.
+func refreshPortForward(i *app.Indicator, data *client.NotifyDataPortForward) {
+	a, present := i.Action(aPortForward)
+	if !present {
+		return
+	}
+	if data.Stopped {
+		a.FreeListChild(data.Key)
+		return
+	}
+	fwNode, present := a.ListChild(data.Key)
+	if !present {
+		fwNode = a.UseListChild("", data.Key)
+		fwNode.Connect(false, portForwardHelperActions, i, data.Key)
+	}
+	status := data.Address()
+	if data.Pod == "" {
+		status = labelPortForwardRestoring
+	}
+	fwNode.SetTitle(fmt.Sprintf("%s → %s", data.Key, status))
+	if data.Error != "" {
+		fwNode.SetTooltip(data.Error)
+	} else {
+		fwNode.SetTooltip(data.Pod)
+	}
+}
+
+//resyncPortForwards rebuilds the entries of the ACTION aPortForward from the current port-forwards.
+func resyncPortForwards(i *app.Indicator, forwards []*client.NotifyDataPortForward) {
+	a, present := i.Action(aPortForward)
+	if !present {
+		return
+	}
+	current := make(map[string]bool)
+	for _, data := range forwards {
+		current[data.Key] = true
+		refreshPortForward(i, data)
+	}
+	for _, fwNode := range a.ListChildren() {
+		if !current[fwNode.Tag()] {
+			a.FreeListChild(fwNode.Tag())
+		}
+	}
+}
+
+//The following functions are the callbacks associated to the entries of the tray menu "Port forwarding" sub-section.
+
+//portForwardHelperNew asks the user the Service port to forward, then starts the port-forward.
+func portForwardHelperNew(i *app.Indicator) {
+	ctrl := i.AgentCtrl()
+	if !ctrl.Connected() || app.GetGuiProvider().Mocked() {
+		return
+	}
+	ports, err := ctrl.ServicePorts(ctrl.OffloadingNamespaces())
+	if err != nil {
+		i.ShowWarning("LIQO AGENT", "Liqo Agent could not retrieve the services:\n"+err.Error())
+		return
+	}
+	if len(ports) == 0 {
+		i.ShowWarning("LIQO AGENT", "No service is available in the namespaces enabled to the offloading.")
+		return
+	}
+	choices := make([]string, len(ports))
+	for idx, p := range ports {
+		choices[idx] = p.String()
+	}
+	choice, ok := app.GetGuiProvider().Choose("PORT FORWARDING", "Choose the service to forward to localhost", choices)
+	if !ok {
+		return
+	}
+	for _, p := range ports {
+		if p.String() != choice {
+			continue
+		}
+		data, err := ctrl.ForwardService(p.Namespace, p.Service, p.Port)
+		if err != nil {
+			i.ShowWarning("LIQO AGENT", "Liqo Agent could not forward the service:\n"+err.Error())
+			return
+		}
+		refreshPortForward(i, data)
+		portForwardHelperCopy(i, data.Address())
+		return
+	}
+}
+
+//portForwardHelperActions lets the user choose an action for a running port-forward.
+func portForwardHelperActions(args ...interface{}) {
+	if len(args) < 2 {
+		panic("wrong function arity: missing app-indicator.*Indicator and port-forward key parameters")
+	}
+	i := args[0].(*app.Indicator)
+	key := args[1].(string)
+	if app.GetGuiProvider().Mocked() {
+		return
+	}
+	ctrl := i.AgentCtrl()
+	var data *client.NotifyDataPortForward
+	for _, fw := range ctrl.PortForwards() {
+		if fw.Key == key {
+			data = fw
+		}
+	}
+	if data == nil {
+		return
+	}
+	choice, ok := app.GetGuiProvider().Choose("PORT FORWARDING", fmt.Sprintf("%s forwarded to %s", key, data.Address()),
+		[]string{portForwardActionCopy, portForwardActionOpen, portForwardActionStop})
+	if !ok {
+		return
+	}
+	switch choice {
+	case portForwardActionCopy:
+		portForwardHelperCopy(i, data.Address())
+	case portForwardActionOpen:
+		if err := open.Start("http://" + data.Address()); err != nil {
+			i.ShowWarning("LIQO AGENT", "Liqo Agent could not open the browser")
+		}
+	case portForwardActionStop:
+		if err := ctrl.StopPortForward(key); err != nil {
+			i.ShowWarning("LIQO AGENT", "Liqo Agent could not stop the port-forward:\n"+err.Error())
+		}
+	}
+}
+
+//portForwardHelperCopy copies the local address of a port-forward in the clipboard, notifying the user.
+func portForwardHelperCopy(i *app.Indicator, address string) {
+	if err := app.GetGuiProvider().Copy(address); err != nil {
+		i.Notify("PORT FORWARDING", "Service available at "+address, app.NotifyIconDefault, app.IconLiqoNil)
+		return
+	}
+	i.Notify("PORT FORWARDING", "Service available at "+address+"\n(address copied in your clipboard)",
+		app.NotifyIconDefault, app.IconLiqoNil)
+}
@@ -0,0 +1,256 @@
+package logic
+
+import (
+	"fmt"
+	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
+	app "github.com/liqotech/liqo-agent/internal/tray-agent/app-indicator"
+	corev1 "k8s.io/api/core/v1"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*This file contains internal variables and helper functions for the ACTION aSharing in charge of displaying
+and editing the policy the home cluster uses to share its resources with the peers.*/
+
+const (
+	//aSharing is the tag of the ACTION "Shared resources".
+	aSharing = "A_SHARING"
+	//timerSharing is the tag of the Timer that periodically refreshes the effective shared resources.
+	timerSharing = "T_SHARING"
+	//sharingRefreshInterval is the interval between two consecutive refreshes of the effective shared resources.
+	sharingRefreshInterval = time.Second * 30
+	//sharingRefreshJitter is the maximum random delay added to sharingRefreshInterval, so that the refreshes
+	//of multiple Agents do not hit the API server at the same time.
+	sharingRefreshJitter = time.Second * 5
+)
+
+// set of frequently used tags for menu entries regarding the sharing policy
+const (
+	tagSharingEnabled    = "sharingEnabled"
+	tagSharingPercentage = "sharingPercentage"
+	tagSharingCpu        = "sharingCpu"
+	tagSharingMemory     = "sharingMemory"
+	tagSharingPods       = "sharingPods"
+	tagSharedResources   = "sharedResources"
+)
+
+// set of frequently used title strings for menu entries regarding the sharing policy
+const (
+	titleSharing           = "Shared resources"
+	titleSharingEnabled    = "Share resources with peers"
+	titleSharingPercentage = "Set sharing percentage"
+	titleSharingCpu        = "Set shared CPU"
+	titleSharingMemory     = "Set shared RAM"
+	titleSharingPods       = "Set shared pods"
+)
+
+//sharedResourcesCache stores the last effective SharedResources retrieved from the cluster, so that
+//the peers list can display them without further requests to the API server.
+var sharedResourcesCache = struct {
+	resources *client.SharedResources
+	policy    *client.SharingPolicy
+	sync.RWMutex
+}{}
+
+//startActionSharing is the wrapper function to register the ACTION "Shared resources".
+func startActionSharing(i *app.Indicator) {
+	a := i.AddAction(titleSharing, aSharing, nil)
+	//effective values currently offered to each peer
+	status := a.UseListChild("", tagSharedResources)
+	status.SetIsEnabled(false)
+	a.AddOption(titleSharingEnabled, tagSharingEnabled, "", true, func(args ...interface{}) {
+		sharingHelperToggle(args[0].(*app.Indicator))
+	}, i)
+	a.AddOption(titleSharingPercentage, tagSharingPercentage, "", false, func(args ...interface{}) {
+		sharingHelperSetPercentage(args[0].(*app.Indicator))
+	}, i)
+	a.AddOption(titleSharingCpu, tagSharingCpu, "", false, func(args ...interface{}) {
+		sharingHelperSetAmount(args[0].(*app.Indicator), corev1.ResourceCPU)
+	}, i)
+	a.AddOption(titleSharingMemory, tagSharingMemory, "", false, func(args ...interface{}) {
+		sharingHelperSetAmount(args[0].(*app.Indicator), corev1.ResourceMemory)
+	}, i)
+	a.AddOption(titleSharingPods, tagSharingPods, "", false, func(args ...interface{}) {
+		sharingHelperSetAmount(args[0].(*app.Indicator), corev1.ResourcePods)
+	}, i)
+	refreshSharing(i)
+	_, _ = i.Scheduler().Add(timerSharing, app.TimerOptions{
+		Interval: sharingRefreshInterval,
+		Jitter:   sharingRefreshJitter,
+		//the refresh updates the resources displayed for each peer
+		EventKey: client.PeersListEventKey,
+	}, func(args ...interface{}) {
+		ind := args[0].(*app.Indicator)
+		if ind.Status().Running() == app.StatRunOn {
+			refreshSharing(ind)
+		}
+	}, i)
+}
+
+//refreshSharing retrieves the current sharing policy and the effective shared resources, updating
+//the content of the ACTION aSharing and of the peers list.
+func refreshSharing(i *app.Indicator) {
+	a, present := i.Action(aSharing)
+	if !present {
+		return
+	}
+	ctrl := i.AgentCtrl()
+	var policy *client.SharingPolicy
+	var resources *client.SharedResources
+	var err error
+	if ctrl.Connected() {
+		if policy, err = ctrl.SharingPolicy(); err == nil {
+			resources, err = ctrl.SharedResources()
+		}
+	}
+	sharedResourcesCache.Lock()
+	sharedResourcesCache.policy = policy
+	sharedResourcesCache.resources = resources
+	sharedResourcesCache.Unlock()
+	//refresh ACTION content
+	available := policy != nil && err == nil
+	for _, tag := range []string{tagSharingEnabled, tagSharingPercentage, tagSharingCpu, tagSharingMemory,
+		tagSharingPods} {
+		if opt, ok := a.Option(tag); ok {
+			opt.SetIsEnabled(available)
+		}
+	}
+	if opt, ok := a.Option(tagSharingEnabled); ok && policy != nil {
+		opt.SetIsChecked(policy.Enabled)
+	}
+	if status, ok := a.ListChild(tagSharedResources); ok {
+		status.SetTitle(describeSharedResources(policy, resources))
+	}
+	//refresh the resources offered in the active incoming peerings
+	if quickNode, ok := i.Quick(qPeers); ok {
+		for _, peerNode := range quickNode.ListChildren() {
+			if incomingEntry, ok := peerNode.ListChild(tagPeeringIncoming); ok {
+				refreshInResources(incomingEntry)
+			}
+		}
+	}
+}
+
+//describeSharedResources returns the formatted description of the resources offered to each peer.
+func describeSharedResources(policy *client.SharingPolicy, resources *client.SharedResources) string {
+	content := strings.Builder{}
+	if policy == nil || resources == nil {
+		content.WriteString(peerDataIndentation + "Sharing policy " + labelResourceQuotaUnavailable)
+		return content.String()
+	}
+	if !policy.Enabled {
+		content.WriteString(peerDataIndentation + "Resource sharing is disabled")
+		return content.String()
+	}
+	content.WriteString(fmt.Sprintf("%sOffering %d%% of free resources to each peer\n", peerDataIndentation,
+		policy.Percentage))
+	content.WriteString(peerDataIndentation + "CPU: " + resources.Cpu.String() + "\n")
+	content.WriteString(peerDataIndentation + "RAM: " + resources.Memory.String() + "\n")
+	content.WriteString(peerDataIndentation + "Pods: " + resources.Pods.String())
+	return content.String()
+}
+
+//describeInResources returns the formatted content of an incoming peering status,
+//describing the amount of resources offered to the peer.
+func describeInResources() string {
+	sharedResourcesCache.RLock()
+	defer sharedResourcesCache.RUnlock()
+	policy, resources := sharedResourcesCache.policy, sharedResourcesCache.resources
+	content := strings.Builder{}
+	content.WriteString(peerDataIndentation + "CPU: ")
+	if resources != nil && policy != nil && policy.Enabled {
+		content.WriteString(resources.Cpu.String())
+		content.WriteString("\n" + peerDataIndentation + "RAM: " + resources.Memory.String())
+		content.WriteString("\n" + peerDataIndentation + "Pods: " + resources.Pods.String())
+	} else {
+		content.WriteString(labelResourceQuotaUnavailable)
+	}
+	return content.String()
+}
+
+//refreshInResources updates the description of the resources offered in an incoming peering.
+func refreshInResources(incomingEntry *app.MenuNode) {
+	statusNode, present := incomingEntry.ListChild(tagStatus)
+	if !present {
+		return
+	}
+	if incomingEntry.IsChecked() {
+		statusNode.SetTitle(describeInResources())
+		statusNode.SetIsVisible(true)
+	} else {
+		statusNode.SetIsVisible(false)
+		statusNode.SetTitle("")
+	}
+}
+
+//The following functions are the callbacks associated to the entries of the tray menu "Shared resources" sub-section.
+//They do not refresh the menu, which is updated by the ClusterConfig event (see listenClusterConfig) in order with
+//the events of the peers.
+
+//sharingHelperToggle enables or disables the sharing of the home cluster resources.
+func sharingHelperToggle(i *app.Indicator) {
+	ctrl := i.AgentCtrl()
+	if !ctrl.Connected() {
+		return
+	}
+	policy, err := ctrl.SharingPolicy()
+	if err == nil {
+		policy.Enabled = !policy.Enabled
+		err = ctrl.SetSharingPolicy(policy)
+	}
+	if err != nil {
+		i.ShowWarning("LIQO AGENT", "Liqo Agent could not change the sharing policy:\n"+err.Error())
+	}
+}
+
+//sharingHelperSetPercentage asks the user a new percentage of the home cluster resources to be shared.
+func sharingHelperSetPercentage(i *app.Indicator) {
+	ctrl := i.AgentCtrl()
+	if !ctrl.Connected() || app.GetGuiProvider().Mocked() {
+		return
+	}
+	policy, err := ctrl.SharingPolicy()
+	if err != nil {
+		i.ShowWarning("LIQO AGENT", "Liqo Agent could not retrieve the sharing policy:\n"+err.Error())
+		return
+	}
+	input, ok := app.GetGuiProvider().Prompt("SHARED RESOURCES", "Insert the percentage (0-100) of free resources\n"+
+		"offered to each peer", fmt.Sprint(policy.Percentage))
+	if !ok {
+		return
+	}
+	if policy.Percentage, err = client.ParseSharingPercentage(input); err == nil {
+		err = ctrl.SetSharingPolicy(policy)
+	}
+	if err != nil {
+		i.ShowWarning("LIQO AGENT", "Invalid sharing percentage:\n"+err.Error())
+	}
+}
+
+//sharingHelperSetAmount asks the user the amount of a resource to be offered to each peer, then
+//sets the sharing percentage accordingly.
+func sharingHelperSetAmount(i *app.Indicator, name corev1.ResourceName) {
+	ctrl := i.AgentCtrl()
+	if !ctrl.Connected() || app.GetGuiProvider().Mocked() {
+		return
+	}
+	policy, err := ctrl.SharingPolicy()
+	if err != nil {
+		i.ShowWarning("LIQO AGENT", "Liqo Agent could not retrieve the sharing policy:\n"+err.Error())
+		return
+	}
+	message := fmt.Sprintf("Insert the amount of %s offered to each peer\n"+
+		"(e.g. 500m or 2 for CPU, 1Gi for RAM).\n"+
+		"The sharing percentage of the other resources changes accordingly.", name)
+	input, ok := app.GetGuiProvider().Prompt("SHARED RESOURCES", message, "")
+	if !ok {
+		return
+	}
+	if policy.Percentage, err = ctrl.SharingPercentageForAmount(name, input); err == nil {
+		err = ctrl.SetSharingPolicy(policy)
+	}
+	if err != nil {
+		i.ShowWarning("LIQO AGENT", "Invalid amount:\n"+err.Error())
+	}
+}
@@ -12,13 +12,52 @@ import (
 
 func listenAddedOrUpdatedPeer(data client.NotifyDataGeneric, _ ...interface{}) {
 	i := app.GetIndicator()
-	status := i.Status()
 	fcData, ok := data.(*client.NotifyDataForeignCluster)
 	if !ok {
 		panic("wrong NotifyData type for an event Listener")
 	}
-	//1- store information on Indicator Status
-	peer := status.AddOrUpdatePeer(fcData)
+	peer, wasOutConnected, wasInConnected := applyPeer(i, fcData)
+	//notify selected events
+	if !wasOutConnected && peer.OutPeeringConnected {
+		i.NotifyPeering(app.PeeringOutgoing, app.NotifyEventPeeringOn, peer)
+	} else if wasOutConnected && !peer.OutPeeringConnected {
+		i.NotifyPeering(app.PeeringOutgoing, app.NotifyEventPeeringOff, peer)
+	}
+	if !wasInConnected && peer.InPeeringConnected {
+		i.NotifyPeering(app.PeeringIncoming, app.NotifyEventPeeringOn, peer)
+	} else if wasInConnected && !peer.InPeeringConnected {
+		i.NotifyPeering(app.PeeringIncoming, app.NotifyEventPeeringOff, peer)
+	}
+}
+
+func listenDeletedPeer(data client.NotifyDataGeneric, _ ...interface{}) {
+	i := app.GetIndicator()
+	fcData, ok := data.(*client.NotifyDataForeignCluster)
+	if !ok {
+		panic("wrong NotifyData type for an event Listener")
+	}
+	peer := removePeer(i, fcData.ClusterID)
+	//notify the end of the peerings of the removed peer
+	if peer.OutPeeringConnected {
+		i.NotifyPeering(app.PeeringOutgoing, app.NotifyEventPeeringOff, peer)
+	}
+	if peer.InPeeringConnected {
+		i.NotifyPeering(app.PeeringIncoming, app.NotifyEventPeeringOff, peer)
+	}
+}
+
+//applyPeer stores the information on a new or updated peer in the Indicator Status and in the peers list.
+//It returns the peer, together with the state of its peerings before the update.
+func applyPeer(i *app.Indicator, fcData *client.NotifyDataForeignCluster) (peer *app.PeerInfo, wasOutConnected bool,
+	wasInConnected bool) {
+	status := i.Status()
+	//1- store information on Indicator Status, keeping track of the previous state of the peerings
+	if old, known := status.Peer(fcData.ClusterID); known {
+		old.RLock()
+		wasOutConnected, wasInConnected = old.OutPeeringConnected, old.InPeeringConnected
+		old.RUnlock()
+	}
+	peer = status.AddOrUpdatePeer(fcData)
 	peer.RLock()
 	defer peer.RUnlock()
 	//update content of the Status MenuNode in the tray menu
@@ -39,38 +78,13 @@ func listenAddedOrUpdatedPeer(data client.NotifyDataGeneric, _ ...interface{}) {
 	go refreshPeeringInfo(peerNode, peer, fcData, wg)
 	wg.Wait()
 	refreshPeerCount(quickNode)
-
-	//3- notify selected events
-	if !present {
-		if peer.OutPeeringConnected {
-			i.NotifyPeering(app.PeeringOutgoing, app.NotifyEventPeeringOn, peer)
-		}
-		if peer.InPeeringConnected {
-			i.NotifyPeering(app.PeeringIncoming, app.NotifyEventPeeringOn, peer)
-		}
-	} else {
-		if !fcData.OutPeering.Connected && peer.OutPeeringConnected {
-			i.NotifyPeering(app.PeeringOutgoing, app.NotifyEventPeeringOn, peer)
-		} else if fcData.OutPeering.Connected && !peer.OutPeeringConnected {
-			i.NotifyPeering(app.PeeringOutgoing, app.NotifyEventPeeringOff, peer)
-		}
-		if !fcData.InPeering.Connected && peer.InPeeringConnected {
-			i.NotifyPeering(app.PeeringIncoming, app.NotifyEventPeeringOn, peer)
-		} else if fcData.InPeering.Connected && !peer.InPeeringConnected {
-			i.NotifyPeering(app.PeeringIncoming, app.NotifyEventPeeringOff, peer)
-		}
-	}
+	return
 }
 
-func listenDeletedPeer(data client.NotifyDataGeneric, _ ...interface{}) {
-	i := app.GetIndicator()
-	status := i.Status()
-	fcData, ok := data.(*client.NotifyDataForeignCluster)
-	if !ok {
-		panic("wrong NotifyData type for an event Listener")
-	}
+//removePeer removes a peer from the Indicator Status and from the peers list, returning its last information.
+func removePeer(i *app.Indicator, clusterID string) *app.PeerInfo {
 	//1- update peer data
-	peer := status.RemovePeer(fcData)
+	peer := i.Status().RemovePeer(&client.NotifyDataForeignCluster{ClusterID: clusterID})
 	peer.RLock()
 	defer peer.RUnlock()
 	//update content of the Status MenuNode in the tray menu
@@ -79,7 +93,7 @@ func listenDeletedPeer(data client.NotifyDataGeneric, _ ...interface{}) {
 	//2- update information on tray menu
 	quickNode, present := i.Quick(qPeers)
 	if !present {
-		return
+		return peer
 	}
 	_, present = quickNode.ListChild(peer.ClusterID)
 	if present {
@@ -87,28 +101,241 @@ func listenDeletedPeer(data client.NotifyDataGeneric, _ ...interface{}) {
 		quickNode.FreeListChild(peer.ClusterID)
 	}
 	refreshPeerCount(quickNode)
+	return peer
+}
 
-	//3- notify selected events
-	if !fcData.OutPeering.Connected && peer.OutPeeringConnected {
-		i.NotifyPeering(app.PeeringOutgoing, app.NotifyEventPeeringOn, peer)
-	} else if fcData.OutPeering.Connected && !peer.OutPeeringConnected {
-		i.NotifyPeering(app.PeeringOutgoing, app.NotifyEventPeeringOff, peer)
+//resyncPeers rebuilds the information on peers and peerings from the ForeignCluster cache, since the events
+//received while the Agent was OFF have been discarded. It returns a summary of the changes.
+func resyncPeers(i *app.Indicator) app.ResyncSummary {
+	summary := app.ResyncSummary{}
+	current := make(map[string]bool)
+	for _, fcData := range i.AgentCtrl().ListPeers() {
+		current[fcData.ClusterID] = true
+		if _, known := i.Status().Peer(fcData.ClusterID); !known {
+			summary.PeersAdded++
+		}
+		peer, wasOutConnected, wasInConnected := applyPeer(i, fcData)
+		if wasOutConnected != peer.OutPeeringConnected {
+			summary.AddPeering(app.PeeringOutgoing, peeringEvent(peer.OutPeeringConnected))
+		}
+		if wasInConnected != peer.InPeeringConnected {
+			summary.AddPeering(app.PeeringIncoming, peeringEvent(peer.InPeeringConnected))
+		}
 	}
-	if !fcData.InPeering.Connected && peer.InPeeringConnected {
-		i.NotifyPeering(app.PeeringIncoming, app.NotifyEventPeeringOn, peer)
-	} else if fcData.InPeering.Connected && !peer.InPeeringConnected {
-		i.NotifyPeering(app.PeeringIncoming, app.NotifyEventPeeringOff, peer)
+	for _, clusterID := range i.Status().PeerIDs() {
+		if current[clusterID] {
+			continue
+		}
+		peer := removePeer(i, clusterID)
+		summary.PeersRemoved++
+		if peer.OutPeeringConnected {
+			summary.AddPeering(app.PeeringOutgoing, app.NotifyEventPeeringOff)
+		}
+		if peer.InPeeringConnected {
+			summary.AddPeering(app.PeeringIncoming, app.NotifyEventPeeringOff)
+		}
+	}
+	return summary
+}
+
+/*resyncMenus rebuilds the menu sections filled by the listeners (configuration of the home cluster, virtual nodes,
+offloaded pods, namespaces, incoming workloads, applications and port-forwards) from the caches of the
+AgentController, since the events received while the Agent was OFF have been discarded.
+
+The peers list must be already synchronized (see resyncPeers).*/
+func resyncMenus(i *app.Indicator) {
+	ctrl := i.AgentCtrl()
+	applyClusterConfig(i, ctrl.ClusterConfigData())
+	namespaces := ctrl.ListNamespaces()
+	resetVirtualNodes(ctrl.ListVirtualNodes())
+	resetOffloadedPods(ctrl.ListOffloadedPods())
+	resetIncomingWorkloads(namespaces, ctrl.ListIncomingPods())
+	for _, clusterID := range i.Status().PeerIDs() {
+		refreshPeerOffloadedPods(i, clusterID)
+		refreshPeerIncomingWorkloads(i, clusterID)
 	}
+	resyncNamespaces(i, namespaces)
+	resyncApplications(i, ctrl.ListAppPods())
+	resyncPortForwards(i, ctrl.PortForwards())
+}
 
+//peeringEvent returns the NotifyPeeringEvent leading to a peering in the 'connected' state.
+func peeringEvent(connected bool) app.NotifyPeeringEvent {
+	if connected {
+		return app.NotifyEventPeeringOn
+	}
+	return app.NotifyEventPeeringOff
 }
 
-func listenClusterName(data client.NotifyDataGeneric, _ ...interface{}) {
-	clusterName, ok := data.(string)
+//******* VIRTUAL NODES *******
+
+func listenAddedOrUpdatedVirtualNode(data client.NotifyDataGeneric, _ ...interface{}) {
+	i := app.GetIndicator()
+	nodeData, ok := data.(*client.NotifyDataVirtualNode)
 	if !ok {
 		panic("wrong NotifyData type for an event Listener")
 	}
+	//1- store information on the virtual node
+	old, present := storeVirtualNode(nodeData)
+	//2- update information on tray menu
+	refreshPeerVirtualNode(i, nodeData.ClusterID)
+	//3- notify readiness changes
+	if present && old.Ready != nodeData.Ready {
+		i.NotifyVirtualNode(nodeData.Name, nodeData.Ready, peerFromClusterID(i, nodeData.ClusterID))
+	}
+}
+
+func listenDeletedVirtualNode(data client.NotifyDataGeneric, _ ...interface{}) {
 	i := app.GetIndicator()
+	nodeData, ok := data.(*client.NotifyDataVirtualNode)
+	if !ok {
+		panic("wrong NotifyData type for an event Listener")
+	}
+	removeVirtualNode(nodeData)
+	refreshPeerVirtualNode(i, nodeData.ClusterID)
+}
+
+//******* OFFLOADED PODS *******
+
+func listenAddedOrUpdatedOffloadedPod(data client.NotifyDataGeneric, _ ...interface{}) {
+	podData, ok := data.(*client.NotifyDataOffloadedPod)
+	if !ok {
+		panic("wrong NotifyData type for an event Listener")
+	}
+	storeOffloadedPod(podData)
+	refreshPeerOffloadedPods(app.GetIndicator(), podData.ClusterID)
+}
+
+func listenDeletedOffloadedPod(data client.NotifyDataGeneric, _ ...interface{}) {
+	podData, ok := data.(*client.NotifyDataOffloadedPod)
+	if !ok {
+		panic("wrong NotifyData type for an event Listener")
+	}
+	if clusterID, present := removeOffloadedPod(podData); present {
+		refreshPeerOffloadedPods(app.GetIndicator(), clusterID)
+	}
+}
+
+//******* NAMESPACES AND INCOMING WORKLOADS *******
+
+func listenAddedOrUpdatedNamespace(data client.NotifyDataGeneric, _ ...interface{}) {
+	nsData, ok := data.(*client.NotifyDataNamespace)
+	if !ok {
+		panic("wrong NotifyData type for an event Listener")
+	}
+	i := app.GetIndicator()
+	if nsData.IncomingClusterID != "" {
+		storeIncomingNamespace(nsData, true)
+		refreshPeerIncomingWorkloads(i, nsData.IncomingClusterID)
+	} else {
+		refreshNamespace(i, nsData)
+	}
+}
+
+func listenDeletedNamespace(data client.NotifyDataGeneric, _ ...interface{}) {
+	nsData, ok := data.(*client.NotifyDataNamespace)
+	if !ok {
+		panic("wrong NotifyData type for an event Listener")
+	}
+	i := app.GetIndicator()
+	if nsData.IncomingClusterID != "" {
+		storeIncomingNamespace(nsData, false)
+		refreshPeerIncomingWorkloads(i, nsData.IncomingClusterID)
+	} else {
+		removeNamespace(i, nsData)
+	}
+}
+
+func listenAddedOrUpdatedIncomingPod(data client.NotifyDataGeneric, _ ...interface{}) {
+	podData, ok := data.(*client.NotifyDataIncomingPod)
+	if !ok {
+		panic("wrong NotifyData type for an event Listener")
+	}
+	storeIncomingPod(podData, true)
+	refreshPeerIncomingWorkloads(app.GetIndicator(), podData.ClusterID)
+}
+
+func listenDeletedIncomingPod(data client.NotifyDataGeneric, _ ...interface{}) {
+	podData, ok := data.(*client.NotifyDataIncomingPod)
+	if !ok {
+		panic("wrong NotifyData type for an event Listener")
+	}
+	storeIncomingPod(podData, false)
+	refreshPeerIncomingWorkloads(app.GetIndicator(), podData.ClusterID)
+}
+
+//******* APPLICATIONS *******
+
+func listenAddedOrUpdatedAppPod(data client.NotifyDataGeneric, _ ...interface{}) {
+	podData, ok := data.(*client.NotifyDataAppPod)
+	if !ok {
+		panic("wrong NotifyData type for an event Listener")
+	}
+	i := app.GetIndicator()
+	started, failed := storeAppPod(podData, true)
+	refreshApplication(i, podData.Namespace, podData.App)
+	notifyApplication(i, podData, started, failed)
+}
+
+func listenDeletedAppPod(data client.NotifyDataGeneric, _ ...interface{}) {
+	podData, ok := data.(*client.NotifyDataAppPod)
+	if !ok {
+		panic("wrong NotifyData type for an event Listener")
+	}
+	storeAppPod(podData, false)
+	refreshApplication(app.GetIndicator(), podData.Namespace, podData.App)
+}
+
+//******* PORT-FORWARDS *******
+
+func listenPortForward(data client.NotifyDataGeneric, _ ...interface{}) {
+	fwData, ok := data.(*client.NotifyDataPortForward)
+	if !ok {
+		panic("wrong NotifyData type for an event Listener")
+	}
+	refreshPortForward(app.GetIndicator(), fwData)
+}
+
+//******* LIQODASH *******
+
+func listenDashboard(data client.NotifyDataGeneric, _ ...interface{}) {
+	dashData, ok := data.(*client.NotifyDataDashboard)
+	if !ok {
+		panic("wrong NotifyData type for an event Listener")
+	}
+	if dashQuick, present := app.GetIndicator().Quick(qDash); present {
+		dashQuick.SetIsEnabled(dashData.Ready)
+	}
+}
+
+//peerFromClusterID returns the PeerInfo of the peer with the given ClusterID, or nil if it is not known.
+func peerFromClusterID(i *app.Indicator, clusterID string) *app.PeerInfo {
+	if peer, present := i.Status().Peer(clusterID); present {
+		return peer
+	}
+	return nil
+}
+
+//listenClusterConfig is the callback for the ChanClusterConfig NotifyChannel. It refreshes all the menu
+//sections depending on the configuration of the home cluster.
+func listenClusterConfig(data client.NotifyDataGeneric, _ ...interface{}) {
+	confData, ok := data.(*client.NotifyDataClusterConfig)
+	if !ok {
+		panic("wrong NotifyData type for an event Listener")
+	}
+	applyClusterConfig(app.GetIndicator(), confData)
+}
+
+//applyClusterConfig refreshes all the menu sections depending on the configuration of the home cluster.
+func applyClusterConfig(i *app.Indicator, confData *client.NotifyDataClusterConfig) {
 	status := i.Status()
-	status.SetClusterName(clusterName)
+	status.SetClusterName(confData.ClusterName)
 	i.RefreshStatus()
+	refreshSharing(i)
+	if confData.Deleted {
+		//the discovery settings are no more available
+		refreshDiscovery(i, nil)
+	} else {
+		refreshDiscovery(i, &confData.Discovery)
+	}
 }
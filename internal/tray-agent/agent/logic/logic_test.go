@@ -10,13 +10,7 @@ import (
 
 //test the routines OnReady that is called in the app-indicator/Run() loop and manages the Liqo Agent logic.
 func TestOnReady(t *testing.T) {
-	app.UseMockedGuiProvider()
-	client.UseMockedAgentController()
-	app.DestroyMockedIndicator()
-	client.DestroyMockedAgentController()
-	app.DestroyStatus()
-	OnReady()
-	i := app.GetIndicator()
+	i := test.StartHarness(t, OnReady).Indicator
 	//test startup Icon
 	startIcon := i.Icon()
 	assert.Equal(t, app.IconLiqoMain, startIcon, "startup Indicator icon is not IconLiqoMain")
@@ -46,66 +40,26 @@ func TestOnReady(t *testing.T) {
 	assert.True(t, exist, "Listener for NotifyChanType ChanPeerDeleted not registered")
 }
 
-func TestPeersListeners(t *testing.T) {
-	app.UseMockedGuiProvider()
-	client.UseMockedAgentController()
-	app.DestroyMockedIndicator()
-	client.DestroyMockedAgentController()
-	app.DestroyStatus()
-	eventTester := app.GetGuiProvider().NewEventTester()
-	eventTester.Test()
-	OnReady()
-	i := app.GetIndicator()
-	//test peers list when no ForeignCluster is present
-	quickNode, present := i.Quick(qPeers)
-	if !present {
-		t.Fatal("Show Peers QUICK not registered")
-	}
-	assert.Equal(t, 0, quickNode.ListChildrenLen(), "peers list is not empty when 0 ForeignCluster(s) exist [init phase]")
-	assert.False(t, quickNode.IsEnabled(), "peers menu entry should be disabled when 0 ForeignCluster(s) exist [init phase]")
-
-	//test addition of ForeignClusters
-	clusterID1 := "cl1"
-	clusterName1 := "test1"
-	clusterName2 := "test2"
-	fc1 := test.CreateForeignCluster(clusterID1, clusterName1)
-	fcCtrl := i.AgentCtrl().Controller(client.CRForeignCluster)
-	eventTester.Add(1)
-	err := fcCtrl.Store.Add(fc1)
-	eventTester.Wait()
-	assert.NoError(t, err, "ForeignCluster addition failed")
-
-	assert.True(t, quickNode.IsEnabled(), "peers menu entry should be enabled when 1+ ForeignCluster(s) exist [init phase]")
-	assert.Equal(t, 1, quickNode.ListChildrenLen(), "peers list is empty when 1+ ForeignCluster(s) exist")
-
-	//check if inserted element is present
-	var fc1Node *app.MenuNode
-	fc1Node, present = quickNode.ListChild(clusterID1)
-	assert.Truef(t, present, "LIST MenuNode for ForeignCluster %v not present", clusterID1)
-	assert.Equal(t, clusterName1, fc1Node.Title(), "peers menu entry displays wrong content")
-	fc2 := fc1.DeepCopy()
-	fc2.Spec.ClusterIdentity.ClusterName = clusterName2
-	eventTester.Add(1)
-	err = fcCtrl.Store.Update(fc2)
-	eventTester.Wait()
-	//time.Sleep(time.Second * 3)
-	assert.NoError(t, err, "ForeignCluster update failed")
-	//peerUpdateChan <- clusterID1
-
-	//check update of the text content
-	fc1Node, present = quickNode.ListChild(clusterID1)
-	assert.Truef(t, present, "LIST MenuNode for ForeignCluster %v not present", clusterID1)
-	assert.Equal(t, clusterName2, fc1Node.Title(), "peers menu entry displays wrong content after update")
-
-	//delete element
-	eventTester.Add(1)
-	err = fcCtrl.Store.Delete(fc2)
-	eventTester.Wait()
-	//time.Sleep(time.Second * 3)
-	assert.NoError(t, err, "ForeignCluster deletion failed")
-
-	//check peers list back at initial condition
-	endCount := quickNode.ListChildrenLen()
-	assert.Equal(t, 0, endCount, "peers list is not empty when 0 ForeignCluster(s) exist [init phase]")
-	assert.False(t, quickNode.IsEnabled(), "peers menu entry should be disabled when 0 ForeignCluster(s) exist")
+//test that the package caches do not keep the data of a previous menu.
+func TestResetCaches(t *testing.T) {
+	storeVirtualNode(&client.NotifyDataVirtualNode{ClusterID: "cl1"})
+	storeOffloadedPod(&client.NotifyDataOffloadedPod{ClusterID: "cl1", Namespace: "apps", Name: "web-1"})
+	storeIncomingNamespace(&client.NotifyDataNamespace{Name: "apps-cl2", IncomingClusterID: "cl2"}, true)
+	storeAppPod(&client.NotifyDataAppPod{App: "web", Namespace: "apps", Name: "web-1"}, true)
+	appCatalog.Lock()
+	appCatalog.templates["appCatalog/apps/web"] = &client.AppTemplate{Name: "web"}
+	appCatalog.Unlock()
+	sharedResourcesCache.Lock()
+	sharedResourcesCache.resources = &client.SharedResources{}
+	sharedResourcesCache.policy = &client.SharingPolicy{Enabled: true}
+	sharedResourcesCache.Unlock()
+	resetCaches()
+	assert.Empty(t, virtualNodeCache.nodes, "virtual nodes not reset")
+	assert.Empty(t, offloadedPodCache.pods, "offloaded pods not reset")
+	assert.Empty(t, incomingWorkloadCache.namespaces, "incoming namespaces not reset")
+	assert.Empty(t, incomingWorkloadCache.pods, "incoming pods not reset")
+	assert.Empty(t, appCache.apps, "applications not reset")
+	assert.Empty(t, appCatalog.templates, "application catalog not reset")
+	assert.Nil(t, sharedResourcesCache.resources, "shared resources not reset")
+	assert.Nil(t, sharedResourcesCache.policy, "sharing policy not reset")
 }
@@ -1,6 +1,7 @@
 package logic
 
 import (
+	"context"
 	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
 	app "github.com/liqotech/liqo-agent/internal/tray-agent/app-indicator"
 	"github.com/skratchdot/open-golang/open"
@@ -8,33 +9,69 @@ import (
 
 //OnReady is the routine orchestrating Liqo Agent execution.
 func OnReady() {
+	resetCaches()
 	// Indicator configuration
 	i := app.GetIndicator()
 	i.RefreshStatus()
 	startListenerClusterConfig(i)
 	startListenerPeersList(i)
+	startListenerVirtualNodes(i)
+	startListenerOffloadedPods(i)
+	startListenerIncomingWorkloads(i)
+	startListenerApplications(i)
+	startListenerPortForwards(i)
+	startListenerDashboard(i)
 	startQuickOnOff(i)
 	startQuickChangeMode(i)
 	startQuickDashboard(i)
 	startQuickShowPeers(i)
+	startActionSharing(i)
+	startActionDiscovery(i)
+	startActionNamespaces(i)
+	startActionApplications(i)
+	startActionPortForward(i)
 	i.AddSeparator()
 	startQuickSetNotifications(i)
 	startQuickLiqoWebsite(i)
 	startQuickQuit(i)
-	//try to start Liqo and main ACTION
-	quickTurnOnOff(i)
+	//try to start Liqo and main ACTION. There are no changes to notify, since the Agent has never been ON.
+	i.RunOnPeersList(func() {
+		turnOn(i, false)
+	})
+}
+
+//resetCaches empties all the package caches storing the data displayed by the menu, so that a new menu is not
+//filled with the data of a previous one. Every new cache must be reset here.
+func resetCaches() {
+	resetVirtualNodes(nil)
+	resetOffloadedPods(nil)
+	resetIncomingWorkloads(nil, nil)
+	appCache.Lock()
+	appCache.apps = make(map[string]*appState)
+	appCache.Unlock()
+	appCatalog.Lock()
+	appCatalog.templates = make(map[string]*client.AppTemplate)
+	appCatalog.Unlock()
+	sharedResourcesCache.Lock()
+	sharedResourcesCache.resources = nil
+	sharedResourcesCache.policy = nil
+	sharedResourcesCache.Unlock()
 }
 
 //OnExit is the routine containing clean-up operations to be performed at Liqo Agent exit.
 func OnExit() {
-	app.GetIndicator().Disconnect()
+	i := app.GetIndicator()
+	i.AgentCtrl().StopPortForwards()
+	i.Disconnect()
 }
 
 //startQuickOnOff is the wrapper function to register the QUICK "START/STOP LIQO".
 func startQuickOnOff(i *app.Indicator) {
-	i.AddQuick("", qOnOff, func(args ...interface{}) {
+	q := i.AddQuick("", qOnOff, func(args ...interface{}) {
 		quickTurnOnOff(args[0].(*app.Indicator))
 	}, i)
+	//turning the Agent ON or OFF resynchronizes the whole peers list
+	q.SetEventKey(client.PeersListEventKey)
 	//the Quick MenuNode title is refreshed
 	updateQuickTurnOnOff(i)
 }
@@ -72,9 +109,11 @@ func startQuickSetNotifications(i *app.Indicator) {
 
 //startQuickQuit is the wrapper function to register QUICK "QUIT".
 func startQuickQuit(i *app.Indicator) {
-	i.AddQuick("Quit", qQuit, func(args ...interface{}) {
+	q := i.AddQuick("Quit", qQuit, nil)
+	//the callback shuts down the Indicator, so it provides its own context not to wait for itself
+	q.ConnectContext(false, func(ctx context.Context, args ...interface{}) {
 		i := args[0].(*app.Indicator)
-		i.Quit()
+		i.QuitContext(ctx)
 	}, i)
 }
 
@@ -94,7 +133,44 @@ func startListenerPeersList(i *app.Indicator) {
 	i.Listen(client.ChanPeerDeleted, listenDeletedPeer)
 }
 
+//startListenerVirtualNodes is a wrapper that starts the listeners regarding the virtual nodes of the outgoing peerings.
+func startListenerVirtualNodes(i *app.Indicator) {
+	i.Listen(client.ChanVirtualNodeAddedOrUpdated, listenAddedOrUpdatedVirtualNode)
+	i.Listen(client.ChanVirtualNodeDeleted, listenDeletedVirtualNode)
+}
+
+//startListenerOffloadedPods is a wrapper that starts the listeners regarding the pods offloaded to the peers.
+func startListenerOffloadedPods(i *app.Indicator) {
+	i.Listen(client.ChanOffloadedPodAddedOrUpdated, listenAddedOrUpdatedOffloadedPod)
+	i.Listen(client.ChanOffloadedPodDeleted, listenDeletedOffloadedPod)
+}
+
+//startListenerIncomingWorkloads is a wrapper that starts the listeners regarding the namespaces of the home cluster
+//and the workloads offloaded to the home cluster by the peers.
+func startListenerIncomingWorkloads(i *app.Indicator) {
+	i.Listen(client.ChanNamespaceAddedOrUpdated, listenAddedOrUpdatedNamespace)
+	i.Listen(client.ChanNamespaceDeleted, listenDeletedNamespace)
+	i.Listen(client.ChanIncomingPodAddedOrUpdated, listenAddedOrUpdatedIncomingPod)
+	i.Listen(client.ChanIncomingPodDeleted, listenDeletedIncomingPod)
+}
+
+//startListenerApplications is a wrapper that starts the listeners regarding the applications launched by the Agent.
+func startListenerApplications(i *app.Indicator) {
+	i.Listen(client.ChanAppPodAddedOrUpdated, listenAddedOrUpdatedAppPod)
+	i.Listen(client.ChanAppPodDeleted, listenDeletedAppPod)
+}
+
+//startListenerPortForwards is a wrapper that starts the listeners regarding the port-forwards started by the Agent.
+func startListenerPortForwards(i *app.Indicator) {
+	i.Listen(client.ChanPortForward, listenPortForward)
+}
+
+//startListenerDashboard is a wrapper that starts the listeners regarding the LiqoDash endpoint.
+func startListenerDashboard(i *app.Indicator) {
+	i.Listen(client.ChanDashboard, listenDashboard)
+}
+
 //startListenerClusterConfig is a wrapper that starts the listeners regarding Liqo configuration data.
 func startListenerClusterConfig(i *app.Indicator) {
-	i.Listen(client.ChanClusterName, listenClusterName)
+	i.Listen(client.ChanClusterConfig, listenClusterConfig)
 }
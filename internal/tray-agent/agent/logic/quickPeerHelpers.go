@@ -90,12 +90,19 @@ Each peer entry has the following structure:
 	3-		OUTGOING PEERING: display information and commands for an outgoing peering towards this peer
 	3.1-	START/STOP peering
 	3.2-	PEERING STATUS: details on the active peering (e.g. consumed resources)
+	3.3-	VIRTUAL NODE: readiness and resources of the virtual node representing the peer
+	3.4-	OFFLOADED PODS: pods running on the peer, grouped by namespace
 	4-		INCOMING PEERING: display information and commands for an incoming peering from this peer
 	4.1-	STOP PEERING
+	4.2-	PEERING STATUS: details on the active peering (e.g. offered resources)
+	4.3-	WORKLOADS: namespaces, pods and resources the peer is using on the home cluster
+	4.4-	EVICT WORKLOADS: emergency command to delete all the pods offloaded by the peer
 */
 func createPeerNode(peerList *app.MenuNode, data *client.NotifyDataForeignCluster, peer *app.PeerInfo) *app.MenuNode {
 	//create the structure for a single peer
 	peerNode := peerList.UseListChild("", data.ClusterID)
+	//the commands of the peer are handled in order with its events
+	peerNode.SetEventKey(client.PeerEventKey(data.ClusterID))
 	//1- STATUS
 	statusNode := peerNode.UseListChild("", tagStatus)
 	statusNode.SetIsEnabled(false)
@@ -114,6 +121,13 @@ func createPeerNode(peerList *app.MenuNode, data *client.NotifyDataForeignCluste
 	outgoingStatus := outgoingNode.UseListChild("", tagStatus)
 	outgoingStatus.SetIsVisible(false)
 	outgoingStatus.SetIsEnabled(false)
+	//3.3- VIRTUAL NODE
+	virtualNodeEntry := outgoingNode.UseListChild("", tagVirtualNode)
+	virtualNodeEntry.SetIsVisible(false)
+	virtualNodeEntry.SetIsEnabled(false)
+	//3.4- OFFLOADED PODS
+	offloadedPodsEntry := outgoingNode.UseListChild("", tagOffloadedPods)
+	offloadedPodsEntry.SetIsVisible(false)
 	//4- INCOMING PEERING
 	incomingNode := peerNode.UseListChild(peerDataIndentation+titlePeeringIncoming, tagPeeringIncoming)
 	//4.1- STOP PEERING
@@ -122,6 +136,18 @@ func createPeerNode(peerList *app.MenuNode, data *client.NotifyDataForeignCluste
 	//the "stop peering" entry is by default disabled since its callback can be executed only in presence
 	//of an active incoming peering
 	incomingCmd.SetIsEnabled(false)
+	//4.2- STATUS
+	incomingStatus := incomingNode.UseListChild("", tagStatus)
+	incomingStatus.SetIsVisible(false)
+	incomingStatus.SetIsEnabled(false)
+	//4.3- WORKLOADS
+	incomingWorkloads := incomingNode.UseListChild("", tagIncomingWorkloads)
+	incomingWorkloads.SetIsVisible(false)
+	incomingWorkloads.SetIsEnabled(false)
+	//4.4- EVICT WORKLOADS
+	incomingEvict := incomingNode.UseListChild(peerDataIndentation+titleIncomingEvict, tagIncomingEvict)
+	incomingEvict.Connect(false, peerHelperEvictWorkloads, peer)
+	incomingEvict.SetIsEnabled(false)
 	return peerNode
 }
 
@@ -228,6 +254,8 @@ func refreshPeeringInfo(peerNode *app.MenuNode, peer *app.PeerInfo, data *client
 				statusNode.SetTitle("")
 			}
 		}
+		refreshVirtualNode(outgoingEntry, data.ClusterID)
+		refreshOffloadedPods(outgoingEntry, data.ClusterID)
 	}
 	//incoming peering
 	incomingEntry, inPresent := peerNode.ListChild(tagPeeringIncoming)
@@ -244,6 +272,9 @@ func refreshPeeringInfo(peerNode *app.MenuNode, peer *app.PeerInfo, data *client
 				cmdNode.SetIsEnabled(false)
 			}
 		}
+		//show resources offered in active peering
+		refreshInResources(incomingEntry)
+		refreshIncomingWorkloads(incomingEntry, data.ClusterID)
 	}
 }
 
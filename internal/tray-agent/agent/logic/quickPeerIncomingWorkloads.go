@@ -0,0 +1,190 @@
+package logic
+
+import (
+	"fmt"
+	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
+	app "github.com/liqotech/liqo-agent/internal/tray-agent/app-indicator"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"strings"
+	"sync"
+)
+
+/*This file contains internal variables and helper functions to display, inside the INCOMING PEERING section
+of each peer, the workloads the foreign cluster is running on the home cluster.*/
+
+// set of frequently used tags for menu entries regarding incoming workloads
+const (
+	tagIncomingWorkloads = "inWorkloads"
+	tagIncomingEvict     = "inEvict"
+)
+
+//titleIncomingEvict is the title of the menu entry to evict all the workloads offloaded by a peer.
+const titleIncomingEvict = "• Evict all workloads"
+
+//incomingWorkloadCache stores the last known data about the namespaces and the pods offloaded to the home
+//cluster, indexed by the ClusterID of the peer that created them.
+var incomingWorkloadCache = struct {
+	namespaces map[string]map[string]bool
+	pods       map[string]map[string]*client.NotifyDataIncomingPod
+	sync.RWMutex
+}{
+	namespaces: make(map[string]map[string]bool),
+	pods:       make(map[string]map[string]*client.NotifyDataIncomingPod),
+}
+
+//incomingWorkloads summarizes the workloads offloaded to the home cluster by a peer.
+type incomingWorkloads struct {
+	namespaces int
+	pods       int
+	cpu        resource.Quantity
+	memory     resource.Quantity
+}
+
+//storeIncomingNamespace saves (add = true) or removes a namespace reflected by a peer.
+func storeIncomingNamespace(data *client.NotifyDataNamespace, add bool) {
+	incomingWorkloadCache.Lock()
+	defer incomingWorkloadCache.Unlock()
+	namespaces, present := incomingWorkloadCache.namespaces[data.IncomingClusterID]
+	if add {
+		if !present {
+			namespaces = make(map[string]bool)
+			incomingWorkloadCache.namespaces[data.IncomingClusterID] = namespaces
+		}
+		namespaces[data.Name] = true
+	} else if present {
+		delete(namespaces, data.Name)
+	}
+}
+
+//storeIncomingPod saves (add = true) or removes a pod offloaded by a peer.
+//Terminated pods are not considered, since they do not consume resources.
+func storeIncomingPod(data *client.NotifyDataIncomingPod, add bool) {
+	incomingWorkloadCache.Lock()
+	defer incomingWorkloadCache.Unlock()
+	key := data.Namespace + "/" + data.Name
+	pods, present := incomingWorkloadCache.pods[data.ClusterID]
+	if add && !data.Terminated {
+		if !present {
+			pods = make(map[string]*client.NotifyDataIncomingPod)
+			incomingWorkloadCache.pods[data.ClusterID] = pods
+		}
+		pods[key] = data
+	} else if present {
+		delete(pods, key)
+	}
+}
+
+//resetIncomingWorkloads replaces the stored data of the namespaces and the pods offloaded by the peers with
+//the provided ones. The namespaces not reflected by a peer and the terminated pods are ignored.
+func resetIncomingWorkloads(namespaces []*client.NotifyDataNamespace, pods []*client.NotifyDataIncomingPod) {
+	currentNamespaces := make(map[string]map[string]bool)
+	for _, data := range namespaces {
+		if data.IncomingClusterID == "" {
+			continue
+		}
+		if _, present := currentNamespaces[data.IncomingClusterID]; !present {
+			currentNamespaces[data.IncomingClusterID] = make(map[string]bool)
+		}
+		currentNamespaces[data.IncomingClusterID][data.Name] = true
+	}
+	currentPods := make(map[string]map[string]*client.NotifyDataIncomingPod)
+	for _, data := range pods {
+		if data.Terminated {
+			continue
+		}
+		if _, present := currentPods[data.ClusterID]; !present {
+			currentPods[data.ClusterID] = make(map[string]*client.NotifyDataIncomingPod)
+		}
+		currentPods[data.ClusterID][data.Namespace+"/"+data.Name] = data
+	}
+	incomingWorkloadCache.Lock()
+	incomingWorkloadCache.namespaces = currentNamespaces
+	incomingWorkloadCache.pods = currentPods
+	incomingWorkloadCache.Unlock()
+}
+
+//peerIncomingWorkloads returns the summary of the workloads offloaded to the home cluster by a peer.
+func peerIncomingWorkloads(clusterID string) *incomingWorkloads {
+	incomingWorkloadCache.RLock()
+	defer incomingWorkloadCache.RUnlock()
+	w := &incomingWorkloads{
+		namespaces: len(incomingWorkloadCache.namespaces[clusterID]),
+		cpu:        *resource.NewMilliQuantity(0, resource.DecimalSI),
+		memory:     *resource.NewQuantity(0, resource.BinarySI),
+	}
+	for _, pod := range incomingWorkloadCache.pods[clusterID] {
+		w.pods++
+		w.cpu.Add(pod.Cpu)
+		w.memory.Add(pod.Memory)
+	}
+	return w
+}
+
+//describeIncomingWorkloads returns the formatted description of the workloads offloaded by a peer.
+func describeIncomingWorkloads(w *incomingWorkloads) string {
+	content := strings.Builder{}
+	content.WriteString(fmt.Sprintf("%sNamespaces: %d\n", peerDataIndentation, w.namespaces))
+	content.WriteString(fmt.Sprintf("%sPods: %d\n", peerDataIndentation, w.pods))
+	content.WriteString(fmt.Sprintf("%sRequested CPU: %s\n", peerDataIndentation, w.cpu.String()))
+	content.WriteString(fmt.Sprintf("%sRequested RAM: %s", peerDataIndentation, w.memory.String()))
+	return content.String()
+}
+
+//refreshIncomingWorkloads updates the description of the workloads offloaded by the peer with the given ClusterID.
+func refreshIncomingWorkloads(incomingEntry *app.MenuNode, clusterID string) {
+	w := peerIncomingWorkloads(clusterID)
+	if workloadsNode, present := incomingEntry.ListChild(tagIncomingWorkloads); present {
+		workloadsNode.SetTitle(describeIncomingWorkloads(w))
+		workloadsNode.SetIsVisible(w.namespaces > 0 || w.pods > 0)
+	}
+	if evictNode, present := incomingEntry.ListChild(tagIncomingEvict); present {
+		evictNode.SetIsEnabled(w.pods > 0)
+	}
+}
+
+//refreshPeerIncomingWorkloads updates the incoming workloads of a peer, if it is displayed in the peers list.
+func refreshPeerIncomingWorkloads(i *app.Indicator, clusterID string) {
+	quickNode, present := i.Quick(qPeers)
+	if !present {
+		return
+	}
+	peerNode, present := quickNode.ListChild(clusterID)
+	if !present {
+		return
+	}
+	if incomingEntry, ok := peerNode.ListChild(tagPeeringIncoming); ok {
+		refreshIncomingWorkloads(incomingEntry, clusterID)
+	}
+}
+
+//peerHelperEvictWorkloads is a callback to evict all the workloads offloaded to the home cluster by a peer.
+//It takes the *app-indicator/PeerInfo data of the correspondent peer.
+func peerHelperEvictWorkloads(args ...interface{}) {
+	if len(args) < 1 {
+		panic("wrong function arity: missing app-indicator.*PeerInfo parameter")
+	}
+	peer, ok := args[0].(*app.PeerInfo)
+	if !ok {
+		panic("argument is not *app-Indicator.PeerInfo")
+	}
+	i := app.GetIndicator()
+	ctrl := i.AgentCtrl()
+	peer.RLock()
+	clusterID := peer.ClusterID
+	peerName := peer.ClusterName
+	peer.RUnlock()
+	if !ctrl.Connected() {
+		return
+	}
+	if !app.GetGuiProvider().Mocked() {
+		confirm := app.GetGuiProvider().Confirm("EVICT WORKLOADS", fmt.Sprintf("Do you want to delete all the pods "+
+			"%s is running on your cluster?\n"+
+			"They may be scheduled again while the incoming peering is active.", peerName))
+		if !confirm {
+			return
+		}
+	}
+	if err := ctrl.EvictIncomingWorkloads(clusterID); err != nil {
+		i.ShowWarning("LIQO AGENT", "Liqo Agent could not evict all the workloads:\n"+err.Error())
+	}
+}
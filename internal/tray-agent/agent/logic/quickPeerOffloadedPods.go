@@ -0,0 +1,209 @@
+package logic
+
+import (
+	"fmt"
+	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
+	app "github.com/liqotech/liqo-agent/internal/tray-agent/app-indicator"
+	"sync"
+)
+
+/*This file contains internal variables and helper functions to display, inside the OUTGOING PEERING section
+of each peer, the pods of the home cluster that are running on the foreign cluster.*/
+
+//tagOffloadedPods is the tag of the menu entry listing the pods offloaded to a peer.
+const tagOffloadedPods = "offloadedPods"
+
+//titleOffloadedPods is the title of the menu entry listing the pods offloaded to a peer.
+const titleOffloadedPods = "Offloaded pods"
+
+//dashPodPath is the LiqoDash path of the page describing a pod, given its namespace and name.
+const dashPodPath = "/api/v1/namespaces/%s/pods/%s"
+
+// set of the actions available for an offloaded pod
+const (
+	podActionCopyLogs = "Copy 'kubectl logs' command"
+	podActionCopyExec = "Copy 'kubectl exec' command"
+	podActionOpenDash = "Open in LiqoDash"
+)
+
+//offloadedPodCache stores the last known data about the offloaded pods, indexed by the ClusterID of the peer
+//running them and then by namespace and name.
+var offloadedPodCache = struct {
+	pods map[string]map[string]map[string]*client.NotifyDataOffloadedPod
+	sync.RWMutex
+}{pods: make(map[string]map[string]map[string]*client.NotifyDataOffloadedPod)}
+
+//storeOffloadedPod saves the data of an offloaded pod.
+func storeOffloadedPod(data *client.NotifyDataOffloadedPod) {
+	offloadedPodCache.Lock()
+	defer offloadedPodCache.Unlock()
+	namespaces, present := offloadedPodCache.pods[data.ClusterID]
+	if !present {
+		namespaces = make(map[string]map[string]*client.NotifyDataOffloadedPod)
+		offloadedPodCache.pods[data.ClusterID] = namespaces
+	}
+	pods, present := namespaces[data.Namespace]
+	if !present {
+		pods = make(map[string]*client.NotifyDataOffloadedPod)
+		namespaces[data.Namespace] = pods
+	}
+	pods[data.Name] = data
+}
+
+//resetOffloadedPods replaces the stored data of the offloaded pods with the provided ones.
+func resetOffloadedPods(pods []*client.NotifyDataOffloadedPod) {
+	current := make(map[string]map[string]map[string]*client.NotifyDataOffloadedPod)
+	for _, data := range pods {
+		namespaces, present := current[data.ClusterID]
+		if !present {
+			namespaces = make(map[string]map[string]*client.NotifyDataOffloadedPod)
+			current[data.ClusterID] = namespaces
+		}
+		if _, present = namespaces[data.Namespace]; !present {
+			namespaces[data.Namespace] = make(map[string]*client.NotifyDataOffloadedPod)
+		}
+		namespaces[data.Namespace][data.Name] = data
+	}
+	offloadedPodCache.Lock()
+	offloadedPodCache.pods = current
+	offloadedPodCache.Unlock()
+}
+
+//removeOffloadedPod deletes the data of an offloaded pod, returning the ClusterID of the peer that was running it.
+//Since the virtual node of a deleted pod may no longer exist, the pod is searched among all peers when
+//its ClusterID is not provided.
+func removeOffloadedPod(data *client.NotifyDataOffloadedPod) (clusterID string, present bool) {
+	offloadedPodCache.Lock()
+	defer offloadedPodCache.Unlock()
+	for id, namespaces := range offloadedPodCache.pods {
+		if data.ClusterID != "" && id != data.ClusterID {
+			continue
+		}
+		pods, ok := namespaces[data.Namespace]
+		if !ok {
+			continue
+		}
+		if _, ok = pods[data.Name]; ok {
+			delete(pods, data.Name)
+			if len(pods) == 0 {
+				delete(namespaces, data.Namespace)
+			}
+			return id, true
+		}
+	}
+	return "", false
+}
+
+//offloadedPodCount returns the number of pods offloaded to a peer.
+func offloadedPodCount(clusterID string) int {
+	offloadedPodCache.RLock()
+	defer offloadedPodCache.RUnlock()
+	count := 0
+	for _, pods := range offloadedPodCache.pods[clusterID] {
+		count += len(pods)
+	}
+	return count
+}
+
+//describeOffloadedPod returns the formatted description of an offloaded pod.
+func describeOffloadedPod(data *client.NotifyDataOffloadedPod) string {
+	return fmt.Sprintf("%s%s [%s] restarts: %d", peerDataIndentation, data.Name, data.Phase, data.Restarts)
+}
+
+//refreshOffloadedPods updates the list of pods offloaded to the peer with the given ClusterID.
+func refreshOffloadedPods(outgoingEntry *app.MenuNode, clusterID string) {
+	podsEntry, present := outgoingEntry.ListChild(tagOffloadedPods)
+	if !present {
+		return
+	}
+	offloadedPodCache.RLock()
+	defer offloadedPodCache.RUnlock()
+	namespaces := offloadedPodCache.pods[clusterID]
+	//remove the entries of the namespaces and pods no longer offloaded
+	for _, nsEntry := range podsEntry.ListChildren() {
+		pods, ok := namespaces[nsEntry.Tag()]
+		if !ok {
+			podsEntry.FreeListChild(nsEntry.Tag())
+			continue
+		}
+		for _, podEntry := range nsEntry.ListChildren() {
+			if _, ok = pods[podEntry.Tag()]; !ok {
+				nsEntry.FreeListChild(podEntry.Tag())
+			}
+		}
+	}
+	//add or update the current ones
+	count := 0
+	for namespace, pods := range namespaces {
+		nsEntry, ok := podsEntry.ListChild(namespace)
+		if !ok {
+			nsEntry = podsEntry.UseListChild(peerDataIndentation+namespace, namespace)
+		}
+		for name, data := range pods {
+			podEntry, ok := nsEntry.ListChild(name)
+			if !ok {
+				podEntry = nsEntry.UseListChild("", name)
+				podEntry.Connect(false, podHelperActions, namespace, name)
+			}
+			podEntry.SetTitle(describeOffloadedPod(data))
+			count++
+		}
+	}
+	podsEntry.SetTitle(fmt.Sprintf("%s%s (%d)", peerDataIndentation, titleOffloadedPods, count))
+	podsEntry.SetIsVisible(count > 0)
+}
+
+//refreshPeerOffloadedPods updates the offloaded pods list of a peer, if it is displayed in the peers list.
+func refreshPeerOffloadedPods(i *app.Indicator, clusterID string) {
+	quickNode, present := i.Quick(qPeers)
+	if !present {
+		return
+	}
+	peerNode, present := quickNode.ListChild(clusterID)
+	if !present {
+		return
+	}
+	if outgoingEntry, ok := peerNode.ListChild(tagPeeringOutgoing); ok {
+		refreshOffloadedPods(outgoingEntry, clusterID)
+		refreshVirtualNode(outgoingEntry, clusterID)
+	}
+}
+
+//podHelperActions is the callback of an offloaded pod entry. It lets the user choose among the
+//available actions for the pod. It takes the namespace and the name of the pod.
+func podHelperActions(args ...interface{}) {
+	if len(args) < 2 {
+		panic("wrong function arity: missing pod namespace and name")
+	}
+	namespace, ok1 := args[0].(string)
+	name, ok2 := args[1].(string)
+	if !ok1 || !ok2 {
+		panic("pod namespace and name must be strings")
+	}
+	if app.GetGuiProvider().Mocked() {
+		return
+	}
+	i := app.GetIndicator()
+	choice, ok := app.GetGuiProvider().Choose("OFFLOADED POD", fmt.Sprintf("Pod %s/%s", namespace, name),
+		[]string{podActionCopyLogs, podActionCopyExec, podActionOpenDash})
+	if !ok {
+		return
+	}
+	switch choice {
+	case podActionCopyLogs:
+		podHelperCopyCommand(i, client.KubectlCommand("logs", "-n", namespace, name))
+	case podActionCopyExec:
+		podHelperCopyCommand(i, client.KubectlCommand("exec", "-it", "-n", namespace, name, "--", "sh"))
+	case podActionOpenDash:
+		openDashboard(i, fmt.Sprintf(dashPodPath, namespace, name))
+	}
+}
+
+//podHelperCopyCommand copies a command in the clipboard, notifying the user.
+func podHelperCopyCommand(i *app.Indicator, command string) {
+	if err := app.GetGuiProvider().Copy(command); err != nil {
+		i.ShowWarning("LIQO AGENT", "Liqo Agent could not copy the command to the clipboard")
+		return
+	}
+	i.Notify("Liqo Agent", "The command was copied in your clipboard", app.NotifyIconDefault, app.IconLiqoNil)
+}
@@ -0,0 +1,112 @@
+package logic
+
+import (
+	"fmt"
+	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
+	app "github.com/liqotech/liqo-agent/internal/tray-agent/app-indicator"
+	"strings"
+	"sync"
+)
+
+/*This file contains internal variables and helper functions to display, inside the OUTGOING PEERING section
+of each peer, the virtual node that represents the foreign cluster in the home cluster.*/
+
+//tagVirtualNode is the tag of the menu entry displaying the virtual node of an outgoing peering.
+const tagVirtualNode = "virtualNode"
+
+// set of frequently used text strings for menu entries regarding virtual nodes
+const (
+	//labelVirtualNodeReady is the label used to describe a virtual node ready to run pods.
+	labelVirtualNodeReady = "READY"
+	//labelVirtualNodeNotReady is the label used to describe a virtual node not ready to run pods.
+	labelVirtualNodeNotReady = "NOT READY"
+)
+
+//virtualNodeCache stores the last known data about the virtual node of each peer, indexed by ClusterID,
+//so that the peers list can display them even if the virtual node events arrive before the peer ones.
+var virtualNodeCache = struct {
+	nodes map[string]*client.NotifyDataVirtualNode
+	sync.RWMutex
+}{nodes: make(map[string]*client.NotifyDataVirtualNode)}
+
+//storeVirtualNode saves the data of a virtual node, returning the previously stored ones (if present).
+func storeVirtualNode(data *client.NotifyDataVirtualNode) (old *client.NotifyDataVirtualNode, present bool) {
+	virtualNodeCache.Lock()
+	defer virtualNodeCache.Unlock()
+	old, present = virtualNodeCache.nodes[data.ClusterID]
+	virtualNodeCache.nodes[data.ClusterID] = data
+	return
+}
+
+//removeVirtualNode deletes the data of a virtual node, if it is still the one associated to the peer.
+func removeVirtualNode(data *client.NotifyDataVirtualNode) {
+	virtualNodeCache.Lock()
+	defer virtualNodeCache.Unlock()
+	if stored, present := virtualNodeCache.nodes[data.ClusterID]; present && stored.Name == data.Name {
+		delete(virtualNodeCache.nodes, data.ClusterID)
+	}
+}
+
+//resetVirtualNodes replaces the stored data of the virtual nodes with the provided ones.
+func resetVirtualNodes(nodes []*client.NotifyDataVirtualNode) {
+	current := make(map[string]*client.NotifyDataVirtualNode)
+	for _, data := range nodes {
+		current[data.ClusterID] = data
+	}
+	virtualNodeCache.Lock()
+	virtualNodeCache.nodes = current
+	virtualNodeCache.Unlock()
+}
+
+//virtualNode returns the stored data of the virtual node associated to a peer.
+func virtualNode(clusterID string) (data *client.NotifyDataVirtualNode, present bool) {
+	virtualNodeCache.RLock()
+	defer virtualNodeCache.RUnlock()
+	data, present = virtualNodeCache.nodes[clusterID]
+	return
+}
+
+//describeVirtualNode returns the formatted description of a virtual node.
+func describeVirtualNode(data *client.NotifyDataVirtualNode) string {
+	readiness := labelVirtualNodeNotReady
+	if data.Ready {
+		readiness = labelVirtualNodeReady
+	}
+	content := strings.Builder{}
+	content.WriteString(fmt.Sprintf("%sVirtual node: %s [%s]\n", peerDataIndentation, data.Name, readiness))
+	content.WriteString(fmt.Sprintf("%sAllocatable CPU: %s\n", peerDataIndentation, data.Cpu))
+	content.WriteString(fmt.Sprintf("%sAllocatable RAM: %s\n", peerDataIndentation, data.Memory))
+	content.WriteString(fmt.Sprintf("%sPods: %d/%s", peerDataIndentation, offloadedPodCount(data.ClusterID), data.Pods))
+	return content.String()
+}
+
+//refreshVirtualNode updates the menu entry describing the virtual node of the outgoing peering
+//towards the peer with the given ClusterID.
+func refreshVirtualNode(outgoingEntry *app.MenuNode, clusterID string) {
+	nodeEntry, present := outgoingEntry.ListChild(tagVirtualNode)
+	if !present {
+		return
+	}
+	if data, ok := virtualNode(clusterID); ok {
+		nodeEntry.SetTitle(describeVirtualNode(data))
+		nodeEntry.SetIsVisible(true)
+	} else {
+		nodeEntry.SetIsVisible(false)
+		nodeEntry.SetTitle("")
+	}
+}
+
+//refreshPeerVirtualNode updates the virtual node entry of a peer, if it is displayed in the peers list.
+func refreshPeerVirtualNode(i *app.Indicator, clusterID string) {
+	quickNode, present := i.Quick(qPeers)
+	if !present {
+		return
+	}
+	peerNode, present := quickNode.ListChild(clusterID)
+	if !present {
+		return
+	}
+	if outgoingEntry, ok := peerNode.ListChild(tagPeeringOutgoing); ok {
+		refreshVirtualNode(outgoingEntry, clusterID)
+	}
+}
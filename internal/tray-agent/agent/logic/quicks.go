@@ -1,13 +1,11 @@
 package logic
 
 import (
+	"errors"
 	"fmt"
-	"github.com/atotto/clipboard"
-	"github.com/gen2brain/dlgs"
 	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
 	app "github.com/liqotech/liqo-agent/internal/tray-agent/app-indicator"
 	"github.com/skratchdot/open-golang/open"
-	"os"
 	"strings"
 )
 
@@ -24,36 +22,53 @@ const (
 
 //quickTurnOnOff is the callback for the QUICK "START/STOP LIQO".
 func quickTurnOnOff(i *app.Indicator) {
-	runSt := i.Status().Running()
-	dashQuick, dashPresent := i.Quick(qDash)
-	peersQuick, peersPresent := i.Quick(qPeers)
-	switch runSt {
+	switch i.Status().Running() {
 	case app.StatRunOff:
-		//turning ON LiqoAgent if possible
-		if i.AgentCtrl().Connected() {
-			i.Status().SetRunning(app.StatRunOn)
-			updateQuickTurnOnOff(i)
-			i.RefreshStatus()
-			i.SetIcon(app.IconLiqoMain)
-			if dashPresent {
-				dashQuick.SetIsEnabled(true)
-			}
-			if peersPresent {
-				refreshPeerCount(peersQuick)
-			}
-		}
+		turnOn(i, true)
 	case app.StatRunOn:
-		//turning OFF LiqoAgent
-		i.Status().SetRunning(app.StatRunOff)
-		updateQuickTurnOnOff(i)
-		i.RefreshStatus()
-		i.SetIcon(app.IconLiqoOff)
-		if dashPresent {
-			dashQuick.SetIsEnabled(false)
-		}
-		if peersPresent {
-			peersQuick.SetIsEnabled(false)
-		}
+		turnOff(i)
+	}
+}
+
+//turnOn turns ON LiqoAgent if possible, resynchronizing the information on peers, peerings and the other menu
+//sections with the cluster. If notify is true, the changes of the peers occurred while the Agent was OFF are
+//notified to the user.
+func turnOn(i *app.Indicator, notify bool) {
+	if !i.AgentCtrl().Connected() {
+		return
+	}
+	dashQuick, dashPresent := i.Quick(qDash)
+	peersQuick, peersPresent := i.Quick(qPeers)
+	i.Status().SetRunning(app.StatRunOn)
+	updateQuickTurnOnOff(i)
+	i.SetIcon(app.IconLiqoMain)
+	summary := resyncPeers(i)
+	resyncMenus(i)
+	i.RefreshStatus()
+	if dashPresent {
+		dashQuick.SetIsEnabled(i.AgentCtrl().DashboardStatus().Ready)
+	}
+	if peersPresent {
+		refreshPeerCount(peersQuick)
+	}
+	if notify {
+		i.NotifyResync(summary)
+	}
+}
+
+//turnOff turns OFF LiqoAgent.
+func turnOff(i *app.Indicator) {
+	dashQuick, dashPresent := i.Quick(qDash)
+	peersQuick, peersPresent := i.Quick(qPeers)
+	i.Status().SetRunning(app.StatRunOff)
+	updateQuickTurnOnOff(i)
+	i.RefreshStatus()
+	i.SetIcon(app.IconLiqoOff)
+	if dashPresent {
+		dashQuick.SetIsEnabled(false)
+	}
+	if peersPresent {
+		peersQuick.SetIsEnabled(false)
 	}
 }
 
@@ -127,9 +142,9 @@ func quickChangeNotifyLevel() {
 	i := app.GetIndicator()
 	if !app.GetGuiProvider().Mocked() {
 		notifyDescription := i.Config().NotifyDescriptions()
-		level, ok, _ := dlgs.List("NOTIFICATION SETTINGS", fmt.Sprintf("Choose how you would like to receive "+
-			"notifications from Liqo.\n"+
-			"CURRENT: %s", i.Config().NotifyTranslate(i.Config().NotifyLevel())), notifyDescription)
+		message := fmt.Sprintf("Choose how you would like to receive notifications from Liqo.\n"+
+			"CURRENT: %s", i.Config().NotifyTranslate(i.Config().NotifyLevel()))
+		level, ok := app.GetGuiProvider().Choose("NOTIFICATION SETTINGS", message, notifyDescription)
 		if ok {
 			i.NotificationSetLevel(i.Config().NotifyTranslateReverse(level))
 		}
@@ -138,44 +153,52 @@ func quickChangeNotifyLevel() {
 
 //quickConnectDashboard is the callback function for the QUICK "Launch LiqoDash".
 //
-//- If LiqoDash connection parameters are set (or can be retrieved), it opens the LiqoDash address
+//- If the LiqoDash endpoint is available (or can be resolved), it opens the LiqoDash address
 //in the default browser.
 //
-//- Then, it searches for an access token in the cluster and provides it to the user directly in the
-//clipboard, ready to be pasted.
+//- The page is served by a local proxy that authenticates the requests with an access token of the
+//cluster, so that no login is required.
 func quickConnectDashboard(i *app.Indicator) {
+	openDashboard(i, "")
+}
+
+//openDashboard opens the LiqoDash page at the given path in the default browser, through the local
+//authentication proxy.
+func openDashboard(i *app.Indicator, path string) {
 	ctrl := i.AgentCtrl()
-	//Check if connection parameters are already set in the env vars to speed execution up.
-	host, ok1 := os.LookupEnv(client.EnvLiqoDashHost)
-	port, ok2 := os.LookupEnv(client.EnvLiqoDashPort)
-	if !ok1 || !ok2 {
-		if err := ctrl.AcquireDashboardConfig(); err != nil {
-			i.Notify("Liqo Agent: SERVICE UNAVAILABLE", err.Error(),
-				app.NotifyIconDefault, app.IconLiqoNil)
-			return
-		}
-		host = os.Getenv(client.EnvLiqoDashHost)
-		port = os.Getenv(client.EnvLiqoDashPort)
-	}
-	dashUrlBuilder := strings.Builder{}
-	dashUrlBuilder.WriteString(host)
-	if port != "" {
-		dashUrlBuilder.WriteString(":" + port)
-	}
-	dashUrl := dashUrlBuilder.String()
-	if err := open.Run(dashUrl); err == nil {
-		//try to recover access token
-		if token, errNFound := ctrl.GetLiqoDashSecret(); errNFound == nil {
-			if err = clipboard.WriteAll(*token); err == nil {
-				i.Notify("Liqo Agent", "The LiqoDash access token was copied in your clipboard",
-					app.NotifyIconDefault, app.IconLiqoNil)
-			} else {
-				i.ShowWarning("LIQO AGENT", "Liqo Agent could not copy LiqoDash access token\n"+
-					"to the clipboard")
-			}
-		} else {
-			i.Notify("Liqo Agent", "LiqoDash access token was not found",
-				app.NotifyIconDefault, app.IconLiqoNil)
-		}
+	//the endpoint is cached by the AgentController until the LiqoDash resources change
+	endpoint, err := ctrl.DashboardEndpoint()
+	if choiceErr, ok := err.(*client.DashboardChoiceError); ok {
+		endpoint, err = dashboardHelperChoose(i, choiceErr.Candidates)
+	}
+	if err != nil {
+		i.Notify("Liqo Agent: SERVICE UNAVAILABLE", err.Error(),
+			app.NotifyIconDefault, app.IconLiqoNil)
+		return
+	}
+	proxyUrl, err := ctrl.DashboardProxyURL(endpoint, path)
+	if err != nil {
+		i.Notify("Liqo Agent: SERVICE UNAVAILABLE", "Liqo Agent could not authenticate to LiqoDash:\n"+err.Error(),
+			app.NotifyIconDefault, app.IconLiqoNil)
+		return
+	}
+	if err := open.Run(proxyUrl); err != nil {
+		i.ShowWarning("LIQO AGENT", "Liqo Agent could not open the browser")
+	}
+}
+
+//dashboardHelperChoose asks the user which LiqoDash URL to use among the available ones, remembering the choice.
+func dashboardHelperChoose(i *app.Indicator, candidates []string) (string, error) {
+	if app.GetGuiProvider().Mocked() {
+		return "", errors.New("no LiqoDash address chosen")
+	}
+	choice, ok := app.GetGuiProvider().Choose("LIQODASH", "LiqoDash is available at several addresses.\n"+
+		"Choose the one to use:", candidates)
+	if !ok {
+		return "", errors.New("no LiqoDash address chosen")
+	}
+	if err := client.SaveDashboardChoice(choice); err != nil {
+		i.ShowWarning("LIQO AGENT", "Liqo Agent could not save settings changes")
 	}
+	return i.AgentCtrl().DashboardEndpoint()
 }
@@ -0,0 +1,28 @@
+package logic
+
+import (
+	"flag"
+	"github.com/liqotech/liqo-agent/internal/tray-agent/test"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//updateGolden regenerates the golden files of the scenarios instead of comparing them:
+//
+//	go test ./internal/tray-agent/agent/logic -run TestScenarios -update
+var updateGolden = flag.Bool("update", false, "update the golden files of the scenarios")
+
+//TestScenarios runs the scenarios in testdata/scenarios on a fake Liqo cluster.
+func TestScenarios(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "scenarios", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		path := path
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".yaml"), func(t *testing.T) {
+			test.RunScenario(t, OnReady, path, *updateGolden)
+		})
+	}
+}
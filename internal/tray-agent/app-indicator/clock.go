@@ -0,0 +1,142 @@
+package app_indicator
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//Clock provides the current time and the timers to a Scheduler, so that tests can control the flow of time.
+type Clock interface {
+	//Now returns the current time.
+	Now() time.Time
+	//NewTimer creates a ClockTimer that fires after d. If d <= 0, it fires immediately.
+	NewTimer(d time.Duration) ClockTimer
+}
+
+//ClockTimer is a single-shot timer created by a Clock.
+type ClockTimer interface {
+	//C returns the channel on which the current time is sent when the ClockTimer fires.
+	C() <-chan time.Time
+	//Stop prevents the ClockTimer from firing. It returns false if the ClockTimer already fired or was stopped.
+	Stop() bool
+}
+
+//indicatorClock is the Clock of the Scheduler of the Indicator. If nil, the system clock is used.
+var indicatorClock Clock
+
+//UseClock selects the Clock of the Scheduler of the Indicator, e.g. a FakeClock for testing purposes.
+//
+//Function MUST be called before GetIndicator in order to be effective.
+func UseClock(clock Clock) {
+	indicatorClock = clock
+}
+
+//systemClock is the Clock based on the system time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) ClockTimer {
+	return &systemTimer{timer: time.NewTimer(d)}
+}
+
+//systemTimer is the ClockTimer of systemClock.
+type systemTimer struct {
+	timer *time.Timer
+}
+
+func (t *systemTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t *systemTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+//FakeClock is a Clock whose time only flows when Advance is called.
+type FakeClock struct {
+	//now is the current time of the FakeClock.
+	now time.Time
+	//timers contains the ClockTimers that have not fired yet.
+	timers []*fakeTimer
+	mutex  sync.Mutex
+}
+
+//NewFakeClock creates a FakeClock whose current time is now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+//Now returns the current time of the FakeClock.
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+//NewTimer creates a ClockTimer that fires when the FakeClock is advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) ClockTimer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &fakeTimer{clock: c, deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+//Advance moves the time of the FakeClock forward by d, firing the expired ClockTimers in order of deadline.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+	var pending []*fakeTimer
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	c.timers = pending
+}
+
+//Timers returns the number of ClockTimers of the FakeClock that have not fired yet. It allows tests to wait
+//for a Scheduler to be waiting for its next Timer.
+func (c *FakeClock) Timers() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.timers)
+}
+
+//fakeTimer is the ClockTimer of FakeClock.
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for n, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:n], c.timers[n+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package app_indicator
+
+import (
+	"github.com/atotto/clipboard"
+	bip "github.com/gen2brain/beeep"
+	"github.com/gen2brain/dlgs"
+)
+
+//desktopDialogs implements the dialogs of the GuiProviderInterface with the windows, banners and clipboard of the
+//desktop environment. It is embedded by the frontends running on the user desktop.
+type desktopDialogs struct{}
+
+func (desktopDialogs) Prompt(title, message, defaultValue string) (string, bool) {
+	input, ok, _ := dlgs.Entry(title, message, defaultValue)
+	return input, ok
+}
+
+func (desktopDialogs) Choose(title, message string, items []string) (string, bool) {
+	choice, ok, _ := dlgs.List(title, message, items)
+	return choice, ok
+}
+
+func (desktopDialogs) SelectFile(title, filter string) (string, bool) {
+	path, ok, _ := dlgs.File(title, filter, false)
+	return path, ok
+}
+
+func (desktopDialogs) Confirm(title, message string) bool {
+	confirm, _ := dlgs.Question(title, message, false)
+	return confirm
+}
+
+func (desktopDialogs) Warning(title, message string) {
+	_, _ = dlgs.Warning(title, message)
+}
+
+func (desktopDialogs) Error(title, message string) {
+	_, _ = dlgs.Error(title, message)
+}
+
+func (desktopDialogs) Notify(title, message, iconPath string) {
+	_ = bip.Notify(title, message, iconPath)
+}
+
+func (desktopDialogs) Copy(text string) error {
+	return clipboard.WriteAll(text)
+}
@@ -0,0 +1,369 @@
+package app_indicator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
+	"sync"
+	"time"
+)
+
+//maxPendingEvents is the maximum number of events the dispatcher accepts before their handling. When it is reached,
+//the dispatcher stops receiving new events (backpressure) until a pending event is handled.
+const maxPendingEvents = 1000
+
+//event is a unit of work handled by the dispatcher: a notification of the AgentController, a 'clicked' event
+//of a MenuNode or the execution of a Timer callback.
+type event struct {
+	//key identifies the events that have to be handled in order (e.g. the events regarding the same peer).
+	key string
+	//handle executes the callback associated to the event. It returns false if the event has been discarded.
+	//ctx identifies the handler (see handlerContext).
+	handle func(ctx context.Context) bool
+}
+
+//handlerKey is the key of the context value marking an event handler.
+type handlerKey struct{}
+
+//handlerContext returns the context of an event handler, which is marked so that Shutdown does not wait for the
+//handler calling it. detach stops tracking the worker executing the handler.
+func handlerContext(detach func()) context.Context {
+	return context.WithValue(context.Background(), handlerKey{}, detach)
+}
+
+//detachHandler stops tracking the worker executing the event handler identified by ctx (if any).
+func detachHandler(ctx context.Context) {
+	if detach, ok := ctx.Value(handlerKey{}).(func()); ok {
+		detach()
+	}
+}
+
+//DispatcherStats contains the metrics of the Indicator event pipeline.
+type DispatcherStats struct {
+	//Received is the number of events received.
+	Received int
+	//Handled is the number of events whose callback has been executed.
+	Handled int
+	//Discarded is the number of events that had no callback to execute (e.g. notifications received
+	//while the Agent is OFF).
+	Discarded int
+	//Pending is the number of events received and not handled yet.
+	Pending int
+	//MaxPending is the highest value reached by Pending.
+	MaxPending int
+	//Stalls is the number of times the dispatcher stopped receiving events because of the maxPendingEvents limit.
+	Stalls int
+	//Keys is the number of event keys having pending events.
+	Keys int
+}
+
+/*dispatcher is the single entry point of the events handled by the Indicator. Events sharing the same key
+are handled sequentially, in the order they have been received, while events with different keys are handled
+concurrently: each key with pending events has a worker goroutine that exits as soon as its queue is empty.
+The only exception are the events regarding the whole peers list (client.PeersListEventKey), which are never
+handled together with the events regarding a single peer (client.PeerEventKey).
+
+If the dispatcher is synchronous (test mode), no goroutine is used: events are received and handled in the goroutine
+calling dispatch or dispatchPending.*/
+type dispatcher struct {
+	//events is the stream of the AgentController notifications.
+	events <-chan client.NotifyEvent
+	//clicks receives the 'clicked' events of the MenuNodes and the executions of the Timers callbacks.
+	clicks chan event
+	//listening returns whether the Listeners callbacks can be executed.
+	listening func() bool
+	//synchronous specifies whether the dispatcher is in test mode.
+	synchronous bool
+	//listeners contains the registered Listeners, by NotifyChannel.
+	listeners map[client.NotifyChannel]*Listener
+	//queues contains the pending events, by key. The event at the head of a queue is the one being handled.
+	queues map[string][]event
+	//slots limits the number of pending events to maxPendingEvents.
+	slots chan struct{}
+	//quit is closed when the dispatcher stops: the workers then discard the pending events.
+	quit <-chan struct{}
+	//workers tracks the workers of the keys, so that their handlers are awaited at Shutdown.
+	workers *client.WorkerGroup
+	//peers is held in write mode by the handlers of the events regarding the whole peers list, in read mode by
+	//the ones regarding a single peer.
+	peers sync.RWMutex
+	stats DispatcherStats
+	mutex sync.Mutex
+}
+
+//newDispatcher creates a dispatcher for the stream of AgentController notifications 'events'. The workers
+//are tracked by 'workers'.
+func newDispatcher(events <-chan client.NotifyEvent, synchronous bool, listening func() bool,
+	workers *client.WorkerGroup) *dispatcher {
+	return &dispatcher{
+		events:      events,
+		workers:     workers,
+		clicks:      make(chan event),
+		listening:   listening,
+		synchronous: synchronous,
+		listeners:   make(map[client.NotifyChannel]*Listener),
+		queues:      make(map[string][]event),
+		slots:       make(chan struct{}, maxPendingEvents),
+	}
+}
+
+//run receives the events until quit is closed, assigning them to the workers of their keys. Once quit is
+//closed, the workers discard the pending events.
+func (d *dispatcher) run(quit <-chan struct{}) {
+	d.mutex.Lock()
+	d.quit = quit
+	d.mutex.Unlock()
+	for {
+		var e event
+		select {
+		case data, open := <-d.events:
+			if !open {
+				return
+			}
+			e = d.notifyEvent(data)
+		case e = <-d.clicks:
+		case <-quit:
+			return
+		}
+		select {
+		case d.slots <- struct{}{}:
+		default:
+			d.mutex.Lock()
+			d.stats.Stalls++
+			d.mutex.Unlock()
+			select {
+			case d.slots <- struct{}{}:
+			case <-quit:
+				return
+			}
+		}
+		d.enqueue(e)
+	}
+}
+
+//enqueue appends an event to the queue of its key, starting a worker if the queue was empty.
+func (d *dispatcher) enqueue(e event) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.stats.Received++
+	d.stats.Pending++
+	if d.stats.Pending > d.stats.MaxPending {
+		d.stats.MaxPending = d.stats.Pending
+	}
+	queue := d.queues[e.key]
+	d.queues[e.key] = append(queue, e)
+	if len(queue) == 0 {
+		d.workers.GoDetachable(func(detach func()) {
+			d.work(e.key, detach)
+		})
+	}
+}
+
+//work handles in order the events of the queue 'key', until it is empty. detach stops tracking the worker
+//(see handlerContext).
+func (d *dispatcher) work(key string, detach func()) {
+	d.mutex.Lock()
+	e := d.queues[key][0]
+	quit := d.quit
+	d.mutex.Unlock()
+	ctx := handlerContext(detach)
+	for {
+		handled := false
+		select {
+		case <-quit:
+		default:
+			handled = d.handle(ctx, e)
+		}
+		d.mutex.Lock()
+		d.done(handled)
+		queue := d.queues[key][1:]
+		if len(queue) == 0 {
+			delete(d.queues, key)
+			d.mutex.Unlock()
+			<-d.slots
+			return
+		}
+		d.queues[key] = queue
+		e = queue[0]
+		d.mutex.Unlock()
+		<-d.slots
+	}
+}
+
+//handle executes the callback of an event, excluding the concurrent changes to the peers list.
+func (d *dispatcher) handle(ctx context.Context, e event) bool {
+	switch {
+	case e.key == client.PeersListEventKey:
+		d.peers.Lock()
+		defer d.peers.Unlock()
+	case client.IsPeerEventKey(e.key):
+		d.peers.RLock()
+		defer d.peers.RUnlock()
+	}
+	return e.handle(ctx)
+}
+
+//done updates the stats after the handling of an event. It must be called holding the mutex.
+func (d *dispatcher) done(handled bool) {
+	d.stats.Pending--
+	if handled {
+		d.stats.Handled++
+	} else {
+		d.stats.Discarded++
+	}
+}
+
+//dispatch receives and handles n events in the calling goroutine. It returns an error if the events are not
+//received within timeout.
+func (d *dispatcher) dispatch(n int, timeout time.Duration) error {
+	if !d.synchronous {
+		return errors.New("events are dispatched synchronously only in test mode")
+	}
+	deadline := time.After(timeout)
+	for handled := 0; handled < n; handled++ {
+		var e event
+		select {
+		case data := <-d.events:
+			e = d.notifyEvent(data)
+		case e = <-d.clicks:
+		case <-deadline:
+			return fmt.Errorf("%d of %d events not received after %s", n-handled, n, timeout)
+		}
+		d.handleSync(e)
+	}
+	return nil
+}
+
+//dispatchPending handles in the calling goroutine the events already received, returning their number.
+func (d *dispatcher) dispatchPending() int {
+	for handled := 0; ; handled++ {
+		var e event
+		select {
+		case data := <-d.events:
+			e = d.notifyEvent(data)
+		case e = <-d.clicks:
+		default:
+			return handled
+		}
+		d.handleSync(e)
+	}
+}
+
+//handleSync handles an event in test mode.
+func (d *dispatcher) handleSync(e event) {
+	d.mutex.Lock()
+	d.stats.Received++
+	d.stats.Pending++
+	if d.stats.Pending > d.stats.MaxPending {
+		d.stats.MaxPending = d.stats.Pending
+	}
+	d.mutex.Unlock()
+	//the handler runs in the calling goroutine, which is not tracked
+	handled := d.handle(handlerContext(func() {}), e)
+	d.mutex.Lock()
+	d.done(handled)
+	d.mutex.Unlock()
+}
+
+//notifyEvent converts a notification of the AgentController into an event, executing the callback of the
+//Listener registered for its NotifyChannel.
+func (d *dispatcher) notifyEvent(data client.NotifyEvent) event {
+	return event{
+		key: data.Data.EventKey(),
+		handle: func(context.Context) bool {
+			l, present := d.listener(data.Channel)
+			/*While the Agent is OFF, the callback is not executed, in order not to update information
+			on status and tray menu or trigger notifications.*/
+			if !present || !d.listening() {
+				return false
+			}
+			l.callback(data.Data, l.args...)
+			return true
+		},
+	}
+}
+
+//click sends a 'clicked' event (or the execution of a Timer callback) to the dispatcher. It returns false if quit
+//is closed before the event is received.
+func (d *dispatcher) click(key string, callback func(ctx context.Context), quit <-chan struct{}) bool {
+	e := event{
+		key: key,
+		handle: func(ctx context.Context) bool {
+			callback(ctx)
+			return true
+		},
+	}
+	select {
+	case d.clicks <- e:
+		return true
+	case <-quit:
+		return false
+	}
+}
+
+//listen registers a Listener, replacing the one (if any) with the same Tag.
+func (d *dispatcher) listen(l *Listener) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.listeners[l.Tag] = l
+}
+
+//stopListener removes the Listener for a NotifyChannel.
+func (d *dispatcher) stopListener(tag client.NotifyChannel) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	delete(d.listeners, tag)
+}
+
+//listener returns the Listener for a NotifyChannel.
+func (d *dispatcher) listener(tag client.NotifyChannel) (*Listener, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	l, present := d.listeners[tag]
+	return l, present
+}
+
+//RunOnPeersList executes callback as the handler of an event regarding the whole peers list, i.e. while no event
+//regarding a single peer is handled (see client.PeersListEventKey). It must not be called by an event handler.
+func (i *Indicator) RunOnPeersList(callback func()) {
+	d := i.dispatcher
+	d.peers.Lock()
+	defer d.peers.Unlock()
+	callback()
+}
+
+//DispatcherStats returns the metrics of the Indicator event pipeline.
+func (i *Indicator) DispatcherStats() DispatcherStats {
+	d := i.dispatcher
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	stats := d.stats
+	stats.Keys = len(d.queues)
+	return stats
+}
+
+/*DispatchEvents receives and handles, in the calling goroutine, the next n events of the Indicator
+(AgentController notifications, 'clicked' events of the menu and Timers executions). It returns an error if the events are not
+received within timeout.
+
+It works only in test mode (after calling UseMockedGuiProvider), where the events are not handled until
+the test requests it, so that their effects can be checked deterministically:
+
+	i := GetIndicator()
+	//trigger a change on the cluster or click a MenuNode
+	err := i.DispatchEvents(1, time.Second)
+	//perform checks on changes and continue
+*/
+func (i *Indicator) DispatchEvents(n int, timeout time.Duration) error {
+	return i.dispatcher.dispatch(n, timeout)
+}
+
+//DispatchPendingEvents handles, in the calling goroutine, the events of the Indicator already received,
+//returning their number. Like DispatchEvents, it works only in test mode.
+func (i *Indicator) DispatchPendingEvents() int {
+	if !i.dispatcher.synchronous {
+		return 0
+	}
+	return i.dispatcher.dispatchPending()
+}
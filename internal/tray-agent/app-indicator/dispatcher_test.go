@@ -0,0 +1,154 @@
+package app_indicator
+
+import (
+	"context"
+	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+//waitStats waits for the dispatcher stats to satisfy cond.
+func waitStats(t *testing.T, d *dispatcher, cond func(stats DispatcherStats) bool) {
+	t.Helper()
+	i := &Indicator{dispatcher: d}
+	assert.Eventually(t, func() bool {
+		return cond(i.DispatcherStats())
+	}, 5*time.Second, time.Millisecond, "dispatcher stats not reached: %+v", i.DispatcherStats())
+}
+
+func TestDispatcher_Ordering(t *testing.T) {
+	events := make(chan client.NotifyEvent, 10)
+	quit := make(chan struct{})
+	defer close(quit)
+	d := newDispatcher(events, false, func() bool { return true }, nil)
+	unblock := make(chan struct{})
+	mutex := sync.Mutex{}
+	var handled []string
+	d.listen(&Listener{Tag: client.ChanPeerAddedOrUpdated, callback: func(data client.NotifyDataGeneric,
+		args ...interface{}) {
+		fc := data.(*client.NotifyDataForeignCluster)
+		//the first event of peer 'a' blocks its queue, but not the one of peer 'b'
+		if fc.Name == "a1" {
+			<-unblock
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		handled = append(handled, fc.Name)
+	}})
+	go d.run(quit)
+	for _, name := range []string{"a1", "b1", "a2", "b2", "a3"} {
+		events <- client.NotifyEvent{
+			Channel: client.ChanPeerAddedOrUpdated,
+			Data:    &client.NotifyDataForeignCluster{Name: name, ClusterID: name[:1]},
+		}
+	}
+	//events with no Listener are discarded
+	events <- client.NotifyEvent{Channel: client.ChanPeerDeleted, Data: &client.NotifyDataForeignCluster{ClusterID: "b"}}
+	waitStats(t, d, func(stats DispatcherStats) bool {
+		return stats.Handled == 2 && stats.Discarded == 1
+	})
+	stats := (&Indicator{dispatcher: d}).DispatcherStats()
+	assert.Equal(t, 3, stats.Pending, "wrong number of pending events")
+	assert.Equal(t, 1, stats.Keys, "only the queue of the blocked peer should be pending")
+	close(unblock)
+	waitStats(t, d, func(stats DispatcherStats) bool {
+		return stats.Handled == 5 && stats.Pending == 0 && stats.Keys == 0
+	})
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []string{"b1", "b2", "a1", "a2", "a3"}, handled, "events of the same peer not handled in order")
+}
+
+func TestDispatcher_PeersList(t *testing.T) {
+	events := make(chan client.NotifyEvent, 10)
+	quit := make(chan struct{})
+	defer close(quit)
+	d := newDispatcher(events, false, func() bool { return true }, nil)
+	unblock := make(chan struct{})
+	peerRunning := make(chan struct{})
+	mutex := sync.Mutex{}
+	var handled []string
+	appendHandled := func(name string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		handled = append(handled, name)
+	}
+	d.listen(&Listener{Tag: client.ChanPeerAddedOrUpdated, callback: func(data client.NotifyDataGeneric,
+		args ...interface{}) {
+		close(peerRunning)
+		<-unblock
+		appendHandled("peer")
+	}})
+	d.listen(&Listener{Tag: client.ChanClusterConfig, callback: func(client.NotifyDataGeneric, ...interface{}) {
+		appendHandled("peers list")
+	}})
+	go d.run(quit)
+	events <- client.NotifyEvent{Channel: client.ChanPeerAddedOrUpdated, Data: &client.NotifyDataForeignCluster{ClusterID: "a"}}
+	<-peerRunning
+	//the events regarding the whole peers list wait for the ones of the single peers, the others do not
+	events <- client.NotifyEvent{Channel: client.ChanClusterConfig, Data: &client.NotifyDataClusterConfig{}}
+	assert.True(t, d.click("menu/other", func(context.Context) { appendHandled("other") }, quit))
+	waitStats(t, d, func(stats DispatcherStats) bool {
+		return stats.Handled == 1
+	})
+	assert.Never(t, func() bool {
+		return (&Indicator{dispatcher: d}).DispatcherStats().Handled > 1
+	}, 50*time.Millisecond, time.Millisecond, "peers list event handled together with a peer event")
+	close(unblock)
+	waitStats(t, d, func(stats DispatcherStats) bool {
+		return stats.Handled == 3 && stats.Pending == 0
+	})
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []string{"other", "peer", "peers list"}, handled, "peers list event not serialized")
+}
+
+func TestDispatcher_Backpressure(t *testing.T) {
+	events := make(chan client.NotifyEvent, maxPendingEvents+1)
+	quit := make(chan struct{})
+	defer close(quit)
+	d := newDispatcher(events, false, func() bool { return true }, nil)
+	unblock := make(chan struct{})
+	d.listen(&Listener{Tag: client.ChanPeerAddedOrUpdated, callback: func(client.NotifyDataGeneric, ...interface{}) {
+		<-unblock
+	}})
+	go d.run(quit)
+	for n := 0; n <= maxPendingEvents; n++ {
+		events <- client.NotifyEvent{Channel: client.ChanPeerAddedOrUpdated, Data: &client.NotifyDataForeignCluster{}}
+	}
+	waitStats(t, d, func(stats DispatcherStats) bool {
+		return stats.Stalls == 1
+	})
+	stats := (&Indicator{dispatcher: d}).DispatcherStats()
+	assert.Equal(t, maxPendingEvents, stats.Pending, "pending events exceeded the limit")
+	close(unblock)
+	waitStats(t, d, func(stats DispatcherStats) bool {
+		return stats.Handled == maxPendingEvents+1 && stats.MaxPending == maxPendingEvents
+	})
+}
+
+func TestDispatcher_Synchronous(t *testing.T) {
+	events := make(chan client.NotifyEvent, 2)
+	listening := false
+	d := newDispatcher(events, true, func() bool { return listening }, nil)
+	i := &Indicator{dispatcher: d}
+	calls := 0
+	i.Listen(client.ChanPeerDeleted, func(client.NotifyDataGeneric, ...interface{}) {
+		calls++
+	})
+	events <- client.NotifyEvent{Channel: client.ChanPeerDeleted, Data: &client.NotifyDataForeignCluster{}}
+	assert.Equal(t, 0, calls, "event handled before dispatching")
+	assert.Equal(t, 1, i.DispatchPendingEvents())
+	assert.Equal(t, 0, calls, "event handled while not listening")
+	listening = true
+	events <- client.NotifyEvent{Channel: client.ChanPeerDeleted, Data: &client.NotifyDataForeignCluster{}}
+	assert.NoError(t, i.DispatchEvents(1, time.Second))
+	assert.Equal(t, 1, calls, "event not handled")
+	assert.Error(t, i.DispatchEvents(1, time.Millisecond), "missing event dispatched")
+	i.StopListener(client.ChanPeerDeleted)
+	_, present := i.Listener(client.ChanPeerDeleted)
+	assert.False(t, present, "Listener not removed")
+	assert.Equal(t, DispatcherStats{Received: 2, Handled: 1, Discarded: 1, MaxPending: 1}, i.DispatcherStats())
+}
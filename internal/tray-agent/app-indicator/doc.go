@@ -1,7 +1,8 @@
 /*
 package app_indicator provides API to install a system tray Indicator and bind it to a menu.
-It relies on the github.com/getlantern/systray to display the indicator (icon+label) and perform
-a basic management of each menu entry (MenuNode).
+The indicator (icon+label) and each menu entry (MenuNode) are displayed by a frontend (GuiProviderInterface)
+selected among the registered ones (see RegisterGuiProvider). By default, the frontend relies on
+github.com/getlantern/systray.
 
 The GetIndicator() function returns the Indicator singleton.
 
@@ -13,6 +14,17 @@ The Indicator can:
 
 * instantiate an event handler (Listener)
 
+* schedule the periodic or run-once execution of a callback (Timer), with an optional random jitter. The Timers are
+handled by the Indicator Scheduler, whose Clock can be replaced in tests (see UseClock and FakeClock).
+
+All the events (notifications of the AgentController, 'clicked' events of the menu and Timers executions) are handled
+by a single dispatcher: events regarding the same resource (e.g. the same peer) are handled in order, while the others
+are handled concurrently. With a mocked frontend (see UseMockedGuiProvider) the events are handled synchronously, only when the test
+calls Indicator.DispatchEvents.
+
+All the Indicator goroutines share a root context, which is cancelled at Quit: Indicator.Shutdown waits for them to
+exit, together with the ones of the AgentController.
+
 * communicate with the user through a notification system that exploits changes of the Indicator icon and desktop banners.
 
 USAGE EXAMPLE:
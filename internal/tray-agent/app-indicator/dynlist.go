@@ -80,18 +80,21 @@ func (nl *nodeList) freeNode(tag string) {
 	}
 }
 
-//freeAllNodes iteratively applies freeNode() to all used LIST MenuNode.
+//freeAllNodes iteratively applies freeNode() to all used LIST MenuNode. The LIST children of the nodes
+//are freed concurrently.
 func (nl *nodeList) freeAllNodes() {
 	nl.Lock()
 	defer nl.Unlock()
-	defer nl.Wait()
-	for tag, node := range nl.usedNodes {
+	for _, node := range nl.usedNodes {
 		nl.Add(1)
-		go func(n *MenuNode, str string) {
+		go func(n *MenuNode) {
 			defer nl.Done()
 			n.FreeListChildren()
-			nl.freeNode(str)
-		}(node, tag)
+		}(node)
+	}
+	nl.Wait()
+	for tag := range nl.usedNodes {
+		nl.freeNode(tag)
 	}
 }
 
@@ -101,3 +104,14 @@ func (nl *nodeList) usedNodeLen() int {
 	defer nl.RUnlock()
 	return len(nl.usedNodes)
 }
+
+//usedNodeList returns the LIST MenuNode currently in use.
+func (nl *nodeList) usedNodeList() []*MenuNode {
+	nl.RLock()
+	defer nl.RUnlock()
+	nodes := make([]*MenuNode, 0, len(nl.usedNodes))
+	for _, node := range nl.usedNodes {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
@@ -1,7 +1,8 @@
 package app_indicator
 
 import (
-	"github.com/getlantern/systray"
+	"os"
+	"sort"
 	"sync"
 )
 
@@ -11,14 +12,13 @@ var mockedGui bool
 //mockOnce prevents mockedGui to be modified at runtime.
 var mockOnce sync.Once
 
-//guiProviderInstance is the guiProvider singleton.
-var guiProviderInstance *guiProvider
+//guiProviderInstance is the GuiProviderInterface singleton.
+var guiProviderInstance GuiProviderInterface
 
 //guiProviderOnce protects guiProviderInstance.
 var guiProviderOnce sync.Once
 
-//UseMockedGuiProvider enables a mocked guiProvider that does not interact with the OS graphic server.
-//The real guiProvider internally exploits github.com/getlantern/systray to orchestrate GUI execution.
+//UseMockedGuiProvider selects the GuiMock provider, which does not interact with any graphic server.
 //
 //Function MUST be called before GetGuiProvider in order to be effective.
 func UseMockedGuiProvider() {
@@ -32,23 +32,65 @@ func UseMockedGuiProvider() {
 func DestroyMockedIndicator() {
 	if mockedGui {
 		root = nil
+		if g, ok := GetGuiProvider().(*MockGuiProvider); ok {
+			g.reset()
+		}
 	}
 }
 
-//GetGuiProvider returns the guiProvider singleton that provides the functions to interact with the graphic server.
+//EnvLiqoGui is the env var selecting the frontend of the Agent among the registered ones.
+//If not set (or unknown), GuiTray is used.
+const EnvLiqoGui = "LIQO_AGENT_GUI"
+
+//GuiProviderFactory creates the GuiProviderInterface of a frontend.
+type GuiProviderFactory func() GuiProviderInterface
+
+//guiProviders contains the factories of the registered frontends, indexed by name.
+var guiProviders = make(map[string]GuiProviderFactory)
+
+//RegisterGuiProvider registers a frontend of the Agent, which can then be selected with the EnvLiqoGui env var.
+//Function MUST be called before GetGuiProvider (e.g. in an init() function) in order to be effective.
+func RegisterGuiProvider(name string, factory GuiProviderFactory) {
+	if factory == nil {
+		panic("attempted registration of nil GuiProviderFactory")
+	}
+	guiProviders[name] = factory
+}
+
+//GuiProviders returns the names of the registered frontends, sorted.
+func GuiProviders() []string {
+	names := make([]string, 0, len(guiProviders))
+	for name := range guiProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//GetGuiProvider returns the GuiProviderInterface singleton that provides the functions to interact with the
+//graphic server.
 //
-//If UseMockedGuiProvider() has been previously called, it returns a mocked guiProvider.
+//If UseMockedGuiProvider() has been previously called, it returns the GuiMock provider. Otherwise, the
+//frontend is selected by the EnvLiqoGui env var.
 func GetGuiProvider() GuiProviderInterface {
 	guiProviderOnce.Do(func() {
-		guiProviderInstance = &guiProvider{
-			mocked:      mockedGui,
-			eventTester: &EventTester{},
+		name := os.Getenv(EnvLiqoGui)
+		if mockedGui {
+			name = GuiMock
+		}
+		factory, present := guiProviders[name]
+		if !present {
+			factory = guiProviders[GuiTray]
 		}
+		guiProviderInstance = factory()
 	})
 	return guiProviderInstance
 }
 
 //GuiProviderInterface wraps the methods to interact with the OS graphic server and manage a tray icon with its menu.
+//
+//Each implementation (registered with RegisterGuiProvider) provides its own Item type: the Items passed to
+//AddSubMenuItem are always the ones previously returned by the same provider.
 type GuiProviderInterface interface {
 	//Run initializes the GUI and starts the event loop, then invokes the onReady callback. It blocks until
 	//Quit() is called. After Quit() call, it runs onExit() before exiting. It should be called before
@@ -79,128 +121,26 @@ type GuiProviderInterface interface {
 			Otherwise the graphical behavior of Item.Check() is demanded to internal implementation.
 	*/
 	AddSubMenuItem(parent Item, withCheckbox bool) Item
-	//Mocked returns whether the interaction with the OS graphic server is mocked.
+	//Mocked returns whether the provider is a test provider, which does not interact with the user.
 	Mocked() bool
-	//NewEventTester resets and return the EventTester. You can then call EventTester.Test() to start the testing
-	//mechanism for the events handled by the current Indicator instance. Read more on EventTester documentation.
-	NewEventTester() *EventTester
-	//GetEventTester returns current GuiProvider EventTester instance. Read more on EventTester documentation.
-	//
-	//If testing==true, the EventTester is currently registering the events handled by the Indicator instance in test mode.
-	GetEventTester() (eventTester *EventTester, testing bool)
-}
-
-/*EventTester is a WaitGroup-based data struct that enables testers to validate concurrent operations performed
-by the callback associated to an Indicator Listener (which reacts to specific events) or a MenuNode (which reacts when
-the correspondent graphic menu entry is clicked).
-
-During a test, after calling app-indicator.UseMockedGuiProvider(), you can call GetGuiProvider().NewEventTester() to
-create a new EventTester. After calling EventTester.Test(), an EventTester.Done() is called after the execution of the
-associated callback of a Listener or a MenuNode (if MenuNode.Connect() has been previously called).
-
-Inside a test, use EventTester.Add() and EventTester.Wait() to synchronize the execution of Listeners callbacks.
-
-	...
-	eventTester := GetGuiProvider.NewEventTester()
-	eventTester.Test()
-	//test changes after a single callback
-	eventTester.Add(1)
-	// trigger Listener-bind callback
-	// wait for callback to return
-	eventTester.Wait()
-	//perform checks on changes and continue
-*/
-type EventTester struct {
-	sync.WaitGroup
-	testing bool
-}
-
-func (e *EventTester) Test() {
-	e.testing = true
-}
-
-//A guiProvider provides the function to interact with the OS graphic server.
-//It can act as a mocked provider if UseMockedGuiProvider() is previously called.
-type guiProvider struct {
-	//if mocked == true, guiProvider acts a mocked provider
-	mocked      bool
-	eventTester *EventTester
-}
-
-func (g *guiProvider) Run(onReady func(), onExit func()) {
-	if !g.mocked {
-		systray.Run(onReady, onExit)
-	}
-}
-
-func (g *guiProvider) AddSeparator() {
-	if !g.mocked {
-		systray.AddSeparator()
-	}
-}
-
-func (g *guiProvider) Quit() {
-	if !g.mocked {
-		systray.Quit()
-	}
-}
-
-func (g *guiProvider) SetIcon(iconBytes []byte) {
-	if !g.mocked {
-		systray.SetIcon(iconBytes)
-	}
-}
-
-func (g *guiProvider) SetTitle(title string) {
-	if !g.mocked {
-		systray.SetTitle(title)
-	}
-}
-
-func (g *guiProvider) AddMenuItem(withCheckbox bool) Item {
-	if !g.mocked {
-		if withCheckbox {
-			return systray.AddMenuItemCheckbox("", "", false)
-		} else {
-			return systray.AddMenuItem("", "")
-		}
-	} else {
-		return &mockItem{
-			clickChan: make(chan struct{}, 2),
-		}
-	}
-}
-
-func (g *guiProvider) AddSubMenuItem(parent Item, withCheckbox bool) Item {
-	if parent == nil {
-		panic("invalid creation of child Item with nil parent")
-	}
-	if !g.mocked {
-		parentItem := parent.(*systray.MenuItem)
-		if withCheckbox {
-			return parentItem.AddSubMenuItemCheckbox("", "", false)
-		}
-		return parentItem.AddSubMenuItem("", "")
-	} else {
-		parentItem := parent.(*mockItem)
-		return parentItem.AddSubMenuItemCheckbox("", "", false)
-	}
-}
-
-func (g *guiProvider) Mocked() bool {
-	return g.mocked
-}
-
-func (g *guiProvider) NewEventTester() *EventTester {
-	g.eventTester = &EventTester{}
-	return g.eventTester
-}
-
-func (g *guiProvider) GetEventTester() (*EventTester, bool) {
-	if !g.mocked {
-		return g.eventTester, false
-	}
-	return g.eventTester, g.eventTester.testing
+	//Prompt asks the user a text input, proposing defaultValue. ok is false if the user cancels the request.
+	Prompt(title, message, defaultValue string) (input string, ok bool)
+	//Choose asks the user to choose one of items. ok is false if the user cancels the request.
+	Choose(title, message string, items []string) (choice string, ok bool)
+	//SelectFile asks the user the path of a file matching filter (e.g. "*.yaml *.yml").
+	//ok is false if the user cancels the request.
+	SelectFile(title, filter string) (path string, ok bool)
+	//Confirm asks the user a yes/no question, returning whether the answer is yes.
+	Confirm(title, message string) bool
+	//Warning displays a warning message, returning once the user has acknowledged it.
+	Warning(title, message string)
+	//Error displays an error message, returning once the user has acknowledged it.
+	Error(title, message string)
+	//Notify displays a notification without waiting for the user. iconPath is the path of the image displayed
+	//along with the notification (if supported).
+	Notify(title, message, iconPath string)
+	//Copy makes text available to be pasted by the user, e.g. copying it to the clipboard.
+	Copy(text string) error
 }
 
 //Item is an interface representing the actual item that gets pushed (and displayed) in the stack of the tray menu.
@@ -226,87 +166,6 @@ type Item interface {
 	//SetTooltip sets a tooltip for the Item displayed after a 'mouse hover' event.
 	//Currently, this is ineffective on Linux builds.
 	SetTooltip(tooltip string)
-}
-
-//mockItem implements a mock github.com/getlantern/systray/MenuItem
-type mockItem struct {
-	visible   bool
-	checked   bool
-	disabled  bool
-	title     string
-	tooltip   string
-	clickChan chan struct{}
-}
-
-func (i *mockItem) SetTooltip(tooltip string) {
-	i.tooltip = tooltip
-}
-
-//AddSubMenuItem adds an Item as a nested menu entry.
-//This method is the mocked counterpart of systray.MenuItem 's method.
-func (i *mockItem) AddSubMenuItem(title string, tooltip string) Item {
-	return &mockItem{
-		title:     title,
-		tooltip:   tooltip,
-		clickChan: make(chan struct{}, 2),
-	}
-}
-
-//AddSubMenuItemCheckbox adds an Item as a nested menu entry with a graphic checkbox.
-//This method is the mocked counterpart of systray.MenuItem 's method.
-func (i *mockItem) AddSubMenuItemCheckbox(title string, tooltip string, checked bool) Item {
-	return &mockItem{
-		checked:   checked,
-		title:     title,
-		tooltip:   tooltip,
-		clickChan: make(chan struct{}, 2),
-	}
-}
-
-func (i *mockItem) Check() {
-	i.checked = true
-}
-
-func (i *mockItem) Uncheck() {
-	i.checked = false
-}
-
-func (i *mockItem) Checked() bool {
-	return i.checked
-}
-
-func (i *mockItem) Enable() {
-	i.disabled = false
-}
-
-func (i *mockItem) Disable() {
-	i.disabled = true
-}
-
-func (i *mockItem) Disabled() bool {
-	return i.disabled
-}
-
-func (i *mockItem) Show() {
-	i.visible = true
-}
-
-func (i *mockItem) Hide() {
-	i.visible = false
-}
-
-func (i *mockItem) Visible() bool {
-	return i.visible
-}
-
-func (i *mockItem) SetTitle(title string) {
-	i.title = title
-}
-
-func (i *mockItem) Title() string {
-	return i.title
-}
-
-func (i *mockItem) ClickedCh() chan struct{} {
-	return i.clickChan
+	//ClickedCh returns the channel that receives the 'clicked' events of the Item.
+	ClickedCh() chan struct{}
 }
@@ -1,15 +1,20 @@
 package app_indicator
 
 import (
+	"context"
 	"fmt"
 	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
 	"github.com/liqotech/liqo-agent/internal/tray-agent/icon"
 	"sync"
+	"time"
 )
 
 //standard width of an item in the tray menu
 const menuWidth = 64
 
+//shutdownTimeout is the maximum time Quit waits for the Indicator goroutines to exit.
+const shutdownTimeout = 5 * time.Second
+
 //Icon represents the icon displayed in the tray bar
 type Icon int
 
@@ -29,6 +34,29 @@ const (
 	IconLiqoNil
 )
 
+//iconNames contains the names of the Icons, used for debug and test purposes.
+var iconNames = map[Icon]string{
+	IconLiqoMain:    "IconLiqoMain",
+	IconLiqoNoConn:  "IconLiqoNoConn",
+	IconLiqoOff:     "IconLiqoOff",
+	IconLiqoWarning: "IconLiqoWarning",
+	IconLiqoOrange:  "IconLiqoOrange",
+	IconLiqoGreen:   "IconLiqoGreen",
+	IconLiqoPurple:  "IconLiqoPurple",
+	IconLiqoRed:     "IconLiqoRed",
+	IconLiqoYellow:  "IconLiqoYellow",
+	IconLiqoCyan:    "IconLiqoCyan",
+	IconLiqoNil:     "IconLiqoNil",
+}
+
+//String returns the name of the Icon.
+func (ico Icon) String() string {
+	if name, present := iconNames[ico]; present {
+		return name
+	}
+	return fmt.Sprintf("Icon(%d)", int(ico))
+}
+
 //graphicResource defines a graphic interaction handled by the Indicator.
 type graphicResource int
 
@@ -73,16 +101,18 @@ type Indicator struct {
 	gProvider GuiProviderInterface
 	//data struct containing Liqo Status, used to control the menuStatusNode
 	status StatusInterface
-	//controller of all the application goroutines
-	quitChan chan struct{}
-	//if true, quitChan is closed and Indicator can gracefully exit
-	quitClosed bool
+	//ctx is the root context of all the Indicator goroutines, which is cancelled by Disconnect.
+	ctx context.Context
+	//cancel cancels ctx.
+	cancel context.CancelFunc
+	//workers tracks the Indicator goroutines, so that Shutdown can wait for them.
+	workers client.WorkerGroup
 	//data struct that controls Agent interaction with the cluster
 	agentCtrl *client.AgentController
-	//map of all the instantiated Listeners
-	listeners map[client.NotifyChannel]*Listener
-	//map of all the instantiated Timers
-	timers map[string]*Timer
+	//dispatcher handles the events of the Indicator: AgentController notifications and 'clicked' events.
+	dispatcher *dispatcher
+	//scheduler executes the callbacks of the Indicator Timers.
+	scheduler *Scheduler
 	//graphicResource is the map containing the mutex to protect access to the graphic resources handled by the Indicator
 	//(e.g. tray icon, tray label and desktop notifications).
 	graphicResource map[graphicResource]*sync.RWMutex
@@ -93,11 +123,9 @@ func GetIndicator() *Indicator {
 	if root == nil {
 		root = &Indicator{
 			quickMap:        make(map[string]*MenuNode),
-			quitChan:        make(chan struct{}),
-			listeners:       make(map[client.NotifyChannel]*Listener),
-			timers:          make(map[string]*Timer),
 			graphicResource: make(map[graphicResource]*sync.RWMutex),
 		}
+		root.ctx, root.cancel = context.WithCancel(context.Background())
 		root.graphicResource[resourceIcon] = &sync.RWMutex{}
 		root.graphicResource[resourceLabel] = &sync.RWMutex{}
 		root.graphicResource[resourceDesktop] = &sync.RWMutex{}
@@ -113,6 +141,25 @@ func GetIndicator() *Indicator {
 		root.RefreshStatus()
 		client.LoadLocalConfig()
 		root.agentCtrl = client.GetAgentController()
+		i := root
+		root.dispatcher = newDispatcher(root.agentCtrl.Events(), root.gProvider.Mocked(), func() bool {
+			return i.Status().Running() == StatRunOn
+		}, &root.workers)
+		//in test mode, events are dispatched only on DispatchEvents calls
+		if !root.dispatcher.synchronous {
+			root.workers.Go(func() {
+				i.dispatcher.run(i.ctx.Done())
+			})
+		}
+		//the Timers callbacks are handled by the dispatcher, like the 'clicked' events
+		root.scheduler = NewScheduler(indicatorClock, func(key string, run func()) {
+			i.workers.Go(func() {
+				i.dispatcher.click(key, func(context.Context) { run() }, i.ctx.Done())
+			})
+		})
+		root.workers.Go(func() {
+			i.scheduler.Run(i.ctx.Done())
+		})
 		if !root.agentCtrl.Connected() {
 			root.ShowErrorNoConnection()
 		} else if !root.agentCtrl.ValidConfiguration() {
@@ -275,23 +322,40 @@ func (i *Indicator) RefreshLabel() {
 
 //--------------
 
-//Quit stops the indicator execution.
+//Quit stops the indicator execution, gracefully shutting down the Indicator for at most shutdownTimeout.
 func (i *Indicator) Quit() {
+	i.QuitContext(context.Background())
+}
+
+//QuitContext is like Quit. An event handler calling it must provide its own context (see
+//(*MenuNode).ConnectContext), so that the shutdown does not wait for the handler itself.
+func (i *Indicator) QuitContext(ctx context.Context) {
 	if i != nil {
-		i.Disconnect()
-		if i.agentCtrl.Connected() {
-			i.agentCtrl.StopCaches()
-		}
+		ctx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+		defer cancel()
+		_ = i.Shutdown(ctx)
 	}
 	i.gProvider.Quit()
 }
 
+//Shutdown disconnects the Indicator and shuts down its AgentController. It then waits for all the Indicator
+//goroutines (event dispatching, event handlers and Timers) to exit, until ctx expires. The pending events
+//are discarded.
+//
+//If Shutdown is called by an event handler, ctx must derive from the context of the handler (see
+//(*MenuNode).ConnectContext): all the goroutines except the one of the calling handler are awaited.
+func (i *Indicator) Shutdown(ctx context.Context) error {
+	detachHandler(ctx)
+	i.Disconnect()
+	if err := i.agentCtrl.Shutdown(ctx); err != nil {
+		return err
+	}
+	return i.workers.Wait(ctx)
+}
+
 //Disconnect exits all the event handlers associated with any Indicator MenuNode via the Connect() method.
 func (i *Indicator) Disconnect() {
-	if !i.quitClosed {
-		close(i.quitChan)
-		i.quitClosed = true
-	}
+	i.cancel()
 }
 
 //AgentCtrl returns the Indicator AgentController that interacts with the cluster.
@@ -1,9 +1,14 @@
 package app_indicator
 
 import (
+	"context"
+	"fmt"
 	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
 	"github.com/stretchr/testify/assert"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 // test Indicator startup configuration and basic methods
@@ -17,8 +22,8 @@ func TestGetIndicator(t *testing.T) {
 	assert.NotNil(t, i.menu, "root MenuNode not instantiated")
 	assert.NotNil(t, i.quickMap, "root quickMap not instantiated")
 	assert.NotNil(t, i.Config(), "root config obj not instantiated")
-	assert.NotNil(t, i.quitChan, "root quitChan not instantiated")
-	assert.NotNil(t, i.listeners, "root listeners not instantiated")
+	assert.NotNil(t, i.ctx, "root context not instantiated")
+	assert.NotNil(t, i.dispatcher, "root dispatcher not instantiated")
 	if assert.NotNil(t, i.AgentCtrl(), "root agentCtrl obj not instantiated") {
 		if i.agentCtrl.Connected() {
 			assert.Equal(t, IconLiqoMain, i.Icon())
@@ -116,7 +121,7 @@ func TestIndicatorRoutine(t *testing.T) {
 	o.SetIsVisible(false)
 	//test Quit() and Disconnect
 	i.Quit()
-	assert.True(t, i.quitClosed, "Indicator quitChan not closed at Quit()")
+	assert.Error(t, i.ctx.Err(), "Indicator context not cancelled at Quit()")
 }
 
 func TestMenuNode_Connect(t *testing.T) {
@@ -125,8 +130,6 @@ func TestMenuNode_Connect(t *testing.T) {
 	DestroyMockedIndicator()
 	client.DestroyMockedAgentController()
 	i := GetIndicator()
-	et := GetGuiProvider().NewEventTester()
-	et.Test()
 	flagTest := false
 	o := i.AddQuick("test flag", "test", func(args ...interface{}) {
 		fp := args[0].(*bool)
@@ -134,9 +137,166 @@ func TestMenuNode_Connect(t *testing.T) {
 	}, &flagTest)
 	ch := o.Channel()
 	assert.NotNil(t, ch)
-	et.Add(1)
 	ch <- struct{}{}
-	et.Wait()
+	assert.NoError(t, i.DispatchEvents(1, time.Second), "'clicked' event not dispatched")
 	assert.True(t, flagTest, "Connect() callback not executed")
 	i.Quit()
 }
+
+func TestMenuNode_EventKey(t *testing.T) {
+	UseMockedGuiProvider()
+	client.UseMockedAgentController()
+	DestroyMockedIndicator()
+	client.DestroyMockedAgentController()
+	i := GetIndicator()
+	defer i.Quit()
+	q := i.AddQuick("peers", "peers", nil)
+	peer := q.UseListChild("peer", "a")
+	cmd := peer.UseListChild("command", "cmd")
+	assert.Equal(t, fmt.Sprintf("menu/%p", cmd), cmd.EventKey(), "wrong default key")
+	//the descendants of a node share its key
+	peer.SetEventKey(client.PeerEventKey("a"))
+	assert.Equal(t, client.PeerEventKey("a"), cmd.EventKey(), "key not inherited")
+	assert.Equal(t, fmt.Sprintf("menu/%p", q), q.EventKey(), "key inherited by the parent")
+}
+
+//runningGoroutines returns the number of running goroutines, except the ones of the fake informers of the liqo
+//crdClient, whose watch loop is never stopped.
+func runningGoroutines() int {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	count := 0
+	for _, g := range strings.Split(string(buf), "\n\n") {
+		if !strings.Contains(g, "crdClient.(*fakeInformer)") {
+			count++
+		}
+	}
+	return count
+}
+
+//test that Shutdown waits for all the Indicator goroutines, so that start/stop cycles do not leak them.
+func TestIndicator_Shutdown(t *testing.T) {
+	testShutdownCycles(t, false)
+}
+
+//test that start/stop cycles do not leak goroutines also when the events are dispatched concurrently, as in
+//production, so that the dispatcher and its workers are involved.
+func TestIndicator_ShutdownConcurrent(t *testing.T) {
+	testShutdownCycles(t, true)
+}
+
+//testShutdownCycles creates and shuts down an Indicator several times, checking that no goroutine is leaked.
+//If concurrent is true, the dispatcher of the mocked Indicator is started as in production and the cycle waits
+//for some events to be handled by its workers.
+//
+//The goroutines of the fake informers of the crdClient library are not counted: they ignore the stop channel
+//of the informer, so they never exit.
+func testShutdownCycles(t *testing.T, concurrent bool) {
+	UseMockedGuiProvider()
+	client.UseMockedAgentController()
+	DestroyMockedIndicator()
+	client.DestroyMockedAgentController()
+	baseline := runningGoroutines()
+	for cycle := 0; cycle < 5; cycle++ {
+		DestroyMockedIndicator()
+		client.DestroyMockedAgentController()
+		i := GetIndicator()
+		if concurrent {
+			i.workers.Go(func() {
+				i.dispatcher.run(i.ctx.Done())
+			})
+		}
+		i.AddQuick("test", "test", func(args ...interface{}) {})
+		assert.NoError(t, i.StartTimer("test", time.Millisecond, func(args ...interface{}) {}))
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if concurrent {
+			assert.Eventually(t, func() bool {
+				return i.DispatcherStats().Handled > 0
+			}, 5*time.Second, time.Millisecond, "no event handled by the dispatcher")
+			//Shutdown waits for the handlers still running
+			release := startBusyHandler(t, i)
+			done := make(chan error, 1)
+			go func() {
+				done <- i.Shutdown(ctx)
+			}()
+			assert.Never(t, func() bool {
+				return len(done) > 0
+			}, 50*time.Millisecond, time.Millisecond, "Shutdown returned while a handler was running")
+			close(release)
+			assert.NoError(t, <-done, "Indicator goroutines still running after Shutdown")
+		} else {
+			assert.NoError(t, i.Shutdown(ctx), "Indicator goroutines still running after Shutdown")
+		}
+		cancel()
+		assert.Error(t, i.AgentCtrl().Context().Err(), "AgentController context not cancelled")
+	}
+	DestroyMockedIndicator()
+	client.DestroyMockedAgentController()
+	//the goroutines exiting after Shutdown are awaited in the test goroutine, since the condition of
+	//assert.Eventually runs in a goroutine of its own
+	deadline := time.Now().Add(5 * time.Second)
+	for runningGoroutines() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runningGoroutines(), baseline, "goroutines leaked after start/stop cycles")
+}
+
+//startBusyHandler makes the dispatcher of the Indicator execute a handler that runs until the returned channel
+//is closed.
+func startBusyHandler(t *testing.T, i *Indicator) chan struct{} {
+	running := make(chan struct{})
+	release := make(chan struct{})
+	q := i.AddQuick("busy", "busy", nil)
+	q.ConnectContext(true, func(context.Context, ...interface{}) {
+		close(running)
+		<-release
+	})
+	q.Channel() <- struct{}{}
+	select {
+	case <-running:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler not executed")
+	}
+	return release
+}
+
+//test that an event handler can shut down the Indicator, waiting for all the goroutines except itself.
+func TestIndicator_ShutdownFromHandler(t *testing.T) {
+	UseMockedGuiProvider()
+	client.UseMockedAgentController()
+	DestroyMockedIndicator()
+	client.DestroyMockedAgentController()
+	defer client.DestroyMockedAgentController()
+	defer DestroyMockedIndicator()
+	i := GetIndicator()
+	i.workers.Go(func() {
+		i.dispatcher.run(i.ctx.Done())
+	})
+	release := startBusyHandler(t, i)
+	done := make(chan error, 1)
+	q := i.AddQuick("quit", "quit", nil)
+	q.ConnectContext(true, func(ctx context.Context, _ ...interface{}) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		done <- i.Shutdown(ctx)
+	})
+	q.Channel() <- struct{}{}
+	//the shutdown waits for the other handlers, but not for the calling one
+	assert.Never(t, func() bool {
+		return len(done) > 0
+	}, 50*time.Millisecond, time.Millisecond, "Shutdown returned while a handler was running")
+	close(release)
+	select {
+	case err := <-done:
+		assert.NoError(t, err, "Shutdown waited for the calling handler")
+	case <-time.After(10 * time.Second):
+		t.Fatal("Shutdown not returned")
+	}
+}
@@ -0,0 +1,171 @@
+package app_indicator
+
+import (
+	"sync"
+)
+
+//itemTree stores the menu of a GuiProviderInterface that renders the menu by itself (e.g. tuiProvider),
+//instead of relying on the OS graphic server.
+type itemTree struct {
+	//title is the label shown next to the indicator.
+	title string
+	//items are the level-0 entries of the menu.
+	items []*treeItem
+	//index contains all the entries of the menu, indexed by id.
+	index map[int]*treeItem
+	//changed is called after each change of the menu, without holding the mutex.
+	changed func()
+	mutex   sync.RWMutex
+}
+
+//newItemTree creates an itemTree calling changed after each change of the menu.
+func newItemTree(changed func()) *itemTree {
+	return &itemTree{
+		index:   make(map[int]*treeItem),
+		changed: changed,
+	}
+}
+
+//setTitle sets the label shown next to the indicator.
+func (t *itemTree) setTitle(title string) {
+	t.mutex.Lock()
+	t.title = title
+	t.mutex.Unlock()
+	t.changed()
+}
+
+//addSeparator adds a separator bar to the level-0 entries of the menu.
+func (t *itemTree) addSeparator() {
+	t.mutex.Lock()
+	item := &treeItem{tree: t, id: len(t.index), separator: true, visible: true}
+	t.index[item.id] = item
+	t.items = append(t.items, item)
+	t.mutex.Unlock()
+	t.changed()
+}
+
+//addItem adds an entry to the menu. If parent is nil, the entry is added to the level-0 ones.
+func (t *itemTree) addItem(parent *treeItem, withCheckbox bool) *treeItem {
+	t.mutex.Lock()
+	item := &treeItem{
+		tree:      t,
+		id:        len(t.index),
+		parent:    parent,
+		checkbox:  withCheckbox,
+		clickChan: make(chan struct{}, 2),
+	}
+	t.index[item.id] = item
+	if parent == nil {
+		t.items = append(t.items, item)
+	} else {
+		parent.children = append(parent.children, item)
+	}
+	t.mutex.Unlock()
+	t.changed()
+	return item
+}
+
+//click sends a 'clicked' event to the entry with the given id, if it can be clicked.
+func (t *itemTree) click(id int) bool {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	item, present := t.index[id]
+	if !present || !item.visible || !item.selectable() || item.hasVisibleChildren() {
+		return false
+	}
+	//the click is dropped if the previous ones have not been handled yet
+	select {
+	case item.clickChan <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+//treeItem is the Item of an itemTree.
+type treeItem struct {
+	tree      *itemTree
+	id        int
+	parent    *treeItem
+	children  []*treeItem
+	separator bool
+	checkbox  bool
+	visible   bool
+	checked   bool
+	disabled  bool
+	title     string
+	tooltip   string
+	clickChan chan struct{}
+}
+
+//selectable returns whether the treeItem can be selected by the user. The caller must hold the itemTree mutex.
+func (i *treeItem) selectable() bool {
+	return !i.separator && (!i.disabled || i.hasVisibleChildren())
+}
+
+//hasVisibleChildren returns whether the treeItem has a submenu to display.
+//The caller must hold the itemTree mutex.
+func (i *treeItem) hasVisibleChildren() bool {
+	for _, child := range i.children {
+		if child.visible {
+			return true
+		}
+	}
+	return false
+}
+
+//update applies a change to the treeItem, then notifies the itemTree.
+func (i *treeItem) update(change func()) {
+	i.tree.mutex.Lock()
+	change()
+	i.tree.mutex.Unlock()
+	i.tree.changed()
+}
+
+func (i *treeItem) Check() {
+	i.update(func() { i.checked = true })
+}
+
+func (i *treeItem) Uncheck() {
+	i.update(func() { i.checked = false })
+}
+
+func (i *treeItem) Checked() bool {
+	i.tree.mutex.RLock()
+	defer i.tree.mutex.RUnlock()
+	return i.checked
+}
+
+func (i *treeItem) Enable() {
+	i.update(func() { i.disabled = false })
+}
+
+func (i *treeItem) Disable() {
+	i.update(func() { i.disabled = true })
+}
+
+func (i *treeItem) Disabled() bool {
+	i.tree.mutex.RLock()
+	defer i.tree.mutex.RUnlock()
+	return i.disabled
+}
+
+func (i *treeItem) Show() {
+	i.update(func() { i.visible = true })
+}
+
+func (i *treeItem) Hide() {
+	i.update(func() { i.visible = false })
+}
+
+func (i *treeItem) SetTitle(title string) {
+	i.update(func() { i.title = title })
+}
+
+func (i *treeItem) SetTooltip(tooltip string) {
+	i.update(func() { i.tooltip = tooltip })
+}
+
+//ClickedCh returns the channel that receives the 'clicked' events of the treeItem.
+func (i *treeItem) ClickedCh() chan struct{} {
+	return i.clickChan
+}
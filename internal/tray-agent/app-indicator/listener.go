@@ -8,55 +8,27 @@ import (
 type Listener struct {
 	//Tag specifies the type of notification channel on which it listens to
 	Tag client.NotifyChannel
-	//StopChan lets control the Listener event loop
-	StopChan chan struct{}
-	//NotifyChan is the client.NotifyChannel on which it listens to
-	NotifyChan chan client.NotifyDataGeneric
-}
-
-//newListener returns a new Listener.
-func newListener(tag client.NotifyChannel) *Listener {
-	ch := client.GetAgentController().NotifyChannel(tag)
-	if ch == nil {
-		panic("Indicator tried to listen to non existing NotifyChannel")
-	}
-	l := Listener{StopChan: make(chan struct{}, 1), Tag: tag, NotifyChan: ch}
-	return &l
+	//callback is executed when a notification arrives on the channel
+	callback func(data client.NotifyDataGeneric, args ...interface{})
+	//args are the additional arguments of callback
+	args []interface{}
 }
 
 //Listener returns the registered Listener for the specified NotifyChannel. If such Listener does not exist,
 //present == false.
 func (i *Indicator) Listener(tag client.NotifyChannel) (listener *Listener, present bool) {
-	listener, present = i.listeners[tag]
-	return
+	return i.dispatcher.listener(tag)
 }
 
-//Listen starts a Listener for a specific channel, executing callback when a notification arrives.
+//Listen registers a Listener for a specific channel, executing callback when a notification arrives.
+//Notifications regarding the same resource (e.g. the same peer) are handled in the order they are sent
+//by the AgentController.
 func (i *Indicator) Listen(tag client.NotifyChannel, callback func(data client.NotifyDataGeneric, args ...interface{}), args ...interface{}) {
-	l := newListener(tag)
-	i.listeners[tag] = l
-	go func() {
-		for {
-			select {
-			//exec handler
-			case data, open := <-l.NotifyChan:
-				/*While the Agent is OFF, the callback is not executed, in order not to update information
-				on status and tray menu or trigger notifications.*/
-				if open && i.Status().Running() == StatRunOn {
-					callback(data, args...)
-					//signal callback execution in test mode
-					if et, testing := GetGuiProvider().GetEventTester(); testing {
-						et.Done()
-					}
-				}
-				//closing application
-			case <-i.quitChan:
-				return
-				//closing single listener. Channel controlled by Indicator
-			case <-l.StopChan:
-				delete(i.listeners, tag)
-				return
-			}
-		}
-	}()
+	i.dispatcher.listen(&Listener{Tag: tag, callback: callback, args: args})
+}
+
+//StopListener removes the Listener for the specified NotifyChannel. Further notifications on that channel
+//are discarded.
+func (i *Indicator) StopListener(tag client.NotifyChannel) {
+	i.dispatcher.stopListener(tag)
 }
@@ -1,7 +1,8 @@
 package app_indicator
 
 import (
-	"github.com/getlantern/systray"
+	"context"
+	"fmt"
 	"github.com/ozgio/strutil"
 	"sync"
 )
@@ -104,6 +105,8 @@ type MenuNode struct {
 	//text content of the menu item. This redundancy of information is due to the fact Item does not provide getters
 	//for the data.
 	title string
+	//eventKey is the key of the 'clicked' events of the node and of its descendants (see SetEventKey).
+	eventKey string
 	//protection for concurrent access to MenuNode attributes.
 	sync.RWMutex
 }
@@ -157,52 +160,72 @@ func newMenuNode(nodeType NodeType, withCheckbox bool, parent *MenuNode) *MenuNo
 
 //Channel returns the ClickedChan chan of the MenuNode which reacts to the 'clicked' event
 func (n *MenuNode) Channel() chan struct{} {
-	switch n.item.(type) {
-	case *systray.MenuItem:
-		return n.item.(*systray.MenuItem).ClickedCh
-	case *mockItem:
-		return n.item.(*mockItem).ClickedCh()
-	default:
-		return nil
-	}
+	return n.item.ClickedCh()
 }
 
 //Connect instantiates a listener for the 'clicked' event of the node.
 //If once == true, the event handler is at most executed once.
 func (n *MenuNode) Connect(once bool, callback func(args ...interface{}), args ...interface{}) {
+	n.ConnectContext(once, func(_ context.Context, args ...interface{}) {
+		callback(args...)
+	}, args...)
+}
+
+//ConnectContext is like Connect, but the callback also receives the context of the event handler, which has to be
+//passed to (*Indicator).Shutdown when it is called by the callback.
+func (n *MenuNode) ConnectContext(once bool, callback func(ctx context.Context, args ...interface{}),
+	args ...interface{}) {
 	n.Lock()
 	if n.stopped {
 		n.stopChan = make(chan struct{})
 		n.stopped = false
 	}
+	//the handler waits on its own stopChan, which is replaced if the node is connected again
+	stopChan := n.stopChan
 	n.Unlock()
-	var clickCh chan struct{}
-	switch n.item.(type) {
-	case *systray.MenuItem:
-		clickCh = n.item.(*systray.MenuItem).ClickedCh
-	case *mockItem:
-		clickCh = n.item.(*mockItem).ClickedCh()
-	default:
-		clickCh = make(chan struct{}, 2)
-	}
-	go func() {
+	clickCh := n.item.ClickedCh()
+	d := root.dispatcher
+	quit := root.ctx.Done()
+	root.workers.Go(func() {
 		for {
 			select {
+			//the callback is executed by the dispatcher, so that it never overlaps with a previous execution
 			case <-clickCh:
-				callback(args...)
-				if et, testing := GetGuiProvider().GetEventTester(); testing {
-					et.Done()
-				}
-				if once {
+				if !d.click(n.EventKey(), func(ctx context.Context) { callback(ctx, args...) }, quit) || once {
 					return
 				}
-			case <-n.stopChan:
+			case <-stopChan:
 				return
-			case <-root.quitChan:
+			case <-quit:
 				return
 			}
 		}
-	}()
+	})
+}
+
+//SetEventKey sets the key of the 'clicked' events of the MenuNode and of its descendants without a key of their
+//own, so that their callbacks are handled in order with the other events sharing the key
+//(e.g. client.PeerEventKey for the entries of a peer).
+func (n *MenuNode) SetEventKey(key string) {
+	n.Lock()
+	defer n.Unlock()
+	n.eventKey = key
+}
+
+//EventKey returns the key of the 'clicked' events of the MenuNode: the one set on the node or on its closest
+//ancestor or, if none, a key of the node alone.
+func (n *MenuNode) EventKey() string {
+	for node := n; ; node = node.parent {
+		node.RLock()
+		key := node.eventKey
+		node.RUnlock()
+		if key != "" {
+			return key
+		}
+		if node.parent == nil || node.parent == node {
+			return fmt.Sprintf("menu/%p", n)
+		}
+	}
 }
 
 //Disconnect removes the event handler (if any) from the MenuNode.
@@ -239,6 +262,17 @@ func (n *MenuNode) UseListChild(title string, tag string) *MenuNode {
 	return n.nodeList.useNode(title, tag)
 }
 
+//UseCheckboxListChildren provides the LIST MenuNode children of n with a graphic checkbox.
+//It has to be called before the first call to UseListChild in order to be effective on all of them.
+func (n *MenuNode) UseCheckboxListChildren() {
+	n.Lock()
+	defer n.Unlock()
+	if n.nodeList == nil {
+		n.nodeList = newNodeList(n)
+	}
+	n.nodeList.withCheckbox = true
+}
+
 //FreeListChild marks a LIST MenuNode and its nested children as unused, graphically removing them
 //from the submenu of MenuNode n in the tray menu. This is a no-op in case of tagged child missing.
 func (n *MenuNode) FreeListChild(tag string) {
@@ -281,6 +315,16 @@ func (n *MenuNode) ListChildrenLen() int {
 	return n.nodeList.usedNodeLen()
 }
 
+//ListChildren returns the LIST MenuNode currently in use, in no particular order.
+func (n *MenuNode) ListChildren() []*MenuNode {
+	n.RLock()
+	defer n.RUnlock()
+	if n.nodeList == nil {
+		return nil
+	}
+	return n.nodeList.usedNodeList()
+}
+
 //------ OPTION ------
 
 //AddOption adds an OPTION to the MenuNode as a choice for the submenu.
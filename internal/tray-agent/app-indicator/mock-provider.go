@@ -0,0 +1,349 @@
+package app_indicator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+//GuiMock is the name of the test frontend, which does not interact with any graphic server.
+//It is selected by UseMockedGuiProvider.
+const GuiMock = "mock"
+
+func init() {
+	RegisterGuiProvider(GuiMock, func() GuiProviderInterface {
+		return NewMockGuiProvider()
+	})
+}
+
+//MockGuiProvider is a GuiProviderInterface that does not interact with any graphic server. It records the state of
+//the menu, the label and the icon, so that tests can inspect them.
+type MockGuiProvider struct {
+	//title is the label next to the tray icon.
+	title string
+	//icon is the tray icon.
+	icon []byte
+	//items are the level-0 entries of the menu.
+	items []*mockItem
+	//notifications are the desktop banners displayed by the Indicator, in the 'title: message' format.
+	notifications []string
+	//clipboard is the last text copied by the Indicator.
+	clipboard string
+	//quit specifies whether Quit() has been called.
+	quit  bool
+	mutex sync.RWMutex
+}
+
+//NewMockGuiProvider creates a MockGuiProvider.
+func NewMockGuiProvider() *MockGuiProvider {
+	return &MockGuiProvider{}
+}
+
+//Run is a no-op: the test is in charge of the Indicator execution.
+func (g *MockGuiProvider) Run(_ func(), _ func()) {}
+
+func (g *MockGuiProvider) Quit() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.quit = true
+}
+
+func (g *MockGuiProvider) AddSeparator() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.items = append(g.items, &mockItem{separator: true, visible: true})
+}
+
+func (g *MockGuiProvider) SetIcon(iconBytes []byte) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.icon = iconBytes
+}
+
+func (g *MockGuiProvider) SetTitle(title string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.title = title
+}
+
+func (g *MockGuiProvider) AddMenuItem(withCheckbox bool) Item {
+	item := newMockItem(withCheckbox)
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.items = append(g.items, item)
+	return item
+}
+
+func (g *MockGuiProvider) AddSubMenuItem(parent Item, withCheckbox bool) Item {
+	if parent == nil {
+		panic("invalid creation of child Item with nil parent")
+	}
+	parentItem := parent.(*mockItem)
+	item := newMockItem(withCheckbox)
+	parentItem.Lock()
+	defer parentItem.Unlock()
+	parentItem.children = append(parentItem.children, item)
+	return item
+}
+
+func (g *MockGuiProvider) Mocked() bool {
+	return true
+}
+
+//reset removes the menu, the label and the icon recorded by the MockGuiProvider.
+func (g *MockGuiProvider) reset() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.title = ""
+	g.icon = nil
+	g.items = nil
+	g.notifications = nil
+	g.clipboard = ""
+	g.quit = false
+}
+
+//Prompt cancels the request, since there is no user to answer it.
+func (g *MockGuiProvider) Prompt(_, _, _ string) (string, bool) {
+	return "", false
+}
+
+//Choose cancels the request, since there is no user to answer it.
+func (g *MockGuiProvider) Choose(_, _ string, _ []string) (string, bool) {
+	return "", false
+}
+
+//SelectFile cancels the request, since there is no user to answer it.
+func (g *MockGuiProvider) SelectFile(_, _ string) (string, bool) {
+	return "", false
+}
+
+//Confirm answers no, since there is no user to answer the question.
+func (g *MockGuiProvider) Confirm(_, _ string) bool {
+	return false
+}
+
+func (g *MockGuiProvider) Warning(_, _ string) {}
+
+func (g *MockGuiProvider) Error(_, _ string) {}
+
+//Notify records a desktop banner displayed by the Indicator.
+func (g *MockGuiProvider) Notify(title, message, _ string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.notifications = append(g.notifications, title+": "+message)
+}
+
+func (g *MockGuiProvider) Copy(text string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.clipboard = text
+	return nil
+}
+
+//------ INSPECTION ------
+
+//Title returns the label currently displayed next to the tray icon.
+func (g *MockGuiProvider) Title() string {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.title
+}
+
+//Icon returns the tray icon currently displayed.
+func (g *MockGuiProvider) Icon() []byte {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.icon
+}
+
+//Notifications returns the desktop banners displayed by the Indicator since the last call, in the
+//'title: message' format.
+func (g *MockGuiProvider) Notifications() []string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	notifications := g.notifications
+	g.notifications = nil
+	return notifications
+}
+
+//Clipboard returns the last text copied by the Indicator.
+func (g *MockGuiProvider) Clipboard() string {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.clipboard
+}
+
+//Quitted returns whether Quit() has been called.
+func (g *MockGuiProvider) Quitted() bool {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.quit
+}
+
+/*DumpMenu returns a snapshot of the menu tree. Submenu entries (including each line of multi-line titles) are
+indented by two spaces, separators are displayed as '----' and the state of an entry, if different from the default
+one, is listed after its title:
+
+	❱ Start LiqoAgent
+	⬢ Shared resources [hidden]
+	  Share resources with peers [checkbox, checked, disabled]
+	----
+*/
+func (g *MockGuiProvider) DumpMenu() string {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	var sb strings.Builder
+	for _, item := range g.items {
+		item.dump(&sb, 0)
+	}
+	return sb.String()
+}
+
+/*Snapshot returns a text snapshot of the Indicator as displayed by its MockGuiProvider: the icon, the label
+and the whole menu tree (see MockGuiProvider.DumpMenu).
+
+	icon: IconLiqoMain
+	label: (ON)
+	❱ Start LiqoAgent
+	...
+
+It returns an empty string if the Indicator does not use a MockGuiProvider.*/
+func (i *Indicator) Snapshot() string {
+	g, ok := i.gProvider.(*MockGuiProvider)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("icon: %s\nlabel: %s\n%s", i.Icon(), g.Title(), g.DumpMenu())
+}
+
+//mockItem is the Item of a MockGuiProvider.
+type mockItem struct {
+	visible   bool
+	checked   bool
+	disabled  bool
+	checkbox  bool
+	separator bool
+	title     string
+	tooltip   string
+	children  []*mockItem
+	clickChan chan struct{}
+	sync.RWMutex
+}
+
+//newMockItem creates a mockItem.
+func newMockItem(withCheckbox bool) *mockItem {
+	return &mockItem{
+		checkbox:  withCheckbox,
+		clickChan: make(chan struct{}, 2),
+	}
+}
+
+//dump writes the state of the mockItem and of its children in sb.
+func (i *mockItem) dump(sb *strings.Builder, depth int) {
+	i.RLock()
+	defer i.RUnlock()
+	indent := strings.Repeat("  ", depth)
+	sb.WriteString(indent)
+	if i.separator {
+		sb.WriteString("----\n")
+		return
+	}
+	//multi-line titles keep the indentation of their entry
+	sb.WriteString(strings.ReplaceAll(i.title, "\n", "\n"+indent))
+	var flags []string
+	if !i.visible {
+		flags = append(flags, "hidden")
+	}
+	if i.checkbox {
+		flags = append(flags, "checkbox")
+	}
+	if i.checked {
+		flags = append(flags, "checked")
+	}
+	if i.disabled {
+		flags = append(flags, "disabled")
+	}
+	if len(flags) > 0 {
+		sb.WriteString(" [" + strings.Join(flags, ", ") + "]")
+	}
+	sb.WriteString("\n")
+	for _, child := range i.children {
+		child.dump(sb, depth+1)
+	}
+}
+
+func (i *mockItem) SetTooltip(tooltip string) {
+	i.Lock()
+	defer i.Unlock()
+	i.tooltip = tooltip
+}
+
+func (i *mockItem) Check() {
+	i.Lock()
+	defer i.Unlock()
+	i.checked = true
+}
+
+func (i *mockItem) Uncheck() {
+	i.Lock()
+	defer i.Unlock()
+	i.checked = false
+}
+
+func (i *mockItem) Checked() bool {
+	i.RLock()
+	defer i.RUnlock()
+	return i.checked
+}
+
+func (i *mockItem) Enable() {
+	i.Lock()
+	defer i.Unlock()
+	i.disabled = false
+}
+
+func (i *mockItem) Disable() {
+	i.Lock()
+	defer i.Unlock()
+	i.disabled = true
+}
+
+func (i *mockItem) Disabled() bool {
+	i.RLock()
+	defer i.RUnlock()
+	return i.disabled
+}
+
+func (i *mockItem) Show() {
+	i.Lock()
+	defer i.Unlock()
+	i.visible = true
+}
+
+func (i *mockItem) Hide() {
+	i.Lock()
+	defer i.Unlock()
+	i.visible = false
+}
+
+func (i *mockItem) Visible() bool {
+	i.RLock()
+	defer i.RUnlock()
+	return i.visible
+}
+
+func (i *mockItem) SetTitle(title string) {
+	i.Lock()
+	defer i.Unlock()
+	i.title = title
+}
+
+func (i *mockItem) Title() string {
+	i.RLock()
+	defer i.RUnlock()
+	return i.title
+}
+
+func (i *mockItem) ClickedCh() chan struct{} {
+	return i.clickChan
+}
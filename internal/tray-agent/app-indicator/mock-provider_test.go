@@ -0,0 +1,50 @@
+package app_indicator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGuiProviderRegistry(t *testing.T) {
+	assert.Equal(t, []string{GuiMock, GuiTray, GuiTerminal, GuiWeb}, GuiProviders(),
+		"frontends not registered")
+	assert.Panics(t, func() {
+		RegisterGuiProvider("nil", nil)
+	}, "nil factory registered")
+	UseMockedGuiProvider()
+	_, ok := GetGuiProvider().(*MockGuiProvider)
+	assert.True(t, ok, "mocked GuiProvider not selected")
+}
+
+func TestMockGuiProvider_DumpMenu(t *testing.T) {
+	g := NewMockGuiProvider()
+	g.SetTitle("(ON)")
+	quick := g.AddMenuItem(false)
+	quick.SetTitle("Quick")
+	quick.Show()
+	action := g.AddMenuItem(false)
+	action.SetTitle("Action")
+	action.Show()
+	option := g.AddSubMenuItem(action, true)
+	option.SetTitle("Option")
+	option.Check()
+	option.Disable()
+	option.Show()
+	info := g.AddSubMenuItem(action, false)
+	info.SetTitle("CPU: 1\nRAM: 2")
+	info.Show()
+	g.AddSeparator()
+	hidden := g.AddMenuItem(false)
+	hidden.SetTitle("Hidden")
+	assert.Equal(t, "(ON)", g.Title())
+	assert.Equal(t, "Quick\n"+
+		"Action\n"+
+		"  Option [checkbox, checked, disabled]\n"+
+		"  CPU: 1\n"+
+		"  RAM: 2\n"+
+		"----\n"+
+		"Hidden [hidden]\n", g.DumpMenu(), "wrong menu snapshot")
+	assert.False(t, g.Quitted())
+	g.Quit()
+	assert.True(t, g.Quitted(), "Quit() not recorded")
+}
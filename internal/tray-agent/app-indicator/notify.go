@@ -3,8 +3,6 @@ package app_indicator
 import (
 	"fmt"
 	"github.com/agrison/go-commons-lang/stringUtils"
-	bip "github.com/gen2brain/beeep"
-	"github.com/gen2brain/dlgs"
 	"github.com/ozgio/strutil"
 	"path/filepath"
 	"strconv"
@@ -95,8 +93,9 @@ func (i *Indicator) Notify(title string, message string, notifyIcon NotifyIcon,
 			/*The golang guidelines suggests error messages should not start with a capitalized letter.
 			Therefore, since Notify sometimes receives an error as 'message', the Capitalize() function
 			overcomes this problem, correctly displaying the string to the user.*/
-			_ = bip.Notify(title, stringUtils.Capitalize(message), filepath.Join(i.config.notifyIconPath, icoName))
+			message = stringUtils.Capitalize(message)
 		}
+		i.gProvider.Notify(title, message, filepath.Join(i.config.notifyIconPath, icoName))
 	default:
 		return
 	}
@@ -173,14 +172,110 @@ func (i *Indicator) NotifyPeering(direction PeeringType, event NotifyPeeringEven
 	i.Notify(strings.Join(header, " "), strings.Join(body, " "), desktopIcon, trayIcon)
 }
 
+//ResyncSummary counts the changes on peers and peerings detected when the Agent resynchronizes its status
+//after being OFF.
+type ResyncSummary struct {
+	PeersAdded   int
+	PeersRemoved int
+	//OutgoingOn and OutgoingOff count the outgoing peerings established and closed.
+	OutgoingOn  int
+	OutgoingOff int
+	//IncomingOn and IncomingOff count the incoming peerings established and closed.
+	IncomingOn  int
+	IncomingOff int
+}
+
+//AddPeering counts a peering established (NotifyEventPeeringOn) or closed (NotifyEventPeeringOff).
+func (s *ResyncSummary) AddPeering(direction PeeringType, event NotifyPeeringEvent) {
+	switch {
+	case direction == PeeringOutgoing && event == NotifyEventPeeringOn:
+		s.OutgoingOn++
+	case direction == PeeringOutgoing:
+		s.OutgoingOff++
+	case event == NotifyEventPeeringOn:
+		s.IncomingOn++
+	default:
+		s.IncomingOff++
+	}
+}
+
+//String returns a description of the changes, e.g. "1 new peer, 2 outgoing peerings closed".
+//It returns an empty string if there are no changes.
+func (s ResyncSummary) String() string {
+	var changes []string
+	for _, c := range []struct {
+		count  int
+		noun   string
+		format string
+	}{
+		{s.PeersAdded, "peer", "%d new %s"},
+		{s.PeersRemoved, "peer", "%d %s removed"},
+		{s.OutgoingOn, "peering", "%d outgoing %s established"},
+		{s.OutgoingOff, "peering", "%d outgoing %s closed"},
+		{s.IncomingOn, "peering", "%d incoming %s established"},
+		{s.IncomingOff, "peering", "%d incoming %s closed"},
+	} {
+		if c.count == 0 {
+			continue
+		}
+		noun := c.noun
+		if c.count > 1 {
+			noun += "s"
+		}
+		changes = append(changes, fmt.Sprintf(c.format, c.count, noun))
+	}
+	return strings.Join(changes, ", ")
+}
+
+//NotifyResync is a semi-configured Notify() call to notify, with a single banner, the changes on peers
+//and peerings occurred while the Agent was OFF. Nothing is notified if there are no changes.
+func (i *Indicator) NotifyResync(summary ResyncSummary) {
+	changes := summary.String()
+	if changes == "" {
+		return
+	}
+	i.Notify("Liqo Agent: CHANGES WHILE OFF", changes, NotifyIconDefault, IconLiqoNil)
+}
+
+//NotifyVirtualNode is a semi-configured Notify() call to notify a readiness change of the virtual node
+//representing a specific peer in the home cluster. If the peer is not known, nil can be used for 'peer'.
+func (i *Indicator) NotifyVirtualNode(nodeName string, ready bool, peer *PeerInfo) {
+	var (
+		header      string
+		body        []string
+		desktopIcon NotifyIcon
+		trayIcon    Icon
+	)
+	target := nodeName
+	if peer != nil {
+		peer.RLock()
+		if peer.Unknown {
+			target = strings.Join([]string{"UNKNOWN", strconv.Itoa(peer.UnknownId)}, " ")
+		} else {
+			target = peer.ClusterName
+		}
+		peer.RUnlock()
+	}
+	if ready {
+		header = "VIRTUAL NODE READY"
+		body = append(body, "Resources of", target, "are ready to run your pods")
+		desktopIcon = NotifyIconDefault
+		trayIcon = IconLiqoPurple
+	} else {
+		header = "VIRTUAL NODE NOT READY"
+		body = append(body, "Resources of", target, "cannot currently run your pods")
+		desktopIcon = NotifyIconWarning
+		trayIcon = IconLiqoWarning
+	}
+	i.Notify(header, strings.Join(body, " "), desktopIcon, trayIcon)
+}
+
 //ShowWarning displays a Warning window box.
 func (i *Indicator) ShowWarning(title, message string) {
 	gr := i.graphicResource[resourceDesktop]
 	gr.Lock()
 	defer gr.Unlock()
-	if !GetGuiProvider().Mocked() {
-		_, _ = dlgs.Warning(title, fmt.Sprintln(strutil.CenterText("", menuWidth*2), message))
-	}
+	i.gProvider.Warning(title, fmt.Sprintln(strutil.CenterText("", menuWidth*2), message))
 }
 
 //ShowWarningForbiddenTethered is an already configured ShowWarning() call to warn users
@@ -195,17 +290,13 @@ func (i *Indicator) ShowError(title, message string) {
 	gr := i.graphicResource[resourceDesktop]
 	gr.Lock()
 	defer gr.Unlock()
-	if !GetGuiProvider().Mocked() {
-		_, _ = dlgs.Error(title, fmt.Sprintln(strutil.CenterText("", menuWidth*2), message))
-	}
+	i.gProvider.Error(title, fmt.Sprintln(strutil.CenterText("", menuWidth*2), message))
 }
 
 //ShowErrorNoConnection is an already configured ShowError() call to warn
 //the user about kubeconfig misconfiguration.
 func (i *Indicator) ShowErrorNoConnection() {
-	if !GetGuiProvider().Mocked() {
-		_, _ = dlgs.Error("LIQO AGENT", fmt.Sprintln(strutil.CenterText("", menuWidth*2),
-			"Liqo Agent could not find a valid kubeconfig file.\n",
-			"Please restart the Agent after providing a correct configuration."))
-	}
+	i.gProvider.Error("LIQO AGENT", fmt.Sprintln(strutil.CenterText("", menuWidth*2),
+		"Liqo Agent could not find a valid kubeconfig file.\n",
+		"Please restart the Agent after providing a correct configuration."))
 }
@@ -65,3 +65,13 @@ func TestIndicator_NotifyFunctions(t *testing.T) {
 	i.NotifyNoConnection()
 	assert.Equal(t, IconLiqoWarning, i.icon, "NotifyNoConnection: indicator icon not correctly set")
 }
+
+func TestResyncSummary(t *testing.T) {
+	summary := ResyncSummary{}
+	assert.Equal(t, "", summary.String(), "empty summary not detected")
+	summary.PeersAdded = 2
+	summary.AddPeering(PeeringOutgoing, NotifyEventPeeringOff)
+	summary.AddPeering(PeeringIncoming, NotifyEventPeeringOn)
+	summary.AddPeering(PeeringIncoming, NotifyEventPeeringOn)
+	assert.Equal(t, "2 new peers, 1 outgoing peering closed, 2 incoming peerings established", summary.String())
+}
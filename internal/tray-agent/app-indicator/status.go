@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -140,8 +141,8 @@ type StatusInterface interface {
 	SetUser(user string)
 	//Running returns the running status of Liqo.
 	Running() StatRun
-	//SetRunning changes the running status of Liqo. Transition to StatRunOff
-	//implies the end of all active peerings.
+	//SetRunning changes the running status of Liqo. While Liqo is OFF, the information on peers and peerings
+	//is not updated: it has to be resynchronized when Liqo is turned back ON.
 	SetRunning(running StatRun)
 	//Mode returns the current working mode of Liqo.
 	Mode() StatMode
@@ -164,6 +165,8 @@ type StatusInterface interface {
 	Peers() int
 	//Peer returns data related to a cluster if it is currently discovered by the home cluster.
 	Peer(clusterId string) (peer *PeerInfo, present bool)
+	//PeerIDs returns the sorted ClusterIDs of the peers currently registered.
+	PeerIDs() []string
 	//AddOrUpdatePeer updates the internal information on an existing or newly discovered peer.
 	//In case no info about the peer's common name is provided, a placeholder "unknown identifier"
 	//is assigned to allow the user to visually distinguish between different unknown peers.
@@ -278,6 +281,18 @@ func (st *Status) Peer(clusterId string) (peer *PeerInfo, present bool) {
 	return
 }
 
+//PeerIDs returns the sorted ClusterIDs of the peers currently registered.
+func (st *Status) PeerIDs() []string {
+	st.RLock()
+	defer st.RUnlock()
+	ids := make([]string, 0, len(st.peerList))
+	for id := range st.peerList {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 //addPeer registers a newly discovered peer. In case no info about the peer's common name is provided,
 //a placeholder "unknown identifier" is assigned to allow the user to visually distinguish between different unknown peers.
 //When the number of unknown peers is decremented to 0, the identifier number is reset.
@@ -432,18 +447,12 @@ func (st *Status) Running() StatRun {
 	return st.running
 }
 
-//SetRunning changes the running status of Liqo. Transition to StatRunOff
-//implies the end of all active peerings.
+//SetRunning changes the running status of Liqo. While Liqo is OFF, the information on peers and peerings
+//is not updated: it has to be resynchronized when Liqo is turned back ON.
 func (st *Status) SetRunning(running StatRun) {
 	st.Lock()
 	defer st.Unlock()
-	if running != st.running {
-		if running == StatRunOff {
-			st.outgoingPeerings = 0
-			st.incomingPeerings = 0
-		}
-		st.running = running
-	}
+	st.running = running
 }
 
 //Mode returns the current working mode of Liqo.
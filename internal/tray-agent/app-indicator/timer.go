@@ -2,70 +2,327 @@ package app_indicator
 
 import (
 	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
 	"time"
 )
 
+//TimerOptions defines when the callback of a Timer is executed.
+type TimerOptions struct {
+	//Interval is the time between two consecutive executions of the callback. For a run-once Timer, it is the
+	//delay of the execution (or of the next attempt, if the Timer is inactive when it fires).
+	Interval time.Duration
+	//Jitter is the maximum random delay added to each Interval, so that Timers with the same Interval
+	//are spread over time.
+	Jitter time.Duration
+	//Immediate specifies whether the callback is executed as soon as the Timer is scheduled, instead of
+	//after the first Interval.
+	Immediate bool
+	//Once specifies whether the Timer is removed after the first execution of the callback.
+	Once bool
+	//EventKey is the key of the events executing the callback (see client.NotifyDataGeneric). If empty, the
+	//executions of each Timer have their own key.
+	EventKey string
+}
+
 //Timer is a data structure that allows to control a time triggered loop execution of a callback.
 type Timer struct {
 	//tag is the Timer id
 	tag string
-	//controller is the channel that lets control the callback execution, allowing or preventing it whether the channel
-	//receive a true or false value.
-	controller chan bool
+	//options defines when the callback is executed.
+	options TimerOptions
+	//callback is executed when the Timer fires.
+	callback func(args ...interface{})
+	//args are the additional arguments of callback.
+	args []interface{}
 	//active defines if the time triggered callback is executed (active = true)
 	active bool
-	//quitCh is the stop chan used to permanently stop the time loop
-	quitCh chan struct{}
+	//running specifies whether an execution of the callback is in progress. The Timer does not fire meanwhile.
+	running bool
+	//next is the time of the next execution.
+	next time.Time
+	//scheduler is the Scheduler the Timer belongs to.
+	scheduler *Scheduler
 }
 
 //SetActive controls the Timer behavior, allowing or not future calls of the associated callback.
+//While inactive, the Timer keeps its schedule, skipping the executions.
 func (t *Timer) SetActive(active bool) {
-	t.controller <- active
+	t.scheduler.mutex.Lock()
+	defer t.scheduler.mutex.Unlock()
+	t.active = active
 }
 
 //Active returns if the Timer is currently active, i.e. timed calls of the associated callback are allowed.
 func (t *Timer) Active() bool {
+	t.scheduler.mutex.Lock()
+	defer t.scheduler.mutex.Unlock()
 	return t.active
 }
 
-//StartTimer registers a new Timer in charge of controlling the loop execution of callback. The Timer starts
-//automatically and can be controlled using (*Timer).SetActive() .
-//
-//	- tag : Timer id.
-//
-//	- interval : specifies the time interval after which the callback execution is triggered.
-func (i *Indicator) StartTimer(tag string, interval time.Duration, callback func(args ...interface{}), args ...interface{}) error {
-	if _, present := i.timers[tag]; present {
-		return errors.New("A Timer with the same tag already exists")
+//Next returns the time of the next execution of the callback.
+func (t *Timer) Next() time.Time {
+	t.scheduler.mutex.Lock()
+	defer t.scheduler.mutex.Unlock()
+	return t.next
+}
+
+//eventKey returns the key of the events executing the callback of the Timer.
+func (t *Timer) eventKey() string {
+	if t.options.EventKey != "" {
+		return t.options.EventKey
+	}
+	return "timer/" + t.tag
+}
+
+/*Scheduler executes the callbacks of a set of Timers, identified by tag. A single goroutine (Run) waits for
+the next Timer to fire, according to a Clock. Once fired, a Timer is scheduled again after its Interval (plus
+a random Jitter), unless it is a run-once Timer.
+
+The callback of a Timer never overlaps with a previous execution of its own: a Timer does not fire while its
+callback is running. All the methods are safe for concurrent use.*/
+type Scheduler struct {
+	//clock provides the current time and the timers.
+	clock Clock
+	//execute runs the callback of a fired Timer, without blocking the Scheduler. run must be called
+	//once the Timer callback has to be executed. key is the EventKey of the Timer.
+	execute func(key string, run func())
+	//timers contains the scheduled Timers, by tag.
+	timers map[string]*Timer
+	//wake wakes Run up when the Timers change.
+	wake chan struct{}
+	//rand generates the jitter of the Timers.
+	rand  *rand.Rand
+	mutex sync.Mutex
+}
+
+//NewScheduler creates a Scheduler based on clock (if nil, the system clock is used). When a Timer fires,
+//execute is called to run its callback without blocking. If execute is nil, the callback is executed
+//in a new goroutine.
+func NewScheduler(clock Clock, execute func(key string, run func())) *Scheduler {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	if execute == nil {
+		execute = func(_ string, run func()) {
+			go run()
+		}
+	}
+	return &Scheduler{
+		clock:   clock,
+		execute: execute,
+		timers:  make(map[string]*Timer),
+		wake:    make(chan struct{}, 1),
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+//Add schedules a new Timer in charge of executing callback according to options. The Timer starts active
+//and can be controlled using (*Timer).SetActive() .
+func (s *Scheduler) Add(tag string, options TimerOptions, callback func(args ...interface{}),
+	args ...interface{}) (*Timer, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, present := s.timers[tag]; present {
+		return nil, fmt.Errorf("a Timer %s already exists", tag)
 	}
 	t := &Timer{
-		tag:        tag,
-		controller: make(chan bool, 2),
-		quitCh:     i.quitChan,
-		active:     true,
-	}
-	i.timers[tag] = t
-	go func(timer *Timer) {
-		for {
-			select {
-			case <-time.After(interval):
-				if timer.active {
-					callback(args...)
-				}
-			case stat, open := <-timer.controller:
-				if open {
-					timer.active = stat
+		tag:       tag,
+		options:   options,
+		callback:  callback,
+		args:      args,
+		active:    true,
+		scheduler: s,
+	}
+	s.schedule(t, options.Immediate)
+	s.timers[tag] = t
+	s.notify()
+	return t, nil
+}
+
+//Remove removes the Timer identified by tag. It returns false if such Timer does not exist.
+//A running execution of its callback is not interrupted.
+func (s *Scheduler) Remove(tag string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, present := s.timers[tag]; !present {
+		return false
+	}
+	delete(s.timers, tag)
+	s.notify()
+	return true
+}
+
+//Reschedule replaces the options of the Timer identified by tag, scheduling its next execution from now.
+func (s *Scheduler) Reschedule(tag string, options TimerOptions) error {
+	if err := options.validate(); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	t, present := s.timers[tag]
+	if !present {
+		return fmt.Errorf("no Timer %s exists", tag)
+	}
+	t.options = options
+	s.schedule(t, options.Immediate)
+	s.notify()
+	return nil
+}
+
+//Fire executes as soon as possible the callback of the Timer identified by tag, which is then scheduled
+//again from the time of the execution.
+func (s *Scheduler) Fire(tag string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	t, present := s.timers[tag]
+	if !present {
+		return fmt.Errorf("no Timer %s exists", tag)
+	}
+	s.schedule(t, true)
+	s.notify()
+	return nil
+}
+
+//Timer returns the Timer identified by tag. If such Timer does not exist, present == false.
+func (s *Scheduler) Timer(tag string) (timer *Timer, present bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	timer, present = s.timers[tag]
+	return
+}
+
+//Tags returns the tags of the scheduled Timers, sorted.
+func (s *Scheduler) Tags() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	tags := make([]string, 0, len(s.timers))
+	for tag := range s.timers {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+//Run fires the Timers when their time comes, until quit is closed.
+func (s *Scheduler) Run(quit <-chan struct{}) {
+	for {
+		next, scheduled := s.fire()
+		var timer ClockTimer
+		var expired <-chan time.Time
+		if scheduled {
+			timer = s.clock.NewTimer(next.Sub(s.clock.Now()))
+			expired = timer.C()
+		}
+		select {
+		case <-expired:
+		case <-s.wake:
+		case <-quit:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-quit:
+			return
+		default:
+		}
+	}
+}
+
+//fire executes the callbacks of the expired Timers, in order of tag. It returns the time the next Timer fires,
+//if any.
+func (s *Scheduler) fire() (next time.Time, scheduled bool) {
+	s.mutex.Lock()
+	now := s.clock.Now()
+	var expired []*Timer
+	for tag, t := range s.timers {
+		if !t.next.After(now) {
+			if t.active && !t.running {
+				t.running = true
+				expired = append(expired, t)
+				if t.options.Once {
+					delete(s.timers, tag)
+					continue
 				}
-			case <-timer.quitCh:
-				return
 			}
+			s.schedule(t, false)
 		}
-	}(t)
+		if !scheduled || t.next.Before(next) {
+			next, scheduled = t.next, true
+		}
+	}
+	s.mutex.Unlock()
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].tag < expired[j].tag
+	})
+	for _, t := range expired {
+		timer := t
+		s.execute(timer.eventKey(), func() {
+			timer.callback(timer.args...)
+			s.mutex.Lock()
+			timer.running = false
+			s.mutex.Unlock()
+		})
+	}
+	return
+}
+
+//schedule sets the time of the next execution of a Timer, which is now if immediate. It must be called
+//holding the mutex.
+func (s *Scheduler) schedule(t *Timer, immediate bool) {
+	t.next = s.clock.Now()
+	if immediate {
+		return
+	}
+	t.next = t.next.Add(t.options.Interval)
+	if t.options.Jitter > 0 {
+		t.next = t.next.Add(time.Duration(s.rand.Int63n(int64(t.options.Jitter))))
+	}
+}
+
+//notify wakes Run up after a change of the Timers.
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+//validate checks whether the options define a valid schedule.
+func (o TimerOptions) validate() error {
+	if o.Interval <= 0 {
+		return errors.New("the Interval of a Timer must be positive")
+	}
+	if o.Jitter < 0 {
+		return errors.New("the Jitter of a Timer cannot be negative")
+	}
 	return nil
 }
 
+//StartTimer registers a new Timer in charge of controlling the loop execution of callback. The Timer starts
+//automatically and can be controlled using (*Timer).SetActive() or the Indicator Scheduler.
+//
+//	- tag : Timer id.
+//
+//	- interval : specifies the time interval after which the callback execution is triggered.
+func (i *Indicator) StartTimer(tag string, interval time.Duration, callback func(args ...interface{}), args ...interface{}) error {
+	_, err := i.scheduler.Add(tag, TimerOptions{Interval: interval}, callback, args...)
+	return err
+}
+
 //Timer returns the registered Timer for the specified tag. If such Timer does not exist, present == false.
 func (i *Indicator) Timer(tag string) (timer *Timer, present bool) {
-	timer, present = i.timers[tag]
-	return
+	return i.scheduler.Timer(tag)
+}
+
+//Scheduler returns the Scheduler of the Indicator Timers. Their callbacks are handled as Indicator events,
+//so that they never overlap with the other events regarding the same Timer.
+func (i *Indicator) Scheduler() *Scheduler {
+	return i.scheduler
 }
@@ -0,0 +1,251 @@
+package app_indicator
+
+import (
+	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+//startScheduler starts a Scheduler based on a FakeClock. The executions of the Timers callbacks are sent
+//on the returned channel.
+func startScheduler(t *testing.T) (*Scheduler, *FakeClock, chan string, func()) {
+	clock := NewFakeClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := NewScheduler(clock, nil)
+	calls := make(chan string, 100)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		s.Run(quit)
+		close(done)
+	}()
+	return s, clock, calls, func() {
+		close(quit)
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Scheduler not stopped")
+		}
+	}
+}
+
+//record returns a Timer callback that sends tag on calls.
+func record(calls chan string, tag string) func(...interface{}) {
+	return func(...interface{}) {
+		calls <- tag
+	}
+}
+
+//waitArmed waits for the Scheduler to wait for its next Timer.
+func waitArmed(t *testing.T, clock *FakeClock) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		return clock.Timers() == 1
+	}, 5*time.Second, time.Millisecond, "Scheduler not waiting for the next Timer")
+}
+
+//assertCalls checks that only the expected Timers fired, in any order.
+func assertCalls(t *testing.T, calls chan string, expected ...string) {
+	t.Helper()
+	var fired []string
+	for range expected {
+		select {
+		case call := <-calls:
+			fired = append(fired, call)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timers %v not fired, %v fired", expected, fired)
+		}
+	}
+	assert.ElementsMatch(t, expected, fired, "wrong Timers fired")
+	assert.Never(t, func() bool {
+		return len(calls) > 0
+	}, 50*time.Millisecond, time.Millisecond, "unexpected Timer fired")
+}
+
+func TestScheduler_Periodic(t *testing.T) {
+	s, clock, calls, stop := startScheduler(t)
+	defer stop()
+	start := clock.Now()
+	timer, err := s.Add("periodic", TimerOptions{Interval: 10 * time.Second}, record(calls, "periodic"))
+	assert.NoError(t, err)
+	_, err = s.Add("periodic", TimerOptions{Interval: time.Second}, record(calls, "periodic"))
+	assert.Error(t, err, "Timer with the same tag added")
+	_, err = s.Add("invalid", TimerOptions{}, record(calls, "invalid"))
+	assert.Error(t, err, "Timer with no Interval added")
+	waitArmed(t, clock)
+	clock.Advance(9 * time.Second)
+	assertCalls(t, calls)
+	clock.Advance(time.Second)
+	assertCalls(t, calls, "periodic")
+	assert.Equal(t, start.Add(20*time.Second), timer.Next(), "Timer not scheduled again")
+	//an inactive Timer keeps its schedule, skipping the executions
+	timer.SetActive(false)
+	assert.False(t, timer.Active())
+	waitArmed(t, clock)
+	clock.Advance(10 * time.Second)
+	assertCalls(t, calls)
+	assert.Equal(t, start.Add(30*time.Second), timer.Next(), "inactive Timer not scheduled again")
+	timer.SetActive(true)
+	waitArmed(t, clock)
+	clock.Advance(10 * time.Second)
+	assertCalls(t, calls, "periodic")
+}
+
+func TestScheduler_ImmediateOnce(t *testing.T) {
+	s, clock, calls, stop := startScheduler(t)
+	defer stop()
+	_, err := s.Add("once", TimerOptions{Interval: time.Minute, Once: true}, record(calls, "once"))
+	assert.NoError(t, err)
+	_, err = s.Add("immediate", TimerOptions{Interval: time.Minute, Immediate: true}, record(calls, "immediate"))
+	assert.NoError(t, err)
+	assertCalls(t, calls, "immediate")
+	waitArmed(t, clock)
+	clock.Advance(time.Minute)
+	assertCalls(t, calls, "immediate", "once")
+	assert.Equal(t, []string{"immediate"}, s.Tags(), "run-once Timer not removed")
+}
+
+func TestScheduler_EventKey(t *testing.T) {
+	clock := NewFakeClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	keys := make(chan string, 10)
+	s := NewScheduler(clock, func(key string, run func()) {
+		keys <- key
+		run()
+	})
+	quit := make(chan struct{})
+	defer close(quit)
+	go s.Run(quit)
+	_, err := s.Add("own", TimerOptions{Interval: time.Minute, Immediate: true, Once: true},
+		func(...interface{}) {})
+	assert.NoError(t, err)
+	_, err = s.Add("shared", TimerOptions{Interval: time.Minute, Immediate: true, Once: true,
+		EventKey: client.PeersListEventKey}, func(...interface{}) {})
+	assert.NoError(t, err)
+	var fired []string
+	for range []int{0, 1} {
+		select {
+		case key := <-keys:
+			fired = append(fired, key)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timers not fired, keys %v", fired)
+		}
+	}
+	assert.ElementsMatch(t, []string{"timer/own", client.PeersListEventKey}, fired, "wrong event keys")
+}
+
+func TestScheduler_Control(t *testing.T) {
+	s, clock, calls, stop := startScheduler(t)
+	defer stop()
+	start := clock.Now()
+	_, err := s.Add("a", TimerOptions{Interval: time.Hour}, record(calls, "a"))
+	assert.NoError(t, err)
+	_, err = s.Add("b", TimerOptions{Interval: time.Hour}, record(calls, "b"))
+	assert.NoError(t, err)
+	//Fire
+	assert.NoError(t, s.Fire("b"))
+	assertCalls(t, calls, "b")
+	b, present := s.Timer("b")
+	if assert.True(t, present) {
+		assert.Equal(t, start.Add(time.Hour), b.Next(), "fired Timer not scheduled again")
+	}
+	assert.Error(t, s.Fire("c"), "missing Timer fired")
+	//Reschedule
+	assert.NoError(t, s.Reschedule("a", TimerOptions{Interval: time.Minute}))
+	assert.Error(t, s.Reschedule("a", TimerOptions{Interval: -time.Minute}), "invalid Interval accepted")
+	assert.Error(t, s.Reschedule("c", TimerOptions{Interval: time.Minute}), "missing Timer rescheduled")
+	clock.Advance(time.Minute)
+	assertCalls(t, calls, "a")
+	//Remove
+	assert.True(t, s.Remove("a"))
+	assert.False(t, s.Remove("a"), "Timer removed twice")
+	clock.Advance(time.Hour)
+	assertCalls(t, calls, "b")
+	assert.Equal(t, []string{"b"}, s.Tags())
+}
+
+func TestScheduler_Jitter(t *testing.T) {
+	s, clock, calls, stop := startScheduler(t)
+	defer stop()
+	options := TimerOptions{Interval: 10 * time.Second, Jitter: 5 * time.Second}
+	timer, err := s.Add("jitter", options, record(calls, "jitter"))
+	assert.NoError(t, err)
+	spread := false
+	for n := 0; n < 20; n++ {
+		assert.NoError(t, s.Reschedule("jitter", options))
+		delay := timer.Next().Sub(clock.Now())
+		assert.GreaterOrEqual(t, int64(delay), int64(options.Interval), "Timer scheduled before its Interval")
+		assert.Less(t, int64(delay), int64(options.Interval+options.Jitter), "Jitter exceeded")
+		spread = spread || delay != options.Interval
+	}
+	assert.True(t, spread, "no Jitter applied")
+	_, err = s.Add("invalid", TimerOptions{Interval: time.Second, Jitter: -time.Second}, record(calls, "invalid"))
+	assert.Error(t, err, "negative Jitter accepted")
+}
+
+func TestScheduler_NoOverlap(t *testing.T) {
+	s, clock, _, stop := startScheduler(t)
+	defer stop()
+	unblock := make(chan struct{})
+	mutex := sync.Mutex{}
+	running, executions := 0, 0
+	_, err := s.Add("slow", TimerOptions{Interval: time.Second}, func(...interface{}) {
+		mutex.Lock()
+		running++
+		executions++
+		mutex.Unlock()
+		<-unblock
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+	})
+	assert.NoError(t, err)
+	//the Timer expires several times while its callback is running
+	for n := 0; n < 5; n++ {
+		waitArmed(t, clock)
+		clock.Advance(time.Second)
+	}
+	assert.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return executions == 1
+	}, 5*time.Second, time.Millisecond, "Timer not fired")
+	close(unblock)
+	waitArmed(t, clock)
+	clock.Advance(time.Second)
+	assert.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return executions == 2 && running == 0
+	}, 5*time.Second, time.Millisecond, "Timer not fired after the end of the previous execution")
+}
+
+func TestIndicator_Timers(t *testing.T) {
+	UseMockedGuiProvider()
+	client.UseMockedAgentController()
+	DestroyMockedIndicator()
+	client.DestroyMockedAgentController()
+	clock := NewFakeClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	UseClock(clock)
+	defer UseClock(nil)
+	i := GetIndicator()
+	defer i.Quit()
+	calls := 0
+	assert.NoError(t, i.StartTimer("test", time.Minute, func(args ...interface{}) {
+		calls++
+	}))
+	assert.Error(t, i.StartTimer("test", time.Minute, func(args ...interface{}) {}), "Timer with the same tag started")
+	timer, present := i.Timer("test")
+	if assert.True(t, present, "Timer not registered") {
+		assert.True(t, timer.Active())
+	}
+	waitArmed(t, clock)
+	clock.Advance(time.Minute)
+	//in test mode, the Timers callbacks are handled as the other Indicator events
+	assert.Equal(t, 0, calls, "Timer callback executed before dispatching")
+	assert.NoError(t, i.DispatchEvents(1, 5*time.Second), "Timer event not dispatched")
+	assert.Equal(t, 1, calls, "Timer callback not executed")
+	assert.True(t, i.Scheduler().Remove("test"))
+	_, present = i.Timer("test")
+	assert.False(t, present, "Timer not removed")
+}
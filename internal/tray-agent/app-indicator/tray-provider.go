@@ -0,0 +1,72 @@
+package app_indicator
+
+import (
+	"github.com/getlantern/systray"
+)
+
+//GuiTray is the name of the default frontend, which displays the Indicator in the system tray.
+const GuiTray = "tray"
+
+func init() {
+	RegisterGuiProvider(GuiTray, func() GuiProviderInterface {
+		return &trayProvider{}
+	})
+}
+
+//trayProvider is a GuiProviderInterface that exploits github.com/getlantern/systray to display the Indicator
+//in the system tray.
+type trayProvider struct {
+	desktopDialogs
+}
+
+func (g *trayProvider) Run(onReady func(), onExit func()) {
+	systray.Run(onReady, onExit)
+}
+
+func (g *trayProvider) AddSeparator() {
+	systray.AddSeparator()
+}
+
+func (g *trayProvider) Quit() {
+	systray.Quit()
+}
+
+func (g *trayProvider) SetIcon(iconBytes []byte) {
+	systray.SetIcon(iconBytes)
+}
+
+func (g *trayProvider) SetTitle(title string) {
+	systray.SetTitle(title)
+}
+
+func (g *trayProvider) AddMenuItem(withCheckbox bool) Item {
+	if withCheckbox {
+		return &trayItem{systray.AddMenuItemCheckbox("", "", false)}
+	}
+	return &trayItem{systray.AddMenuItem("", "")}
+}
+
+func (g *trayProvider) AddSubMenuItem(parent Item, withCheckbox bool) Item {
+	if parent == nil {
+		panic("invalid creation of child Item with nil parent")
+	}
+	parentItem := parent.(*trayItem)
+	if withCheckbox {
+		return &trayItem{parentItem.AddSubMenuItemCheckbox("", "", false)}
+	}
+	return &trayItem{parentItem.AddSubMenuItem("", "")}
+}
+
+func (g *trayProvider) Mocked() bool {
+	return false
+}
+
+//trayItem is the Item of a trayProvider, wrapping a systray.MenuItem.
+type trayItem struct {
+	*systray.MenuItem
+}
+
+//ClickedCh returns the channel that receives the 'clicked' events of the trayItem.
+func (i *trayItem) ClickedCh() chan struct{} {
+	return i.MenuItem.ClickedCh
+}
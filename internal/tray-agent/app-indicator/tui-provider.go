@@ -0,0 +1,602 @@
+package app_indicator
+
+import (
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*This file contains a terminal implementation of the GuiProviderInterface, which renders the Indicator menu
+as a navigable text menu. It lets the Agent be used where no graphic server is available, e.g. over SSH.
+The dialogs are displayed in place of the menu, while the notifications are displayed in a status line below it.*/
+
+//GuiTerminal is the name of the frontend that displays the Indicator menu on the terminal the Agent
+//is started from.
+const GuiTerminal = "tui"
+
+func init() {
+	RegisterGuiProvider(GuiTerminal, func() GuiProviderInterface {
+		return newTuiProvider(os.Stdin, os.Stdout)
+	})
+}
+
+//set of keys handled by the terminal menu
+const (
+	tuiKeyUp = iota
+	tuiKeyDown
+	tuiKeyEnter
+	tuiKeyBack
+	tuiKeyQuit
+)
+
+//set of literal prefixes and separators used to render the terminal menu
+const (
+	tuiCursor         = "▶ "
+	tuiNoCursor       = "  "
+	tuiSubmenu        = " ▸"
+	tuiChecked        = "[x] "
+	tuiUnchecked      = "[ ] "
+	tuiSeparator      = "────────────────────"
+	tuiBreadcrumbSep  = " › "
+	tuiHelp           = "↑/↓ move · enter select · ← back · ctrl+c quit"
+	tuiPromptHelp     = "type the answer · enter confirm · esc cancel"
+	tuiChoiceHelp     = "↑/↓ move · enter select · esc cancel"
+	tuiPrompt         = "> "
+	tuiCopied         = "Copied: "
+	tuiYes            = "Yes"
+	tuiNo             = "No"
+	tuiOk             = "OK"
+	tuiNewLine        = "\r\n"
+	tuiClearScreen    = "\x1b[H\x1b[2J"
+	tuiHideCursor     = "\x1b[?25l"
+	tuiShowCursor     = "\x1b[?25h"
+	tuiDisabledStart  = "\x1b[2m"
+	tuiFormattingStop = "\x1b[0m"
+	//tuiClipboardFormat is the OSC 52 sequence that sets the clipboard of the terminal (if supported) to
+	//base64-encoded text.
+	tuiClipboardFormat = "\x1b]52;c;%s\a"
+)
+
+//tuiProvider is a GuiProviderInterface that renders the menu on a terminal, reading the user
+//commands from its input.
+type tuiProvider struct {
+	//in is the source of the user key strokes.
+	in io.Reader
+	//out is the terminal the menu is rendered on.
+	out io.Writer
+	//tree contains the menu entries. Its mutex also protects path and cursor.
+	tree *itemTree
+	//path is the stack of the entries whose submenu is currently opened.
+	path []*treeItem
+	//cursor is the index of the selected entry in the current (sub)menu.
+	cursor int
+	//dialog is the dialog currently displayed in place of the menu, if any. It is protected by the itemTree mutex.
+	dialog *tuiDialog
+	//dialogMutex makes the dialogs be displayed one at a time.
+	dialogMutex sync.Mutex
+	//status is the last notification, displayed below the menu. It is protected by the itemTree mutex.
+	status string
+	//clipboard is the text to be copied to the terminal clipboard at the next rendering, if any.
+	//It is protected by the itemTree mutex.
+	clipboard string
+	//closing specifies whether the user asked to quit: the dialogs are then cancelled.
+	//It is protected by the itemTree mutex.
+	closing bool
+	//redrawChan signals that the menu has changed and has to be rendered again.
+	redrawChan chan struct{}
+	//quitChan is closed when Quit() is called.
+	quitChan chan struct{}
+	quitOnce sync.Once
+}
+
+//newTuiProvider creates a tuiProvider reading the key strokes from in and rendering the menu on out.
+func newTuiProvider(in io.Reader, out io.Writer) *tuiProvider {
+	p := &tuiProvider{
+		in:         in,
+		out:        out,
+		redrawChan: make(chan struct{}, 1),
+		quitChan:   make(chan struct{}),
+	}
+	p.tree = newItemTree(p.redraw)
+	return p
+}
+
+func (p *tuiProvider) Run(onReady func(), onExit func()) {
+	if f, ok := p.in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		if state, err := term.MakeRaw(int(f.Fd())); err == nil {
+			defer func() {
+				_ = term.Restore(int(f.Fd()), state)
+			}()
+		}
+	}
+	_, _ = fmt.Fprint(p.out, tuiHideCursor)
+	defer func() {
+		_, _ = fmt.Fprint(p.out, tuiShowCursor+tuiClearScreen)
+	}()
+	go p.drawLoop()
+	go p.readInput()
+	go onReady()
+	<-p.quitChan
+	onExit()
+}
+
+func (p *tuiProvider) Quit() {
+	p.quitOnce.Do(func() {
+		close(p.quitChan)
+	})
+}
+
+func (p *tuiProvider) AddSeparator() {
+	p.tree.addSeparator()
+}
+
+//SetIcon is a no-op: the status of the Agent is displayed by the menu header.
+func (p *tuiProvider) SetIcon(_ []byte) {}
+
+func (p *tuiProvider) SetTitle(title string) {
+	p.tree.setTitle(title)
+}
+
+func (p *tuiProvider) AddMenuItem(withCheckbox bool) Item {
+	return p.tree.addItem(nil, withCheckbox)
+}
+
+func (p *tuiProvider) AddSubMenuItem(parent Item, withCheckbox bool) Item {
+	if parent == nil {
+		panic("invalid creation of child Item with nil parent")
+	}
+	return p.tree.addItem(parent.(*treeItem), withCheckbox)
+}
+
+func (p *tuiProvider) Mocked() bool {
+	return false
+}
+
+func (p *tuiProvider) Prompt(title, message, defaultValue string) (string, bool) {
+	return p.ask(&tuiDialog{title: title, message: message, input: []rune(defaultValue)})
+}
+
+func (p *tuiProvider) Choose(title, message string, items []string) (string, bool) {
+	if len(items) == 0 {
+		return "", false
+	}
+	return p.ask(&tuiDialog{title: title, message: message, items: items})
+}
+
+func (p *tuiProvider) SelectFile(title, filter string) (string, bool) {
+	return p.Prompt(title, fmt.Sprintf("Insert the path of the file (%s)", filter), "")
+}
+
+//Confirm proposes the negative answer, so that a destructive command is not confirmed by mistake.
+func (p *tuiProvider) Confirm(title, message string) bool {
+	choice, ok := p.ask(&tuiDialog{title: title, message: message, items: []string{tuiYes, tuiNo}, cursor: 1})
+	return ok && choice == tuiYes
+}
+
+func (p *tuiProvider) Warning(title, message string) {
+	_, _ = p.ask(&tuiDialog{title: title, message: message, items: []string{tuiOk}})
+}
+
+func (p *tuiProvider) Error(title, message string) {
+	_, _ = p.ask(&tuiDialog{title: title, message: message, items: []string{tuiOk}})
+}
+
+//Notify displays the notification in the status line, replacing the previous one.
+func (p *tuiProvider) Notify(title, message, _ string) {
+	p.setStatus(title + ": " + message)
+}
+
+//Copy sends text to the terminal clipboard (if supported by the terminal), displaying it in the status line
+//so that it can be copied by hand.
+func (p *tuiProvider) Copy(text string) error {
+	p.tree.mutex.Lock()
+	p.clipboard = text
+	p.tree.mutex.Unlock()
+	p.setStatus(tuiCopied + text)
+	return nil
+}
+
+//setStatus replaces the content of the status line.
+func (p *tuiProvider) setStatus(status string) {
+	p.tree.mutex.Lock()
+	p.status = strings.Join(strings.Fields(status), " ")
+	p.tree.mutex.Unlock()
+	p.redraw()
+}
+
+//tuiDialog is a request to the user displayed in place of the menu.
+type tuiDialog struct {
+	title   string
+	message string
+	//items are the choices of the dialog. If nil, the dialog asks a text input.
+	items []string
+	//cursor is the index of the selected item.
+	cursor int
+	//input is the text typed by the user.
+	input []rune
+	//answer receives the answer of the user.
+	answer chan tuiAnswer
+}
+
+//tuiAnswer is the answer to a tuiDialog: the typed text or the chosen item. ok is false if the user cancelled
+//the request.
+type tuiAnswer struct {
+	value string
+	ok    bool
+}
+
+//ask displays a dialog, waiting for the answer of the user. The dialog is cancelled if the user quits.
+func (p *tuiProvider) ask(d *tuiDialog) (string, bool) {
+	p.dialogMutex.Lock()
+	defer p.dialogMutex.Unlock()
+	d.answer = make(chan tuiAnswer, 1)
+	p.tree.mutex.Lock()
+	if p.closing {
+		p.tree.mutex.Unlock()
+		return "", false
+	}
+	p.dialog = d
+	p.tree.mutex.Unlock()
+	p.redraw()
+	defer p.redraw()
+	select {
+	case a := <-d.answer:
+		return a.value, a.ok
+	case <-p.quitChan:
+		p.tree.mutex.Lock()
+		if p.dialog == d {
+			p.dialog = nil
+		}
+		p.tree.mutex.Unlock()
+		return "", false
+	}
+}
+
+//reply closes the current dialog with an answer. The caller must hold the itemTree mutex.
+func (p *tuiProvider) reply(value string, ok bool) {
+	p.dialog.answer <- tuiAnswer{value: value, ok: ok}
+	p.dialog = nil
+}
+
+//handleDialogInput edits the current dialog according to the bytes read from the terminal. It returns false
+//if no dialog is displayed.
+func (p *tuiProvider) handleDialogInput(input []byte) bool {
+	p.tree.mutex.Lock()
+	defer p.tree.mutex.Unlock()
+	d := p.dialog
+	if d == nil {
+		return false
+	}
+	defer p.redraw()
+	for idx := 0; idx < len(input); {
+		r, size := utf8.DecodeRune(input[idx:])
+		idx += size
+		switch {
+		case r == '\x1b':
+			//arrow keys are encoded as ESC [ A-D, while a lone ESC cancels the dialog
+			if idx+1 < len(input) && input[idx] == '[' {
+				switch input[idx+1] {
+				case 'A':
+					d.moveCursor(-1)
+				case 'B':
+					d.moveCursor(1)
+				}
+				idx += 2
+				continue
+			}
+			p.reply("", false)
+			return true
+		case r == '\r' || r == '\n':
+			if d.items == nil {
+				p.reply(string(d.input), true)
+			} else {
+				p.reply(d.items[d.cursor], true)
+			}
+			return true
+		case r == '\x7f' || r == '\b':
+			if len(d.input) > 0 {
+				d.input = d.input[:len(d.input)-1]
+			}
+		case d.items != nil:
+			switch r {
+			case 'k':
+				d.moveCursor(-1)
+			case 'j':
+				d.moveCursor(1)
+			}
+		case unicode.IsPrint(r):
+			d.input = append(d.input, r)
+		}
+	}
+	return true
+}
+
+//moveCursor moves the cursor of a choice dialog by delta positions.
+func (d *tuiDialog) moveCursor(delta int) {
+	if cursor := d.cursor + delta; cursor >= 0 && cursor < len(d.items) {
+		d.cursor = cursor
+	}
+}
+
+//lines returns the text lines representing the dialog.
+func (d *tuiDialog) lines() []string {
+	lines := []string{d.title, tuiSeparator}
+	lines = append(lines, strings.Split(strings.TrimSpace(d.message), "\n")...)
+	lines = append(lines, "")
+	if d.items == nil {
+		return append(lines, tuiPrompt+string(d.input), tuiSeparator, tuiPromptHelp)
+	}
+	for idx, item := range d.items {
+		cursor := tuiNoCursor
+		if idx == d.cursor {
+			cursor = tuiCursor
+		}
+		lines = append(lines, cursor+item)
+	}
+	return append(lines, tuiSeparator, tuiChoiceHelp)
+}
+
+//redraw signals that the menu has to be rendered again. Consecutive signals are coalesced.
+func (p *tuiProvider) redraw() {
+	select {
+	case p.redrawChan <- struct{}{}:
+	default:
+	}
+}
+
+//drawLoop renders the menu each time it changes, until Quit() is called.
+func (p *tuiProvider) drawLoop() {
+	for {
+		select {
+		case <-p.redrawChan:
+			p.tree.mutex.Lock()
+			clipboard := p.clipboard
+			p.clipboard = ""
+			p.tree.mutex.Unlock()
+			if clipboard != "" {
+				_, _ = fmt.Fprintf(p.out, tuiClipboardFormat, base64.StdEncoding.EncodeToString([]byte(clipboard)))
+			}
+			_, _ = fmt.Fprint(p.out, tuiClearScreen+strings.Join(p.lines(), tuiNewLine))
+		case <-p.quitChan:
+			return
+		}
+	}
+}
+
+//readInput translates the bytes read from the terminal into menu commands, until the input is closed.
+func (p *tuiProvider) readInput() {
+	buf := make([]byte, 16)
+	for {
+		n, err := p.in.Read(buf)
+		if p.handleInput(buf[:n]) || err != nil {
+			return
+		}
+	}
+}
+
+//handleInput executes the commands read from the terminal, on the current dialog if any or on the menu.
+//It returns true if the user asked to quit.
+func (p *tuiProvider) handleInput(input []byte) bool {
+	keys := parseTuiKeys(input)
+	for _, key := range keys {
+		if key == tuiKeyQuit {
+			p.quit()
+			return true
+		}
+	}
+	if p.handleDialogInput(input) {
+		return false
+	}
+	for _, key := range keys {
+		p.handleKey(key)
+	}
+	return false
+}
+
+//quit stops the Agent on the user request, cancelling the current and the following dialogs.
+func (p *tuiProvider) quit() {
+	p.tree.mutex.Lock()
+	p.closing = true
+	if p.dialog != nil {
+		p.reply("", false)
+	}
+	p.tree.mutex.Unlock()
+	//the Indicator, if any, stops the Agent before quitting the provider
+	if root != nil {
+		root.Quit()
+	} else {
+		p.Quit()
+	}
+}
+
+//parseTuiKeys converts a sequence of bytes read from a raw terminal into menu commands.
+func parseTuiKeys(input []byte) []int {
+	var keys []int
+	for idx := 0; idx < len(input); idx++ {
+		switch input[idx] {
+		case '\x1b':
+			//arrow keys are encoded as ESC [ A-D, while a lone ESC goes back
+			if idx+2 < len(input) && input[idx+1] == '[' {
+				switch input[idx+2] {
+				case 'A':
+					keys = append(keys, tuiKeyUp)
+				case 'B':
+					keys = append(keys, tuiKeyDown)
+				case 'C':
+					keys = append(keys, tuiKeyEnter)
+				case 'D':
+					keys = append(keys, tuiKeyBack)
+				}
+				idx += 2
+			} else {
+				keys = append(keys, tuiKeyBack)
+			}
+		case 'k':
+			keys = append(keys, tuiKeyUp)
+		case 'j':
+			keys = append(keys, tuiKeyDown)
+		case '\r', '\n', ' ', 'l':
+			keys = append(keys, tuiKeyEnter)
+		case 'h', '\x7f', '\b':
+			keys = append(keys, tuiKeyBack)
+		case '\x03', '\x04':
+			keys = append(keys, tuiKeyQuit)
+		}
+	}
+	return keys
+}
+
+//handleKey executes a menu command.
+func (p *tuiProvider) handleKey(key int) {
+	p.tree.mutex.Lock()
+	defer p.redraw()
+	defer p.tree.mutex.Unlock()
+	p.validatePath()
+	entries := p.currentEntries()
+	switch key {
+	case tuiKeyUp:
+		p.moveCursor(entries, -1)
+	case tuiKeyDown:
+		p.moveCursor(entries, 1)
+	case tuiKeyBack:
+		if len(p.path) > 0 {
+			p.path = p.path[:len(p.path)-1]
+			p.cursor = 0
+			p.moveCursor(p.currentEntries(), 0)
+		}
+	case tuiKeyEnter:
+		if p.cursor >= len(entries) || !entries[p.cursor].selectable() {
+			return
+		}
+		item := entries[p.cursor]
+		if item.hasVisibleChildren() {
+			p.path = append(p.path, item)
+			p.cursor = 0
+			p.moveCursor(p.currentEntries(), 0)
+			return
+		}
+		//the click is dropped if the previous ones have not been handled yet
+		select {
+		case item.clickChan <- struct{}{}:
+		default:
+		}
+	}
+}
+
+//validatePath closes the submenus whose parent entry is no longer available.
+//The caller must hold the itemTree mutex.
+func (p *tuiProvider) validatePath() {
+	for idx, item := range p.path {
+		if !item.visible || !item.hasVisibleChildren() {
+			p.path = p.path[:idx]
+			p.cursor = 0
+			return
+		}
+	}
+}
+
+//currentEntries returns the visible entries of the (sub)menu currently opened.
+//The caller must hold the itemTree mutex.
+func (p *tuiProvider) currentEntries() []*treeItem {
+	items := p.tree.items
+	if len(p.path) > 0 {
+		items = p.path[len(p.path)-1].children
+	}
+	var entries []*treeItem
+	for _, item := range items {
+		if item.visible {
+			entries = append(entries, item)
+		}
+	}
+	return entries
+}
+
+//moveCursor moves the cursor by delta positions, skipping the entries that cannot be selected.
+//With delta == 0, the cursor is moved to the nearest selectable entry. The caller must hold the itemTree mutex.
+func (p *tuiProvider) moveCursor(entries []*treeItem, delta int) {
+	step := delta
+	if step == 0 {
+		step = 1
+	}
+	if p.cursor >= len(entries) {
+		p.cursor = len(entries) - 1
+	}
+	for idx := p.cursor + delta; idx >= 0 && idx < len(entries); idx += step {
+		if entries[idx].selectable() {
+			p.cursor = idx
+			return
+		}
+	}
+	if delta == 0 {
+		for idx := p.cursor; idx >= 0 && idx < len(entries); idx-- {
+			if entries[idx].selectable() {
+				p.cursor = idx
+				return
+			}
+		}
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+}
+
+//lines returns the text lines representing the current state of the menu, or the current dialog if any.
+func (p *tuiProvider) lines() []string {
+	p.tree.mutex.Lock()
+	defer p.tree.mutex.Unlock()
+	if p.dialog != nil {
+		return p.dialog.lines()
+	}
+	p.validatePath()
+	entries := p.currentEntries()
+	p.moveCursor(entries, 0)
+	breadcrumb := []string{"Liqo Agent"}
+	if p.tree.title != "" {
+		breadcrumb[0] += " " + p.tree.title
+	}
+	for _, item := range p.path {
+		breadcrumb = append(breadcrumb, strings.TrimSpace(item.title))
+	}
+	lines := []string{strings.Join(breadcrumb, tuiBreadcrumbSep), tuiSeparator}
+	tooltip := ""
+	for idx, item := range entries {
+		if item.separator {
+			lines = append(lines, tuiNoCursor+tuiSeparator)
+			continue
+		}
+		cursor := tuiNoCursor
+		if idx == p.cursor && item.selectable() {
+			cursor = tuiCursor
+			tooltip = item.tooltip
+		}
+		line := item.title
+		if item.checkbox {
+			if item.checked {
+				line = tuiChecked + line
+			} else {
+				line = tuiUnchecked + line
+			}
+		}
+		if item.hasVisibleChildren() {
+			line += tuiSubmenu
+		}
+		if item.disabled {
+			line = tuiDisabledStart + line + tuiFormattingStop
+		}
+		lines = append(lines, cursor+line)
+	}
+	lines = append(lines, tuiSeparator)
+	if tooltip != "" {
+		lines = append(lines, tooltip)
+	}
+	if p.status != "" {
+		lines = append(lines, p.status)
+	}
+	return append(lines, tuiHelp)
+}
@@ -0,0 +1,134 @@
+package app_indicator
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTuiProvider(t *testing.T) {
+	p := newTuiProvider(strings.NewReader(""), &bytes.Buffer{})
+	p.SetTitle("(ON)")
+	header := p.AddMenuItem(false)
+	header.SetTitle("LIQO")
+	header.Disable()
+	header.Show()
+	p.AddSeparator()
+	quick := p.AddMenuItem(false)
+	quick.SetTitle("Quick")
+	quick.SetTooltip("a quick action")
+	quick.Show()
+	action := p.AddMenuItem(false)
+	action.SetTitle("Action")
+	action.Show()
+	option := p.AddSubMenuItem(action, true)
+	option.SetTitle("Option")
+	option.Show()
+	hidden := p.AddMenuItem(false)
+	hidden.SetTitle("Hidden")
+	//the cursor skips the disabled header, and the hidden entries are not rendered
+	lines := p.lines()
+	assert.Equal(t, "Liqo Agent (ON)", lines[0], "wrong menu header")
+	assert.Contains(t, lines, tuiCursor+"Quick", "cursor not on the first selectable entry")
+	assert.Contains(t, lines, "a quick action", "tooltip of the selected entry not rendered")
+	assert.Contains(t, lines, tuiNoCursor+"Action"+tuiSubmenu, "submenu not rendered")
+	for _, l := range lines {
+		assert.NotContains(t, l, "Hidden", "hidden entry rendered")
+	}
+	//a click on an entry without submenu is sent to its channel
+	p.handleKey(tuiKeyEnter)
+	select {
+	case <-quick.(*treeItem).ClickedCh():
+	default:
+		t.Fatal("click not received")
+	}
+	//navigation into the submenu
+	p.handleKey(tuiKeyDown)
+	p.handleKey(tuiKeyEnter)
+	lines = p.lines()
+	assert.Equal(t, "Liqo Agent (ON)"+tuiBreadcrumbSep+"Action", lines[0], "submenu not opened")
+	assert.Contains(t, lines, tuiCursor+tuiUnchecked+"Option", "option not rendered")
+	option.Check()
+	assert.Contains(t, p.lines(), tuiCursor+tuiChecked+"Option", "checked option not rendered")
+	//the submenu is closed when its entries disappear
+	option.Hide()
+	assert.Equal(t, "Liqo Agent (ON)", p.lines()[0], "empty submenu still opened")
+	option.Show()
+	p.handleKey(tuiKeyEnter)
+	p.handleKey(tuiKeyBack)
+	assert.Equal(t, "Liqo Agent (ON)", p.lines()[0], "submenu not closed")
+}
+
+func TestParseTuiKeys(t *testing.T) {
+	assert.Equal(t, []int{tuiKeyUp, tuiKeyDown, tuiKeyEnter, tuiKeyBack, tuiKeyBack, tuiKeyBack, tuiKeyQuit},
+		parseTuiKeys([]byte("\x1b[A\x1b[B\rh\x1b[D\x1b\x03")), "key strokes not parsed")
+}
+
+//waitDialog waits for the tuiProvider to display a dialog with the given title.
+func waitDialog(t *testing.T, p *tuiProvider, title string) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		return p.lines()[0] == title
+	}, 5*time.Second, time.Millisecond, "dialog %s not displayed", title)
+}
+
+func TestTuiProviderDialogs(t *testing.T) {
+	UseMockedGuiProvider()
+	DestroyMockedIndicator()
+	p := newTuiProvider(strings.NewReader(""), &bytes.Buffer{})
+	item := p.AddMenuItem(false)
+	item.SetTitle("Quick")
+	item.Show()
+	//text input, with the proposed value edited by the user
+	answers := make(chan string, 1)
+	go func() {
+		input, _ := p.Prompt("PROMPT", "Insert a name", "ab")
+		answers <- input
+	}()
+	waitDialog(t, p, "PROMPT")
+	assert.Contains(t, p.lines(), tuiPrompt+"ab", "proposed value not rendered")
+	//the menu keys are typed in the dialog
+	assert.False(t, p.handleInput([]byte("\x7fjk\r")))
+	assert.Equal(t, "ajk", <-answers, "wrong text input")
+	assert.Contains(t, p.lines(), tuiCursor+"Quick", "menu not displayed after the dialog")
+	//choice among items
+	go func() {
+		choice, _ := p.Choose("CHOOSE", "Choose an item", []string{"a", "b", "c"})
+		answers <- choice
+	}()
+	waitDialog(t, p, "CHOOSE")
+	p.handleInput([]byte("\x1b[Bj\x1b[A\r"))
+	assert.Equal(t, "b", <-answers, "wrong choice")
+	//a confirmation proposes the negative answer, and ESC cancels the dialog
+	confirms := make(chan bool, 1)
+	go func() {
+		confirms <- p.Confirm("CONFIRM", "Are you sure?")
+	}()
+	waitDialog(t, p, "CONFIRM")
+	p.handleInput([]byte("\r"))
+	assert.False(t, <-confirms, "negative answer not proposed")
+	go func() {
+		confirms <- p.Confirm("CONFIRM", "Are you sure?")
+	}()
+	waitDialog(t, p, "CONFIRM")
+	p.handleInput([]byte("k\x1b"))
+	assert.False(t, <-confirms, "cancelled dialog confirmed")
+	//notifications and copied text are displayed in the status line
+	p.Notify("TITLE", "first\nline", "")
+	assert.Contains(t, p.lines(), "TITLE: first line", "notification not displayed")
+	assert.NoError(t, p.Copy("kubectl logs pod"))
+	assert.Contains(t, p.lines(), tuiCopied+"kubectl logs pod", "copied text not displayed")
+	//quitting cancels the current and the following dialogs
+	go func() {
+		input, ok := p.Prompt("PROMPT", "Insert a name", "ab")
+		assert.False(t, ok, "dialog not cancelled")
+		answers <- input
+	}()
+	waitDialog(t, p, "PROMPT")
+	assert.True(t, p.handleInput([]byte("\x03")), "quit not requested")
+	assert.Equal(t, "", <-answers)
+	_, ok := p.Prompt("PROMPT", "Insert a name", "ab")
+	assert.False(t, ok, "dialog displayed after quitting")
+}
@@ -0,0 +1,372 @@
+package app_indicator
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/skratchdot/open-golang/open"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+/*This file contains a web implementation of the GuiProviderInterface, which serves the Indicator menu to a
+browser tab over a loopback HTTP server. The menu is pushed to the page with server-sent events at each change,
+while the clicks are posted back to the provider.*/
+
+//GuiWeb is the name of the frontend that displays the Indicator menu in a browser tab, served on the
+//loopback interface.
+const GuiWeb = "web"
+
+func init() {
+	RegisterGuiProvider(GuiWeb, func() GuiProviderInterface {
+		return newWebProvider(os.Getenv(EnvLiqoGuiAddress), os.Stdout)
+	})
+}
+
+//EnvLiqoGuiAddress is the env var setting the local address of the web frontend. If not set, a free port
+//of the loopback interface is used. Addresses outside the loopback interface are refused.
+const EnvLiqoGuiAddress = "LIQO_AGENT_GUI_ADDRESS"
+
+//set of paths served by the web frontend
+const (
+	webPathPage   = "/"
+	webPathEvents = "/events"
+	webPathClick  = "/click"
+)
+
+//webProvider is a GuiProviderInterface that renders the menu in a browser tab.
+//
+//Since any local process can connect to the loopback interface, all the requests must carry the key
+//generated at startup, which is included in the URL opened in the browser.
+//The browser runs on the user desktop, so the dialogs are the desktop ones.
+type webProvider struct {
+	desktopDialogs
+	//out is where the URL of the web frontend is printed.
+	out io.Writer
+	//address is the local address the provider listens on.
+	address string
+	//key authorizes the requests to the provider.
+	key string
+	//tree contains the menu entries.
+	tree *itemTree
+	//clients contains the channels signaling the menu changes to each connected page.
+	clients map[chan struct{}]bool
+	//quitChan is closed when Quit() is called.
+	quitChan chan struct{}
+	quitOnce sync.Once
+	mutex    sync.Mutex
+}
+
+//webMenu is the representation of the menu pushed to the web page.
+type webMenu struct {
+	Title string    `json:"title"`
+	Items []webItem `json:"items"`
+}
+
+//webItem is the representation of a visible menu entry pushed to the web page.
+type webItem struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title,omitempty"`
+	Tooltip   string    `json:"tooltip,omitempty"`
+	Separator bool      `json:"separator,omitempty"`
+	Checkbox  bool      `json:"checkbox,omitempty"`
+	Checked   bool      `json:"checked,omitempty"`
+	Disabled  bool      `json:"disabled,omitempty"`
+	Items     []webItem `json:"items,omitempty"`
+}
+
+//newWebProvider creates a webProvider that will listen on address, printing its URL on out.
+func newWebProvider(address string, out io.Writer) *webProvider {
+	p := &webProvider{
+		out:      out,
+		address:  address,
+		clients:  make(map[chan struct{}]bool),
+		quitChan: make(chan struct{}),
+	}
+	if p.address == "" {
+		p.address = "127.0.0.1:0"
+	}
+	p.tree = newItemTree(p.notifyClients)
+	return p
+}
+
+func (p *webProvider) Run(onReady func(), onExit func()) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		_, _ = fmt.Fprintf(p.out, "Liqo Agent could not start the web frontend: %v\n", err)
+		return
+	}
+	p.key = hex.EncodeToString(key)
+	if err := checkLoopbackAddress(p.address); err != nil {
+		_, _ = fmt.Fprintf(p.out, "Liqo Agent could not start the web frontend: %v\n", err)
+		return
+	}
+	listener, err := net.Listen("tcp", p.address)
+	if err != nil {
+		_, _ = fmt.Fprintf(p.out, "Liqo Agent could not start the web frontend: %v\n", err)
+		return
+	}
+	server := &http.Server{Handler: p.handler()}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	url := fmt.Sprintf("http://%s%s?key=%s", listener.Addr().String(), webPathPage, p.key)
+	_, _ = fmt.Fprintf(p.out, "Liqo Agent is available at %s\n", url)
+	_ = open.Start(url)
+	go onReady()
+	<-p.quitChan
+	onExit()
+	_ = server.Close()
+}
+
+//checkLoopbackAddress checks that address (host:port) belongs to the loopback interface, so that the
+//web frontend is not exposed on the network.
+func checkLoopbackAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("address %s is not on the loopback interface", address)
+	}
+	return nil
+}
+
+func (p *webProvider) Quit() {
+	p.quitOnce.Do(func() {
+		close(p.quitChan)
+	})
+}
+
+func (p *webProvider) AddSeparator() {
+	p.tree.addSeparator()
+}
+
+//SetIcon is a no-op: the status of the Agent is displayed by the menu header.
+func (p *webProvider) SetIcon(_ []byte) {}
+
+func (p *webProvider) SetTitle(title string) {
+	p.tree.setTitle(title)
+}
+
+func (p *webProvider) AddMenuItem(withCheckbox bool) Item {
+	return p.tree.addItem(nil, withCheckbox)
+}
+
+func (p *webProvider) AddSubMenuItem(parent Item, withCheckbox bool) Item {
+	if parent == nil {
+		panic("invalid creation of child Item with nil parent")
+	}
+	return p.tree.addItem(parent.(*treeItem), withCheckbox)
+}
+
+func (p *webProvider) Mocked() bool {
+	return false
+}
+
+//notifyClients signals a menu change to the connected pages. Consecutive signals are coalesced.
+func (p *webProvider) notifyClients() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for ch := range p.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+//menu returns the representation of the visible menu entries.
+func (p *webProvider) menu() *webMenu {
+	p.tree.mutex.RLock()
+	defer p.tree.mutex.RUnlock()
+	return &webMenu{
+		Title: p.tree.title,
+		Items: webItems(p.tree.items),
+	}
+}
+
+//webItems converts the visible treeItems into webItems. The caller must hold the itemTree mutex.
+func webItems(items []*treeItem) []webItem {
+	result := make([]webItem, 0, len(items))
+	for _, item := range items {
+		if !item.visible {
+			continue
+		}
+		result = append(result, webItem{
+			ID:        item.id,
+			Title:     item.title,
+			Tooltip:   item.tooltip,
+			Separator: item.separator,
+			Checkbox:  item.checkbox,
+			Checked:   item.checked,
+			Disabled:  item.disabled,
+			Items:     webItems(item.children),
+		})
+	}
+	return result
+}
+
+//handler returns the http.Handler serving the web frontend.
+func (p *webProvider) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(webPathPage, p.authorize(p.servePage))
+	mux.HandleFunc(webPathEvents, p.authorize(p.serveEvents))
+	mux.HandleFunc(webPathClick, p.authorize(p.serveClick))
+	return mux
+}
+
+//authorize serves only the requests carrying the key of the webProvider.
+func (p *webProvider) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if p.key == "" || subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("key")), []byte(p.key)) != 1 {
+			http.Error(w, "invalid Liqo Agent key", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+//servePage serves the web page displaying the menu.
+func (p *webProvider) servePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != webPathPage {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = io.WriteString(w, webPage)
+}
+
+//serveEvents pushes the menu to the page at each change, until the page or the provider is closed.
+func (p *webProvider) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan struct{}, 1)
+	p.mutex.Lock()
+	p.clients[ch] = true
+	p.mutex.Unlock()
+	defer func() {
+		p.mutex.Lock()
+		delete(p.clients, ch)
+		p.mutex.Unlock()
+	}()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	for {
+		data, err := json.Marshal(p.menu())
+		if err != nil {
+			return
+		}
+		if _, err = fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+		select {
+		case <-ch:
+		case <-r.Context().Done():
+			return
+		case <-p.quitChan:
+			return
+		}
+	}
+}
+
+//serveClick routes the click on a menu entry to its MenuNode.
+func (p *webProvider) serveClick(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || !p.tree.click(id) {
+		http.Error(w, "invalid menu entry", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+//webPage is the web page displaying the menu. The key of the webProvider is read from the page URL.
+const webPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Liqo Agent</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 2em auto; }
+ul { list-style: none; padding-left: 1em; }
+li { margin: 0.3em 0; }
+button { border: none; background: none; font: inherit; cursor: pointer; text-align: left; }
+button:hover { text-decoration: underline; }
+.disabled { color: #888; }
+summary { cursor: pointer; }
+hr { border: none; border-top: 1px solid #ccc; }
+</style>
+</head>
+<body>
+<h2 id="title">Liqo Agent</h2>
+<ul id="menu"></ul>
+<script>
+const key = new URLSearchParams(window.location.search).get("key");
+const opened = new Set();
+
+function render(items) {
+  const list = document.createElement("ul");
+  for (const item of items) {
+    const entry = document.createElement("li");
+    if (item.separator) {
+      entry.appendChild(document.createElement("hr"));
+      list.appendChild(entry);
+      continue;
+    }
+    let label = item.title || "";
+    if (item.checkbox) {
+      label = (item.checked ? "☑ " : "☐ ") + label;
+    }
+    if (item.items && item.items.length > 0) {
+      const submenu = document.createElement("details");
+      submenu.open = opened.has(item.id);
+      submenu.ontoggle = () => submenu.open ? opened.add(item.id) : opened.delete(item.id);
+      const summary = document.createElement("summary");
+      summary.textContent = label;
+      submenu.appendChild(summary);
+      submenu.appendChild(render(item.items));
+      entry.appendChild(submenu);
+    } else if (item.disabled) {
+      entry.textContent = label;
+      entry.className = "disabled";
+    } else {
+      const button = document.createElement("button");
+      button.textContent = label;
+      button.onclick = () => fetch("click?key=" + key + "&id=" + item.id, {method: "POST"});
+      entry.appendChild(button);
+    }
+    entry.title = item.tooltip || "";
+    list.appendChild(entry);
+  }
+  return list;
+}
+
+const events = new EventSource("events?key=" + key);
+events.onmessage = (event) => {
+  const menu = JSON.parse(event.data);
+  document.getElementById("title").textContent = "Liqo Agent " + menu.title;
+  const list = render(menu.items);
+  list.id = "menu";
+  document.getElementById("menu").replaceWith(list);
+};
+</script>
+</body>
+</html>
+`
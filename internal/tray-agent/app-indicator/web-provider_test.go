@@ -0,0 +1,120 @@
+package app_indicator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebProvider(t *testing.T) {
+	p := newWebProvider("", &bytes.Buffer{})
+	p.key = "test-key"
+	server := httptest.NewServer(p.handler())
+	defer server.Close()
+	defer p.Quit()
+	p.SetTitle("(ON)")
+	header := p.AddMenuItem(false)
+	header.SetTitle("LIQO")
+	header.Disable()
+	header.Show()
+	action := p.AddMenuItem(false)
+	action.SetTitle("Action")
+	action.Show()
+	option := p.AddSubMenuItem(action, true)
+	option.SetTitle("Option")
+	option.Show()
+	hidden := p.AddMenuItem(false)
+	hidden.SetTitle("Hidden")
+	//requests without the key are refused
+	resp, err := http.Get(server.URL + webPathPage)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, "page served without key")
+		_ = resp.Body.Close()
+	}
+	resp, err = http.Get(server.URL + webPathPage + "?key=" + p.key)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "page not served")
+		_ = resp.Body.Close()
+	}
+	//the menu is pushed when the page connects and at each change
+	resp, err = http.Get(server.URL + webPathEvents + "?key=" + p.key)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	events := bufio.NewReader(resp.Body)
+	menu := readWebMenu(t, events)
+	assert.Equal(t, "(ON)", menu.Title, "wrong menu title")
+	if assert.Len(t, menu.Items, 2, "hidden entries pushed") {
+		assert.True(t, menu.Items[0].Disabled, "disabled entry not pushed")
+		if assert.Len(t, menu.Items[1].Items, 1, "submenu not pushed") {
+			assert.Equal(t, "Option", menu.Items[1].Items[0].Title)
+			assert.False(t, menu.Items[1].Items[0].Checked)
+		}
+	}
+	option.Check()
+	menu = readWebMenu(t, events)
+	if assert.Len(t, menu.Items, 2) && assert.Len(t, menu.Items[1].Items, 1) {
+		assert.True(t, menu.Items[1].Items[0].Checked, "checked option not pushed")
+	}
+	//the clicks are routed to the entry channel
+	click := func(item Item) int {
+		resp, err := http.Post(fmt.Sprintf("%s%s?key=%s&id=%d", server.URL, webPathClick, p.key,
+			item.(*treeItem).id), "", nil)
+		if !assert.NoError(t, err) {
+			return 0
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusNoContent, click(option), "click refused")
+	select {
+	case <-option.(*treeItem).ClickedCh():
+	default:
+		t.Fatal("click not received")
+	}
+	assert.Equal(t, http.StatusBadRequest, click(header), "click on a disabled entry accepted")
+	assert.Equal(t, http.StatusBadRequest, click(hidden), "click on a hidden entry accepted")
+	assert.Equal(t, http.StatusBadRequest, click(action), "click on a submenu accepted")
+}
+
+//readWebMenu reads the next menu pushed by a webProvider.
+func TestCheckLoopbackAddress(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:0", "127.0.0.2:8080", "[::1]:8080", "localhost:8080"} {
+		assert.NoError(t, checkLoopbackAddress(address), "loopback address %s refused", address)
+	}
+	for _, address := range []string{":8080", "0.0.0.0:8080", "[::]:8080", "192.168.1.10:8080", "example.com:80",
+		"127.0.0.1"} {
+		assert.Error(t, checkLoopbackAddress(address), "address %s accepted", address)
+	}
+	//the web frontend does not start on a public address
+	out := &bytes.Buffer{}
+	p := newWebProvider("0.0.0.0:0", out)
+	p.Run(func() {}, func() {})
+	assert.Contains(t, out.String(), "could not start", "web frontend started on a public address")
+}
+
+func readWebMenu(t *testing.T, events *bufio.Reader) *webMenu {
+	for {
+		line, err := events.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		menu := &webMenu{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), menu); err != nil {
+			t.Fatal(err)
+		}
+		return menu
+	}
+}
@@ -1,4 +1,9 @@
 /*
 Package test contains useful functions to test code in github.com/liqotech/liqo-agent/internal/tray-agent/... packages.
+
+A Harness runs the Agent logic on a mocked Indicator connected to a FakeCluster, whose resources reach the Agent
+through the informers of the mocked AgentController. The Harness dispatches the resulting events synchronously, so
+tests can drive it directly or with a Scenario written in YAML (see RunScenario), which also checks the Agent status,
+the notifications and the menu after each step.
 */
 package test
@@ -0,0 +1,465 @@
+package test
+
+import (
+	"context"
+	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
+	clusterConfig "github.com/liqotech/liqo/apis/config/v1alpha1"
+	"github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	sharing "github.com/liqotech/liqo/apis/sharing/v1alpha1"
+	"github.com/liqotech/liqo/pkg/discovery"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+)
+
+// set of labels and annotations Liqo applies to the resources of the home cluster
+const (
+	//virtualNodeLabel is the label of the Liqo virtual nodes, in the 'key=value' format.
+	virtualNodeLabel = "type=virtual-node"
+	//clusterIDAnnotation is the annotation of a virtual node containing the ClusterID of the foreign cluster.
+	clusterIDAnnotation = "cluster-id"
+	//offloadingLabel is the label enabling the offloading of a namespace.
+	offloadingLabel = "liqo.io/enabled"
+	//appLabel is the label of the resources of the applications launched by the Agent.
+	appLabel = "liqo.io/agent-app"
+	//incomingPodLabel is the label of the pods offloaded to the home cluster by a foreign cluster.
+	incomingPodLabel = "virtualkubelet.liqo.io/outgoing"
+)
+
+//clusterConfigName is the name of the ClusterConfig of the FakeCluster.
+const clusterConfigName = "testConfig"
+
+//FakeCluster is a fake Liqo home cluster backed by the fake CRD clients of a mocked client.AgentController.
+//Its resources are created, updated and deleted through the CRD API, so that the Agent receives the events
+//by the same informer path it uses with a real cluster.
+//
+//The nodes and pods are created through the fake kubernetes client of the AgentController.
+type FakeCluster struct {
+	ctrl *client.AgentController
+	//nodes contains the current nodes, by name.
+	nodes map[string]NodeState
+	//pods contains the current pods, by 'namespace/name'.
+	pods map[string]PodState
+}
+
+//NewFakeCluster creates a FakeCluster for a mocked client.AgentController whose caches are running.
+func NewFakeCluster(ctrl *client.AgentController) *FakeCluster {
+	return &FakeCluster{
+		ctrl:  ctrl,
+		nodes: make(map[string]NodeState),
+		pods:  make(map[string]PodState),
+	}
+}
+
+//PeeringState describes the state of a peering (outgoing or incoming) with a foreign cluster.
+type PeeringState struct {
+	//Joined specifies whether the peering has been requested.
+	Joined bool `yaml:"joined"`
+	//Advertisement is the phase of the Advertisement exchanged for the peering (e.g. 'Accepted').
+	Advertisement sharing.AdvPhase `yaml:"advertisement"`
+}
+
+//ForeignClusterState describes the state of a ForeignCluster.
+type ForeignClusterState struct {
+	ClusterID   string `yaml:"clusterID"`
+	ClusterName string `yaml:"clusterName"`
+	//LanDiscovered specifies whether the foreign cluster has been discovered in the LAN.
+	LanDiscovered bool `yaml:"lanDiscovered"`
+	//Trust is the TrustMode of the foreign cluster (e.g. 'Trusted').
+	Trust discovery.TrustMode `yaml:"trust"`
+	//Auth is the status of the authentication of the home cluster on the foreign cluster (e.g. 'Pending').
+	Auth     discovery.AuthStatus `yaml:"auth"`
+	Outgoing PeeringState         `yaml:"outgoing"`
+	Incoming PeeringState         `yaml:"incoming"`
+}
+
+//ForeignCluster returns the ForeignCluster CR described by the ForeignClusterState.
+func (s *ForeignClusterState) ForeignCluster() *v1alpha1.ForeignCluster {
+	fc := CreateForeignCluster(s.ClusterID, s.ClusterName)
+	if s.LanDiscovered {
+		fc.Spec.DiscoveryType = discovery.LanDiscovery
+	}
+	fc.Spec.TrustMode = s.Trust
+	fc.Status.AuthStatus = s.Auth
+	fc.Status.Outgoing.Joined = s.Outgoing.Joined
+	fc.Status.Outgoing.AdvertisementStatus = s.Outgoing.Advertisement
+	if s.Outgoing.Advertisement != "" {
+		fc.Status.Outgoing.Advertisement = &corev1.ObjectReference{Name: AdvertisementName(s.ClusterID)}
+	}
+	fc.Status.Incoming.Joined = s.Incoming.Joined
+	fc.Status.Incoming.AdvertisementStatus = s.Incoming.Advertisement
+	return fc
+}
+
+//AdvertisementState describes the resources offered by a foreign cluster with its Advertisement.
+type AdvertisementState struct {
+	ClusterID string `yaml:"clusterID"`
+	CPU       string `yaml:"cpu"`
+	Memory    string `yaml:"memory"`
+}
+
+//Advertisement returns the Advertisement CR described by the AdvertisementState.
+func (s *AdvertisementState) Advertisement() (*sharing.Advertisement, error) {
+	hard, err := resourceList(map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    s.CPU,
+		corev1.ResourceMemory: s.Memory,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sharing.Advertisement{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: AdvertisementName(s.ClusterID),
+		},
+		Spec: sharing.AdvertisementSpec{
+			ClusterId:     s.ClusterID,
+			ResourceQuota: corev1.ResourceQuotaSpec{Hard: hard},
+		},
+	}, nil
+}
+
+//AdvertisementName returns the name of the Advertisement received from the foreign cluster 'clusterID'.
+func AdvertisementName(clusterID string) string {
+	return "advertisement-" + clusterID
+}
+
+//ApplyForeignCluster creates the ForeignCluster described by 'state' or, if it already exists, updates it.
+func (c *FakeCluster) ApplyForeignCluster(state *ForeignClusterState) error {
+	api := c.ctrl.Controller(client.CRForeignCluster).Resource(string(client.CRForeignCluster))
+	fc := state.ForeignCluster()
+	if _, err := api.Get(fc.Name, metav1.GetOptions{}); kerrors.IsNotFound(err) {
+		_, err = api.Create(fc, metav1.CreateOptions{})
+		return err
+	}
+	_, err := api.Update(fc.Name, fc, metav1.UpdateOptions{})
+	return err
+}
+
+//DeleteForeignCluster deletes the ForeignCluster of the foreign cluster 'clusterID'.
+func (c *FakeCluster) DeleteForeignCluster(clusterID string) error {
+	api := c.ctrl.Controller(client.CRForeignCluster).Resource(string(client.CRForeignCluster))
+	return api.Delete(clusterID, metav1.DeleteOptions{})
+}
+
+//ApplyAdvertisement creates the Advertisement described by 'state' or, if it already exists, updates it.
+func (c *FakeCluster) ApplyAdvertisement(state *AdvertisementState) error {
+	api := c.ctrl.Controller(client.CRAdvertisement).Resource(string(client.CRAdvertisement))
+	adv, err := state.Advertisement()
+	if err != nil {
+		return err
+	}
+	if _, err := api.Get(adv.Name, metav1.GetOptions{}); kerrors.IsNotFound(err) {
+		_, err = api.Create(adv, metav1.CreateOptions{})
+		return err
+	}
+	_, err = api.Update(adv.Name, adv, metav1.UpdateOptions{})
+	return err
+}
+
+//ClusterConfigState describes the configuration of the home cluster, stored in its ClusterConfig.
+type ClusterConfigState struct {
+	ClusterName string `yaml:"clusterName"`
+	//Sharing specifies whether the home cluster offers its resources to the peers.
+	Sharing bool `yaml:"sharing"`
+	//SharingPercentage is the percentage of the free resources of the home cluster offered to each peer.
+	SharingPercentage int32 `yaml:"sharingPercentage"`
+	//Discovery specifies whether the home cluster discovers the peers in the LAN.
+	Discovery bool `yaml:"discovery"`
+	//Advertise specifies whether the home cluster advertises itself in the LAN.
+	Advertise         bool `yaml:"advertise"`
+	AutoJoin          bool `yaml:"autoJoin"`
+	AutoJoinUntrusted bool `yaml:"autoJoinUntrusted"`
+}
+
+//ClusterConfig returns the ClusterConfig CR described by the ClusterConfigState.
+func (s *ClusterConfigState) ClusterConfig() *clusterConfig.ClusterConfig {
+	conf := &clusterConfig.ClusterConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: clusterConfigName,
+		},
+	}
+	spec := &conf.Spec
+	spec.AgentConfig.DashboardConfig.Namespace = "liqo"
+	spec.AgentConfig.DashboardConfig.AppLabel = "liqodash"
+	spec.AdvertisementConfig.OutgoingConfig.EnableBroadcaster = s.Sharing
+	spec.AdvertisementConfig.OutgoingConfig.ResourceSharingPercentage = s.SharingPercentage
+	spec.DiscoveryConfig.ClusterName = s.ClusterName
+	spec.DiscoveryConfig.EnableDiscovery = s.Discovery
+	spec.DiscoveryConfig.EnableAdvertisement = s.Advertise
+	spec.DiscoveryConfig.AutoJoin = s.AutoJoin
+	spec.DiscoveryConfig.AutoJoinUntrusted = s.AutoJoinUntrusted
+	return conf
+}
+
+//NodeState describes a node of the home cluster.
+type NodeState struct {
+	Name string `yaml:"name"`
+	//ClusterID is the ClusterID of the foreign cluster represented by a virtual node. It is empty for
+	//the physical nodes.
+	ClusterID string `yaml:"clusterID"`
+	Ready     bool   `yaml:"ready"`
+	//CPU, Memory and Pods are the allocatable resources of the node.
+	CPU    string `yaml:"cpu"`
+	Memory string `yaml:"memory"`
+	Pods   string `yaml:"pods"`
+}
+
+//Virtual returns whether the node is a Liqo virtual node.
+func (s *NodeState) Virtual() bool {
+	return s.ClusterID != ""
+}
+
+//Node returns the Node described by the NodeState.
+func (s *NodeState) Node() (*corev1.Node, error) {
+	allocatable, err := resourceList(map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    s.CPU,
+		corev1.ResourceMemory: s.Memory,
+		corev1.ResourcePods:   s.Pods,
+	})
+	if err != nil {
+		return nil, err
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: s.Name,
+		},
+		Status: corev1.NodeStatus{
+			Allocatable: allocatable,
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionFalse},
+			},
+		},
+	}
+	if s.Ready {
+		node.Status.Conditions[0].Status = corev1.ConditionTrue
+	}
+	if s.Virtual() {
+		label := strings.SplitN(virtualNodeLabel, "=", 2)
+		node.Labels = map[string]string{label[0]: label[1]}
+		node.Annotations = map[string]string{clusterIDAnnotation: s.ClusterID}
+	}
+	return node, nil
+}
+
+//NamespaceState describes a namespace of the home cluster.
+type NamespaceState struct {
+	Name string `yaml:"name"`
+	//Offloading specifies whether the pods of the namespace can be offloaded to the peers.
+	Offloading bool `yaml:"offloading"`
+}
+
+//Namespace returns the Namespace described by the NamespaceState.
+func (s *NamespaceState) Namespace() *corev1.Namespace {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: s.Name,
+		},
+	}
+	if s.Offloading {
+		ns.Labels = map[string]string{offloadingLabel: "true"}
+	}
+	return ns
+}
+
+//PodState describes a pod of the home cluster. The labels and the node of a pod are not expected to change
+//once it is created.
+type PodState struct {
+	Namespace string `yaml:"namespace"`
+	Name      string `yaml:"name"`
+	//Node is the name of the node the pod is scheduled on.
+	Node  string          `yaml:"node"`
+	Phase corev1.PodPhase `yaml:"phase"`
+	//App is the name of the application launched by the Agent the pod belongs to (if any).
+	App string `yaml:"app"`
+	//Incoming specifies whether the pod has been offloaded to the home cluster by a peer. In that case,
+	//the namespace of the pod must be reflected by the peer (i.e. its name ends with the peer ClusterID).
+	Incoming bool `yaml:"incoming"`
+	//CPU and Memory are the resources requested by the pod.
+	CPU    string `yaml:"cpu"`
+	Memory string `yaml:"memory"`
+	//Terminating specifies whether the pod is being deleted, i.e. it has a DeletionTimestamp.
+	Terminating bool `yaml:"terminating"`
+}
+
+//Key returns the 'namespace/name' key of the pod.
+func (s *PodState) Key() string {
+	return s.Namespace + "/" + s.Name
+}
+
+//Pod returns the Pod described by the PodState.
+func (s *PodState) Pod() (*corev1.Pod, error) {
+	requests, err := resourceList(map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    s.CPU,
+		corev1.ResourceMemory: s.Memory,
+	})
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[string]string)
+	if s.App != "" {
+		labels[appLabel] = s.App
+	}
+	if s.Incoming {
+		labels[incomingPodLabel] = "true"
+	}
+	var deletionTimestamp *metav1.Time
+	if s.Terminating {
+		now := metav1.Now()
+		deletionTimestamp = &now
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              s.Name,
+			Namespace:         s.Namespace,
+			Labels:            labels,
+			DeletionTimestamp: deletionTimestamp,
+		},
+		Spec: corev1.PodSpec{
+			NodeName: s.Node,
+			Containers: []corev1.Container{
+				{
+					Name:      s.Name,
+					Image:     "nginx",
+					Resources: corev1.ResourceRequirements{Requests: requests},
+				},
+			},
+		},
+		Status: corev1.PodStatus{Phase: s.Phase},
+	}, nil
+}
+
+//resourceList converts the literal representations of a set of quantities into a ResourceList.
+//Empty quantities are ignored.
+func resourceList(quantities map[corev1.ResourceName]string) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+	for name, quantity := range quantities {
+		if quantity == "" {
+			continue
+		}
+		q, err := resource.ParseQuantity(quantity)
+		if err != nil {
+			return nil, err
+		}
+		list[name] = q
+	}
+	return list, nil
+}
+
+//ApplyClusterConfig creates the ClusterConfig described by 'state' or, if it already exists, updates it.
+func (c *FakeCluster) ApplyClusterConfig(state *ClusterConfigState) error {
+	api := c.ctrl.Controller(client.CRClusterConfig).Resource(string(client.CRClusterConfig))
+	conf := state.ClusterConfig()
+	if _, err := api.Get(conf.Name, metav1.GetOptions{}); kerrors.IsNotFound(err) {
+		_, err = api.Create(conf, metav1.CreateOptions{})
+		return err
+	}
+	_, err := api.Update(conf.Name, conf, metav1.UpdateOptions{})
+	return err
+}
+
+//DeleteClusterConfig deletes the ClusterConfig of the home cluster.
+func (c *FakeCluster) DeleteClusterConfig() error {
+	api := c.ctrl.Controller(client.CRClusterConfig).Resource(string(client.CRClusterConfig))
+	return api.Delete(clusterConfigName, metav1.DeleteOptions{})
+}
+
+//Node returns the state of a node of the FakeCluster.
+func (c *FakeCluster) Node(name string) (state NodeState, present bool) {
+	state, present = c.nodes[name]
+	return
+}
+
+//PodsOnNode returns the number of pods scheduled on a node of the FakeCluster.
+func (c *FakeCluster) PodsOnNode(name string) int {
+	count := 0
+	for _, pod := range c.pods {
+		if pod.Node == name {
+			count++
+		}
+	}
+	return count
+}
+
+//Pod returns the state of a pod of the FakeCluster, given its 'namespace/name' key.
+func (c *FakeCluster) Pod(key string) (state PodState, present bool) {
+	state, present = c.pods[key]
+	return
+}
+
+//ApplyNode creates the node described by 'state' or, if it already exists, updates it.
+func (c *FakeCluster) ApplyNode(state *NodeState) error {
+	node, err := state.Node()
+	if err != nil {
+		return err
+	}
+	nodes := c.ctrl.KubeClient().CoreV1().Nodes()
+	if _, present := c.nodes[state.Name]; present {
+		_, err = nodes.Update(context.TODO(), node, metav1.UpdateOptions{})
+	} else {
+		_, err = nodes.Create(context.TODO(), node, metav1.CreateOptions{})
+	}
+	if err == nil {
+		c.nodes[state.Name] = *state
+	}
+	return err
+}
+
+//DeleteNode deletes a node.
+func (c *FakeCluster) DeleteNode(name string) error {
+	err := c.ctrl.KubeClient().CoreV1().Nodes().Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if err == nil {
+		delete(c.nodes, name)
+	}
+	return err
+}
+
+//ApplyNamespace creates the namespace described by 'state' or, if it already exists, updates it.
+func (c *FakeCluster) ApplyNamespace(state *NamespaceState) error {
+	namespaces := c.ctrl.KubeClient().CoreV1().Namespaces()
+	ns := state.Namespace()
+	if _, err := namespaces.Get(context.TODO(), ns.Name, metav1.GetOptions{}); kerrors.IsNotFound(err) {
+		_, err = namespaces.Create(context.TODO(), ns, metav1.CreateOptions{})
+		return err
+	}
+	_, err := namespaces.Update(context.TODO(), ns, metav1.UpdateOptions{})
+	return err
+}
+
+//DeleteNamespace deletes a namespace. As for the fake clientset, its pods are not deleted.
+func (c *FakeCluster) DeleteNamespace(name string) error {
+	return c.ctrl.KubeClient().CoreV1().Namespaces().Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
+
+//ApplyPod creates the pod described by 'state' or, if it already exists, updates it.
+func (c *FakeCluster) ApplyPod(state *PodState) error {
+	pod, err := state.Pod()
+	if err != nil {
+		return err
+	}
+	pods := c.ctrl.KubeClient().CoreV1().Pods(state.Namespace)
+	if _, present := c.pods[state.Key()]; present {
+		_, err = pods.Update(context.TODO(), pod, metav1.UpdateOptions{})
+	} else {
+		_, err = pods.Create(context.TODO(), pod, metav1.CreateOptions{})
+	}
+	if err == nil {
+		c.pods[state.Key()] = *state
+	}
+	return err
+}
+
+//DeletePod deletes a pod, given its 'namespace/name' key.
+func (c *FakeCluster) DeletePod(key string) error {
+	state, present := c.pods[key]
+	if !present {
+		return kerrors.NewNotFound(corev1.Resource("pods"), key)
+	}
+	err := c.ctrl.KubeClient().CoreV1().Pods(state.Namespace).Delete(context.TODO(), state.Name,
+		metav1.DeleteOptions{})
+	if err == nil {
+		delete(c.pods, key)
+	}
+	return err
+}
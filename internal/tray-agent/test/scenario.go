@@ -0,0 +1,550 @@
+package test
+
+import (
+	"fmt"
+	"github.com/liqotech/liqo-agent/internal/tray-agent/agent/client"
+	app "github.com/liqotech/liqo-agent/internal/tray-agent/app-indicator"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// eventTimeout is the maximum time the Harness waits for the Agent to handle an event.
+const eventTimeout = 5 * time.Second
+
+/*
+Scenario is a sequence of changes on a FakeCluster, together with the state of the Agent expected after each of
+them. Scenarios are written in YAML files:
+
+	golden: true
+	steps:
+	  - name: peer discovered
+	    foreignClusters:
+	      - clusterID: cl1
+	        clusterName: test1
+	        auth: Pending
+	    expect:
+	      peers: 1
+	      notifications: []
+	      menu:
+	        - "❱ Peers (1)"
+	  - name: agent off
+	    click: [Q_ON_OFF]
+	    expect:
+	      running: "OFF"
+
+If Golden is true, the snapshots of the Indicator (see app-indicator.Indicator.Snapshot) taken at startup and after
+each step are also compared with the golden file having the same path of the scenario and the '.golden' extension.
+*/
+type Scenario struct {
+	Golden bool `yaml:"golden"`
+	//Catalog contains the templates of the application catalog available at startup.
+	Catalog []client.AppTemplate `yaml:"catalog"`
+	//Initial contains the changes applied to the cluster before the Agent logic starts.
+	Initial ClusterChanges `yaml:"initial"`
+	Steps   []ScenarioStep `yaml:"steps"`
+}
+
+// ClusterChanges are changes on a FakeCluster, applied in the order of the fields declaration.
+type ClusterChanges struct {
+	//ClusterConfig is the configuration of the home cluster to create or update.
+	ClusterConfig *ClusterConfigState `yaml:"clusterConfig"`
+	//Advertisements are the Advertisements to create or update.
+	Advertisements []AdvertisementState `yaml:"advertisements"`
+	//ForeignClusters are the ForeignClusters to create or update.
+	ForeignClusters []ForeignClusterState `yaml:"foreignClusters"`
+	//Nodes are the nodes to create or update.
+	Nodes []NodeState `yaml:"nodes"`
+	//Namespaces are the namespaces to create or update.
+	Namespaces []NamespaceState `yaml:"namespaces"`
+	//Pods are the pods to create or update.
+	Pods []PodState `yaml:"pods"`
+	//DeletePods are the pods to delete, in the 'namespace/name' format.
+	DeletePods []string `yaml:"deletePods"`
+	//DeleteNamespaces are the names of the namespaces to delete.
+	DeleteNamespaces []string `yaml:"deleteNamespaces"`
+	//DeleteNodes are the names of the nodes to delete.
+	DeleteNodes []string `yaml:"deleteNodes"`
+	//DeleteForeignClusters are the ClusterIDs of the ForeignClusters to delete.
+	DeleteForeignClusters []string `yaml:"deleteForeignClusters"`
+	//DeleteClusterConfig specifies whether the configuration of the home cluster has to be deleted.
+	DeleteClusterConfig bool `yaml:"deleteClusterConfig"`
+}
+
+// ScenarioStep is a step of a Scenario. Its changes are applied in the order of the fields declaration.
+type ScenarioStep struct {
+	Name           string `yaml:"name"`
+	ClusterChanges `yaml:",inline"`
+	//PortForwards are the changes of the port-forwards notified by the AgentController, since a mocked
+	//AgentController cannot forward any Service.
+	PortForwards []PortForwardState `yaml:"portForwards"`
+	//Click are the tags of the QUICKs to click.
+	Click []string `yaml:"click"`
+	//Select are the entries of the ACTIONs to click.
+	Select []MenuClick         `yaml:"select"`
+	Expect ScenarioExpectation `yaml:"expect"`
+}
+
+// PortForwardState describes the status of a port-forward started by the Agent.
+type PortForwardState struct {
+	Namespace string `yaml:"namespace"`
+	Service   string `yaml:"service"`
+	Port      int32  `yaml:"port"`
+	LocalPort uint16 `yaml:"localPort"`
+	//Pod is the pod serving the port-forward. It is empty while the port-forward is being restored.
+	Pod     string `yaml:"pod"`
+	Error   string `yaml:"error"`
+	Stopped bool   `yaml:"stopped"`
+}
+
+// MenuClick is a click on an OPTION or on a LIST entry of an ACTION.
+type MenuClick struct {
+	//Action is the tag of the ACTION.
+	Action string `yaml:"action"`
+	//Option is the tag of the OPTION to click.
+	Option string `yaml:"option"`
+	//Entry is the tag of the LIST entry to click, used if Option is not set.
+	Entry string `yaml:"entry"`
+	//Events is the number of events triggered by the callback of the entry (e.g. the update of the
+	//ClusterConfig), that are handled before the next change.
+	Events int `yaml:"events"`
+}
+
+// ScenarioExpectation is the state of the Agent expected after a ScenarioStep. Unset fields are not checked.
+type ScenarioExpectation struct {
+	//Running is the running status of the Agent ('ON' or 'OFF').
+	Running *string `yaml:"running"`
+	//Mode is the working mode of the Agent ('AUTONOMOUS' or 'TETHERED').
+	Mode             *string `yaml:"mode"`
+	Peers            *int    `yaml:"peers"`
+	OutgoingPeerings *int    `yaml:"outgoingPeerings"`
+	IncomingPeerings *int    `yaml:"incomingPeerings"`
+	//Icon is the name of the Indicator icon (e.g. 'IconLiqoMain').
+	Icon  *string `yaml:"icon"`
+	Label *string `yaml:"label"`
+	//Notifications are the desktop banners displayed during the step, in the 'title: message' format.
+	Notifications *[]string `yaml:"notifications"`
+	//Menu are entries that must be in the menu. Entries are compared with the lines of the menu snapshot
+	//(see app-indicator.MockGuiProvider.DumpMenu) without indentation.
+	Menu []string `yaml:"menu"`
+	//NotInMenu are entries that must not be in the menu.
+	NotInMenu []string `yaml:"notInMenu"`
+}
+
+// LoadScenario reads a Scenario from a YAML file.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	scenario := &Scenario{}
+	if err := yaml.UnmarshalStrict(data, scenario); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %v", path, err)
+	}
+	return scenario, nil
+}
+
+// RunScenario starts a Harness and executes the Scenario read from the YAML file 'path'. If updateGolden is true,
+// the golden file of the Scenario is written instead of being compared.
+func RunScenario(t *testing.T, onReady func(), path string, updateGolden bool) {
+	t.Helper()
+	scenario, err := LoadScenario(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goldenPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".golden"
+	startHarness(t, onReady, scenario).Run(scenario, goldenPath, updateGolden)
+}
+
+// Harness runs the Agent logic on a mocked Indicator, connected to a FakeCluster.
+type Harness struct {
+	Indicator *app.Indicator
+	Provider  *app.MockGuiProvider
+	Cluster   *FakeCluster
+	t         *testing.T
+}
+
+// StartHarness resets the mocked Indicator and AgentController and starts the Agent logic, executing onReady.
+// The Agent data are stored in a temporary directory, removed at the end of the test.
+func StartHarness(t *testing.T, onReady func()) *Harness {
+	return startHarness(t, onReady, &Scenario{})
+}
+
+// startHarness starts a Harness for a Scenario, preparing the Agent data directory with the Scenario catalog.
+func startHarness(t *testing.T, onReady func(), scenario *Scenario) *Harness {
+	app.UseMockedGuiProvider()
+	client.UseMockedAgentController()
+	app.DestroyMockedIndicator()
+	client.DestroyMockedAgentController()
+	app.DestroyStatus()
+	h := &Harness{t: t}
+	h.useDataDir(scenario.Catalog)
+	h.Indicator = app.GetIndicator()
+	h.Cluster = NewFakeCluster(h.Indicator.AgentCtrl())
+	//the Agent is still OFF, so the events of the initial changes are discarded as in a real startup
+	h.Apply(&scenario.Initial)
+	onReady()
+	h.Provider = app.GetGuiProvider().(*app.MockGuiProvider)
+	return h
+}
+
+// dataHomeEnv is the env var containing the base directory of the Agent data (see app-indicator config).
+const dataHomeEnv = "XDG_DATA_HOME"
+
+// useDataDir makes the Agent store its data in a temporary directory containing the application catalog.
+// The directory is removed and the environment restored at the end of the test.
+func (h *Harness) useDataDir(catalog []client.AppTemplate) {
+	h.t.Helper()
+	dir, err := ioutil.TempDir("", "liqo-agent-test")
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	oldDataHome, present := os.LookupEnv(dataHomeEnv)
+	h.t.Cleanup(func() {
+		if present {
+			_ = os.Setenv(dataHomeEnv, oldDataHome)
+		} else {
+			_ = os.Unsetenv(dataHomeEnv)
+		}
+		_ = os.RemoveAll(dir)
+	})
+	if err = os.Setenv(dataHomeEnv, dir); err != nil {
+		h.t.Fatal(err)
+	}
+	catalogDir := filepath.Join(dir, "liqo", client.AppCatalogDir)
+	if err = os.MkdirAll(catalogDir, 0755); err != nil {
+		h.t.Fatal(err)
+	}
+	for j := range catalog {
+		data, err := yaml.Marshal(&catalog[j])
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(catalogDir, catalog[j].Name+".yaml"), data, 0644)
+		}
+		if err != nil {
+			h.t.Fatal(err)
+		}
+	}
+}
+
+// wait executes action, then dispatches the 'events' it triggers, so that the Agent handles them.
+func (h *Harness) wait(events int, action func() error) {
+	h.t.Helper()
+	if err := action(); err != nil {
+		h.t.Fatal(err)
+	}
+	if err := h.Indicator.DispatchEvents(events, eventTimeout); err != nil {
+		h.t.Fatalf("event not handled by the Agent: %v", err)
+	}
+}
+
+// ApplyForeignCluster creates or updates a ForeignCluster, waiting for the Agent to handle the event.
+func (h *Harness) ApplyForeignCluster(state *ForeignClusterState) {
+	h.t.Helper()
+	events := 1
+	//ForeignClusters with no ClusterID are ignored until the identity of the foreign cluster is known
+	if state.ClusterID == "" {
+		events = 0
+	}
+	h.wait(events, func() error {
+		return h.Cluster.ApplyForeignCluster(state)
+	})
+}
+
+// DeleteForeignCluster deletes a ForeignCluster, waiting for the Agent to handle the event.
+func (h *Harness) DeleteForeignCluster(clusterID string) {
+	h.t.Helper()
+	h.wait(1, func() error {
+		return h.Cluster.DeleteForeignCluster(clusterID)
+	})
+}
+
+// ApplyAdvertisement creates or updates an Advertisement.
+func (h *Harness) ApplyAdvertisement(state *AdvertisementState) {
+	h.t.Helper()
+	if err := h.Cluster.ApplyAdvertisement(state); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// ApplyClusterConfig creates or updates the ClusterConfig, waiting for the Agent to handle the event.
+func (h *Harness) ApplyClusterConfig(state *ClusterConfigState) {
+	h.t.Helper()
+	h.wait(1, func() error {
+		return h.Cluster.ApplyClusterConfig(state)
+	})
+}
+
+// DeleteClusterConfig deletes the ClusterConfig, waiting for the Agent to handle the event.
+func (h *Harness) DeleteClusterConfig() {
+	h.t.Helper()
+	h.wait(1, h.Cluster.DeleteClusterConfig)
+}
+
+// ApplyNode creates or updates a node, waiting for the Agent to handle the events regarding the virtual nodes.
+func (h *Harness) ApplyNode(state *NodeState) {
+	h.t.Helper()
+	events := 0
+	if state.Virtual() {
+		events = 1
+		//the pods of a new virtual node are notified as soon as they are watched
+		if old, present := h.Cluster.Node(state.Name); !present || !old.Virtual() {
+			events += h.Cluster.PodsOnNode(state.Name)
+		}
+	}
+	h.wait(events, func() error {
+		return h.Cluster.ApplyNode(state)
+	})
+}
+
+// DeleteNode deletes a node, waiting for the Agent to handle the events regarding the virtual nodes.
+func (h *Harness) DeleteNode(name string) {
+	h.t.Helper()
+	events := 0
+	//the pods of a removed virtual node are no more watched, so their removal is notified
+	if state, present := h.Cluster.Node(name); present && state.Virtual() {
+		events = 1 + h.Cluster.PodsOnNode(name)
+	}
+	h.wait(events, func() error {
+		return h.Cluster.DeleteNode(name)
+	})
+}
+
+// ApplyNamespace creates or updates a namespace, waiting for the Agent to handle the event.
+func (h *Harness) ApplyNamespace(state *NamespaceState) {
+	h.t.Helper()
+	h.wait(1, func() error {
+		return h.Cluster.ApplyNamespace(state)
+	})
+}
+
+// DeleteNamespace deletes a namespace, waiting for the Agent to handle the event.
+func (h *Harness) DeleteNamespace(name string) {
+	h.t.Helper()
+	h.wait(1, func() error {
+		return h.Cluster.DeleteNamespace(name)
+	})
+}
+
+// podEvents returns the number of events the Agent receives for a change of a pod: a pod is watched as
+// application pod, as incoming pod and as pod offloaded through a virtual node.
+func (h *Harness) podEvents(state *PodState) int {
+	events := 0
+	if state.App != "" {
+		events++
+	}
+	if state.Incoming {
+		events++
+	}
+	if node, present := h.Cluster.Node(state.Node); present && node.Virtual() {
+		events++
+	}
+	return events
+}
+
+// ApplyPod creates or updates a pod, waiting for the Agent to handle the events.
+func (h *Harness) ApplyPod(state *PodState) {
+	h.t.Helper()
+	h.wait(h.podEvents(state), func() error {
+		return h.Cluster.ApplyPod(state)
+	})
+}
+
+// DeletePod deletes a pod given its 'namespace/name' key, waiting for the Agent to handle the events.
+func (h *Harness) DeletePod(key string) {
+	h.t.Helper()
+	state, present := h.Cluster.Pod(key)
+	if !present {
+		h.t.Fatalf("pod %s not found", key)
+	}
+	h.wait(h.podEvents(&state), func() error {
+		return h.Cluster.DeletePod(key)
+	})
+}
+
+// NotifyPortForward notifies the status of a port-forward, waiting for the Agent to handle the event.
+func (h *Harness) NotifyPortForward(state *PortForwardState) {
+	h.t.Helper()
+	h.wait(1, func() error {
+		h.Indicator.AgentCtrl().Notify(client.ChanPortForward, &client.NotifyDataPortForward{
+			Key:         client.PortForwardKey(state.Namespace, state.Service, state.Port),
+			Namespace:   state.Namespace,
+			Service:     state.Service,
+			ServicePort: state.Port,
+			LocalPort:   state.LocalPort,
+			Pod:         state.Pod,
+			Stopped:     state.Stopped,
+			Error:       state.Error,
+		})
+		return nil
+	})
+}
+
+// Apply applies a set of changes to the FakeCluster, waiting for the Agent to handle them.
+func (h *Harness) Apply(changes *ClusterChanges) {
+	h.t.Helper()
+	if changes.ClusterConfig != nil {
+		h.ApplyClusterConfig(changes.ClusterConfig)
+	}
+	for j := range changes.Advertisements {
+		h.ApplyAdvertisement(&changes.Advertisements[j])
+	}
+	for j := range changes.ForeignClusters {
+		h.ApplyForeignCluster(&changes.ForeignClusters[j])
+	}
+	for j := range changes.Nodes {
+		h.ApplyNode(&changes.Nodes[j])
+	}
+	for j := range changes.Namespaces {
+		h.ApplyNamespace(&changes.Namespaces[j])
+	}
+	for j := range changes.Pods {
+		h.ApplyPod(&changes.Pods[j])
+	}
+	for _, key := range changes.DeletePods {
+		h.DeletePod(key)
+	}
+	for _, name := range changes.DeleteNamespaces {
+		h.DeleteNamespace(name)
+	}
+	for _, name := range changes.DeleteNodes {
+		h.DeleteNode(name)
+	}
+	for _, clusterID := range changes.DeleteForeignClusters {
+		h.DeleteForeignCluster(clusterID)
+	}
+	if changes.DeleteClusterConfig {
+		h.DeleteClusterConfig()
+	}
+}
+
+// Click clicks a QUICK of the Indicator, waiting for the execution of its callback.
+func (h *Harness) Click(tag string) {
+	h.t.Helper()
+	quick, present := h.Indicator.Quick(tag)
+	if !present {
+		h.t.Fatalf("QUICK %s not registered", tag)
+	}
+	h.wait(1, func() error {
+		quick.Channel() <- struct{}{}
+		return nil
+	})
+}
+
+// Select clicks an entry of an ACTION, waiting for the execution of its callback and for the handling of the
+// events it triggers.
+func (h *Harness) Select(click *MenuClick) {
+	h.t.Helper()
+	action, present := h.Indicator.Action(click.Action)
+	if !present {
+		h.t.Fatalf("ACTION %s not registered", click.Action)
+	}
+	var entry *app.MenuNode
+	if click.Option != "" {
+		entry, present = action.Option(click.Option)
+	} else {
+		entry, present = action.ListChild(click.Entry)
+	}
+	if !present {
+		h.t.Fatalf("entry %s%s of ACTION %s not found", click.Option, click.Entry, click.Action)
+	}
+	h.wait(1+click.Events, func() error {
+		entry.Channel() <- struct{}{}
+		return nil
+	})
+}
+
+// Run executes a Scenario, checking the state of the Agent after each step. The golden file of the scenario
+// (if any) is read from goldenPath or, if update is true, written in goldenPath.
+func (h *Harness) Run(scenario *Scenario, goldenPath string, update bool) {
+	h.t.Helper()
+	var snapshots strings.Builder
+	snapshots.WriteString("## startup\n" + h.Indicator.Snapshot())
+	//notifications displayed at startup are not part of any step
+	h.Provider.Notifications()
+	for _, step := range scenario.Steps {
+		h.Apply(&step.ClusterChanges)
+		for j := range step.PortForwards {
+			h.NotifyPortForward(&step.PortForwards[j])
+		}
+		for _, tag := range step.Click {
+			h.Click(tag)
+		}
+		for j := range step.Select {
+			h.Select(&step.Select[j])
+		}
+		h.check(step.name(), &step.Expect)
+		snapshots.WriteString("\n## " + step.name() + "\n" + h.Indicator.Snapshot())
+	}
+	if !scenario.Golden {
+		return
+	}
+	if update {
+		if err := ioutil.WriteFile(goldenPath, []byte(snapshots.String()), 0644); err != nil {
+			h.t.Fatal(err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		h.t.Fatalf("cannot read golden file (run the test with -update to create it): %v", err)
+	}
+	assert.Equal(h.t, string(expected), snapshots.String(),
+		"menu snapshot differs from %s (run the test with -update to accept the changes)", goldenPath)
+}
+
+// name returns the name of the ScenarioStep, used in the golden files.
+func (s *ScenarioStep) name() string {
+	if s.Name == "" {
+		return "unnamed step"
+	}
+	return s.Name
+}
+
+// check compares the state of the Agent with the expected one.
+func (h *Harness) check(step string, expect *ScenarioExpectation) {
+	h.t.Helper()
+	t := h.t
+	status := h.Indicator.Status()
+	if expect.Running != nil {
+		assert.Equal(t, *expect.Running, status.Running().String(), "[%s] wrong running status", step)
+	}
+	if expect.Mode != nil {
+		assert.Equal(t, *expect.Mode, status.Mode().String(), "[%s] wrong mode", step)
+	}
+	if expect.Peers != nil {
+		assert.Equal(t, *expect.Peers, status.Peers(), "[%s] wrong number of peers", step)
+	}
+	if expect.OutgoingPeerings != nil {
+		assert.Equal(t, *expect.OutgoingPeerings, status.Peerings(app.PeeringOutgoing),
+			"[%s] wrong number of outgoing peerings", step)
+	}
+	if expect.IncomingPeerings != nil {
+		assert.Equal(t, *expect.IncomingPeerings, status.Peerings(app.PeeringIncoming),
+			"[%s] wrong number of incoming peerings", step)
+	}
+	if expect.Icon != nil {
+		assert.Equal(t, *expect.Icon, h.Indicator.Icon().String(), "[%s] wrong icon", step)
+	}
+	if expect.Label != nil {
+		assert.Equal(t, *expect.Label, h.Provider.Title(), "[%s] wrong label", step)
+	}
+	notifications := h.Provider.Notifications()
+	if expect.Notifications != nil {
+		assert.ElementsMatch(t, *expect.Notifications, notifications, "[%s] wrong notifications", step)
+	}
+	menu := make(map[string]bool)
+	for _, line := range strings.Split(h.Provider.DumpMenu(), "\n") {
+		menu[strings.TrimSpace(line)] = true
+	}
+	for _, entry := range expect.Menu {
+		assert.Truef(t, menu[entry], "[%s] menu entry '%s' not found", step, entry)
+	}
+	for _, entry := range expect.NotInMenu {
+		assert.Falsef(t, menu[entry], "[%s] unexpected menu entry '%s'", step, entry)
+	}
+}